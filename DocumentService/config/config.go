@@ -9,6 +9,10 @@ type MongoConfigStruct struct {
 	UserCollectionName            string
 	DocumentCollectionName        string
 	SharedDocRecordCollectionName string
+	VersionCollectionName         string
+	OplogCollectionName           string
+	BranchCollectionName          string
+	MergeCollectionName           string
 }
 
 var MongoConfig = MongoConfigStruct{
@@ -17,4 +21,8 @@ var MongoConfig = MongoConfigStruct{
 	UserCollectionName:            "user",
 	DocumentCollectionName:        "document",
 	SharedDocRecordCollectionName: "shared",
+	VersionCollectionName:         "versions",
+	OplogCollectionName:           "oplog",
+	BranchCollectionName:          "branches",
+	MergeCollectionName:           "merges",
 }
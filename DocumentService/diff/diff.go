@@ -0,0 +1,352 @@
+// Package diff compares two states of a document for change reviews
+package diff
+
+import (
+	"document-service/model"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Statuses of slides and objects
+const (
+	StatusAdded     = "added"
+	StatusRemoved   = "removed"
+	StatusModified  = "modified"
+	StatusReordered = "reordered" // only the position changed
+)
+
+// Change is the value of a property before and after, a side is omitted when the
+// property isn't set on it
+type Change struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// DocumentDiff lists what changed between two states of a document. Unchanged
+// slides are left out.
+type DocumentDiff struct {
+	Title  *Change     `json:"title,omitempty"`
+	Slides []SlideDiff `json:"slides"`
+}
+
+// SlideDiff describes a slide which was added, removed or changed. Reordered is set
+// when the slide moved relative to the slides both states have, Objects only lists
+// objects which changed.
+type SlideDiff struct {
+	SlideID     string            `json:"slideId"`
+	Status      string            `json:"status"`
+	IndexBefore *int              `json:"indexBefore,omitempty"`
+	IndexAfter  *int              `json:"indexAfter,omitempty"`
+	Reordered   bool              `json:"reordered,omitempty"`
+	Properties  map[string]Change `json:"properties,omitempty"`
+	Objects     []ObjectDiff      `json:"objects,omitempty"`
+}
+
+// ObjectDiff describes an object of a slide. Objects inside groups and frames are
+// compared like top-level ones, ParentID tells where they are. Added and removed
+// objects carry the whole object, modified ones the attributes which changed.
+type ObjectDiff struct {
+	ObjectID   string            `json:"objectId"`
+	ObjectType string            `json:"objectType"`
+	Status     string            `json:"status"`
+	ParentID   *Change           `json:"parentId,omitempty"` // set when the object changed container, "" is the slide
+	LayerID    *Change           `json:"layerId,omitempty"`
+	Reordered  bool              `json:"reordered,omitempty"` // z-order changed within its container
+	Attributes map[string]Change `json:"attributes,omitempty"`
+	Object     *model.Object     `json:"object,omitempty"`
+}
+
+// Documents compares the slides and title of two states of a document
+func Documents(before *model.Document, after *model.Document) DocumentDiff {
+	result := DocumentDiff{Slides: []SlideDiff{}}
+	if before.Title != after.Title {
+		result.Title = &Change{Before: before.Title, After: after.Title}
+	}
+
+	beforeIndex := slideIndexes(before.Slides)
+	afterIndex := slideIndexes(after.Slides)
+
+	// slides both states have but out of their common order were reordered
+	var beforeCommon, afterCommon []string
+	for _, slide := range before.Slides {
+		if _, ok := afterIndex[slide.ID]; ok {
+			beforeCommon = append(beforeCommon, slide.ID)
+		}
+	}
+	for _, slide := range after.Slides {
+		if _, ok := beforeIndex[slide.ID]; ok {
+			afterCommon = append(afterCommon, slide.ID)
+		}
+	}
+	inOrder := longestCommonSubsequence(beforeCommon, afterCommon)
+
+	for i := range after.Slides {
+		slide := &after.Slides[i]
+		index := i
+		j, ok := beforeIndex[slide.ID]
+		if !ok {
+			result.Slides = append(result.Slides, SlideDiff{SlideID: slide.ID, Status: StatusAdded, IndexAfter: &index, Objects: addedObjects(slide.Objects)})
+			continue
+		}
+
+		indexBefore := j
+		d := compareSlides(&before.Slides[j], slide)
+		d.IndexBefore, d.IndexAfter = &indexBefore, &index
+		d.Reordered = !inOrder[slide.ID]
+		if len(d.Properties) > 0 || len(d.Objects) > 0 {
+			result.Slides = append(result.Slides, d)
+		} else if d.Reordered {
+			d.Status = StatusReordered
+			result.Slides = append(result.Slides, d)
+		}
+	}
+
+	for i := range before.Slides {
+		slide := &before.Slides[i]
+		if _, ok := afterIndex[slide.ID]; ok {
+			continue
+		}
+		index := i
+		d := SlideDiff{SlideID: slide.ID, Status: StatusRemoved, IndexBefore: &index}
+		for _, o := range flatten(slide.Objects, "") {
+			d.Objects = append(d.Objects, ObjectDiff{ObjectID: o.object.ID, ObjectType: o.object.Type, Status: StatusRemoved, Object: o.object})
+		}
+		result.Slides = append(result.Slides, d)
+	}
+
+	return result
+}
+
+func slideIndexes(slides []model.Slide) map[string]int {
+	indexes := make(map[string]int, len(slides))
+	for i := range slides {
+		indexes[slides[i].ID] = i
+	}
+	return indexes
+}
+
+func addedObjects(objects []model.Object) []ObjectDiff {
+	var diffs []ObjectDiff
+	for _, o := range flatten(objects, "") {
+		diffs = append(diffs, ObjectDiff{ObjectID: o.object.ID, ObjectType: o.object.Type, Status: StatusAdded, Object: o.object})
+	}
+	return diffs
+}
+
+// compareSlides compares the properties and objects of two states of a slide
+func compareSlides(before *model.Slide, after *model.Slide) SlideDiff {
+	d := SlideDiff{SlideID: after.ID, Status: StatusModified, Properties: map[string]Change{}}
+
+	properties := []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"background", before.Background, after.Background},
+		{"backgroundImage", before.BackgroundImage, after.BackgroundImage},
+		{"title", before.Title, after.Title},
+		{"notes", before.Notes, after.Notes},
+		{"width", before.Width, after.Width},
+		{"height", before.Height, after.Height},
+		{"layers", before.Layers, after.Layers},
+	}
+	for _, p := range properties {
+		if !Equal(p.before, p.after) {
+			d.Properties[p.name] = Change{Before: omitZero(p.before), After: omitZero(p.after)}
+		}
+	}
+	if len(d.Properties) == 0 {
+		d.Properties = nil
+	}
+
+	beforeObjects := flatten(before.Objects, "")
+	afterObjects := flatten(after.Objects, "")
+	beforeById := make(map[string]flatObject, len(beforeObjects))
+	for _, o := range beforeObjects {
+		beforeById[o.object.ID] = o
+	}
+	afterById := make(map[string]flatObject, len(afterObjects))
+	for _, o := range afterObjects {
+		afterById[o.object.ID] = o
+	}
+	inOrder := objectOrder(beforeObjects, afterObjects, beforeById, afterById)
+
+	for _, a := range afterObjects {
+		b, ok := beforeById[a.object.ID]
+		if !ok {
+			d.Objects = append(d.Objects, ObjectDiff{ObjectID: a.object.ID, ObjectType: a.object.Type, Status: StatusAdded, Object: a.object})
+			continue
+		}
+		if od, changed := compareObjects(b, a, !inOrder[a.object.ID]); changed {
+			d.Objects = append(d.Objects, od)
+		}
+	}
+	for _, b := range beforeObjects {
+		if _, ok := afterById[b.object.ID]; !ok {
+			d.Objects = append(d.Objects, ObjectDiff{ObjectID: b.object.ID, ObjectType: b.object.Type, Status: StatusRemoved, Object: b.object})
+		}
+	}
+
+	return d
+}
+
+// compareObjects returns the changes of an object present in both states. Children
+// are compared on their own, they aren't part of the attributes of their container.
+func compareObjects(before flatObject, after flatObject, reordered bool) (ObjectDiff, bool) {
+	d := ObjectDiff{ObjectID: after.object.ID, ObjectType: after.object.Type, Status: StatusModified}
+	changed := false
+
+	if before.object.Type != after.object.Type {
+		d.Attributes = map[string]Change{"type": {Before: before.object.Type, After: after.object.Type}}
+		changed = true
+	}
+	if before.parentId != after.parentId {
+		d.ParentID = &Change{Before: before.parentId, After: after.parentId}
+		changed = true
+	} else if reordered {
+		d.Reordered = true
+		changed = true
+	}
+	if before.object.LayerID != after.object.LayerID {
+		d.LayerID = &Change{Before: before.object.LayerID, After: after.object.LayerID}
+		changed = true
+	}
+
+	for _, key := range attributeKeys(before.object.Attributes, after.object.Attributes) {
+		b, inBefore := before.object.Attributes[key]
+		a, inAfter := after.object.Attributes[key]
+		if inBefore == inAfter && Equal(b, a) {
+			continue
+		}
+		if d.Attributes == nil {
+			d.Attributes = map[string]Change{}
+		}
+		d.Attributes[key] = Change{Before: b, After: a}
+		changed = true
+	}
+
+	if d.Reordered && d.Attributes == nil && d.LayerID == nil {
+		d.Status = StatusReordered
+	}
+	return d, changed
+}
+
+func attributeKeys(before map[string]interface{}, after map[string]interface{}) []string {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// flatObject is an object of a slide with the container it is in
+type flatObject struct {
+	object   *model.Object
+	parentId string // "" for top-level objects
+}
+
+// flatten lists the objects of a slide depth first, containers before their children.
+// The listed objects have no children, those are listed on their own.
+func flatten(objects []model.Object, parentId string) []flatObject {
+	var flat []flatObject
+	for i := range objects {
+		obj := objects[i]
+		children := obj.Children
+		obj.Children = nil
+		flat = append(flat, flatObject{object: &obj, parentId: parentId})
+		flat = append(flat, flatten(children, obj.ID)...)
+	}
+	return flat
+}
+
+// objectOrder tells which objects kept their z-order within a container that holds
+// them in both states
+func objectOrder(beforeObjects, afterObjects []flatObject, beforeById, afterById map[string]flatObject) map[string]bool {
+	siblings := func(objects []flatObject, other map[string]flatObject) map[string][]string {
+		byParent := map[string][]string{}
+		for _, o := range objects {
+			if counterpart, ok := other[o.object.ID]; ok && counterpart.parentId == o.parentId {
+				byParent[o.parentId] = append(byParent[o.parentId], o.object.ID)
+			}
+		}
+		return byParent
+	}
+
+	beforeSiblings := siblings(beforeObjects, afterById)
+	afterSiblings := siblings(afterObjects, beforeById)
+
+	inOrder := map[string]bool{}
+	for parentId, ids := range afterSiblings {
+		for id := range longestCommonSubsequence(beforeSiblings[parentId], ids) {
+			inOrder[id] = true
+		}
+	}
+	return inOrder
+}
+
+// longestCommonSubsequence returns the ids of the longest sequence both lists have
+// in the same order, the other common ids were moved
+func longestCommonSubsequence(a []string, b []string) map[string]bool {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	common := map[string]bool{}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return common
+}
+
+// Equal compares values regardless of the types json or bson decoded them into
+func Equal(a interface{}, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	var normalizedA, normalizedB interface{}
+	if json.Unmarshal(encodedA, &normalizedA) != nil || json.Unmarshal(encodedB, &normalizedB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalizedA, normalizedB)
+}
+
+// omitZero drops unset slide properties so they are left out of the change
+func omitZero(v interface{}) interface{} {
+	if v == nil || reflect.ValueOf(v).IsZero() {
+		return nil
+	}
+	return v
+}
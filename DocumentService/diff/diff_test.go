@@ -0,0 +1,182 @@
+package diff
+
+import (
+	"document-service/model"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func obj(id string, x float64, children ...model.Object) model.Object {
+	return model.Object{ID: id, Type: "rect", Attributes: map[string]interface{}{"x": x}, Children: children}
+}
+
+func slide(id string, objects ...model.Object) model.Slide {
+	return model.Slide{ID: id, Background: "#fff", Objects: objects}
+}
+
+// describe lists the slide and object statuses of a diff, "slide:status" then
+// "slide/object:status" with the changed attributes, parent or layer
+func describe(d DocumentDiff) []string {
+	out := []string{}
+	if d.Title != nil {
+		out = append(out, fmt.Sprintf("title:%v->%v", d.Title.Before, d.Title.After))
+	}
+	for _, s := range d.Slides {
+		line := s.SlideID + ":" + s.Status
+		if s.Reordered {
+			line += ":reordered"
+		}
+		for name := range s.Properties {
+			line += ":" + name
+		}
+		out = append(out, line)
+		for _, o := range s.Objects {
+			line := s.SlideID + "/" + o.ObjectID + ":" + o.Status
+			for key := range o.Attributes {
+				line += ":" + key
+			}
+			if o.ParentID != nil {
+				line += fmt.Sprintf(":parent %v->%v", o.ParentID.Before, o.ParentID.After)
+			}
+			if o.LayerID != nil {
+				line += fmt.Sprintf(":layer %v->%v", o.LayerID.Before, o.LayerID.After)
+			}
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func TestDocuments(t *testing.T) {
+	base := []model.Slide{slide("s1", obj("a", 1), obj("b", 2), obj("c", 3)), slide("s2"), slide("s3")}
+
+	tests := []struct {
+		name  string
+		after func(slides []model.Slide) []model.Slide
+		title string
+		want  []string
+	}{
+		{
+			name:  "unchanged",
+			after: func(s []model.Slide) []model.Slide { return s },
+			want:  []string{},
+		},
+		{
+			name:  "title",
+			after: func(s []model.Slide) []model.Slide { return s },
+			title: "Renamed",
+			want:  []string{"title:Doc->Renamed"},
+		},
+		{
+			name:  "slide added and removed",
+			after: func(s []model.Slide) []model.Slide { return []model.Slide{s[0], slide("s4", obj("c", 1)), s[2]} },
+			want:  []string{"s4:added", "s4/c:added", "s2:removed"},
+		},
+		{
+			name:  "slide moved",
+			after: func(s []model.Slide) []model.Slide { return []model.Slide{s[2], s[0], s[1]} },
+			want:  []string{"s3:reordered:reordered"},
+		},
+		{
+			name: "slide property",
+			after: func(s []model.Slide) []model.Slide {
+				s[1].Background = "#000"
+				return s
+			},
+			want: []string{"s2:modified:background"},
+		},
+		{
+			name: "object added, removed and modified",
+			after: func(s []model.Slide) []model.Slide {
+				s[0].Objects = []model.Object{obj("a", 5), obj("c", 3), obj("d", 4)}
+				return s
+			},
+			want: []string{"s1:modified", "s1/a:modified:x", "s1/d:added", "s1/b:removed"},
+		},
+		{
+			name: "object reordered",
+			after: func(s []model.Slide) []model.Slide {
+				s[0].Objects = []model.Object{obj("c", 3), obj("a", 1), obj("b", 2)}
+				return s
+			},
+			want: []string{"s1:modified", "s1/c:reordered"},
+		},
+		{
+			name: "object grouped",
+			after: func(s []model.Slide) []model.Slide {
+				s[0].Objects = []model.Object{{ID: "g", Type: "group", Children: []model.Object{obj("a", 1), obj("b", 2)}}, obj("c", 3)}
+				return s
+			},
+			want: []string{"s1:modified", "s1/g:added", "s1/a:modified:parent ->g", "s1/b:modified:parent ->g"},
+		},
+		{
+			name: "object layer",
+			after: func(s []model.Slide) []model.Slide {
+				s[0].Objects[1].LayerID = "l1"
+				return s
+			},
+			want: []string{"s1:modified", "s1/b:modified:layer ->l1"},
+		},
+		{
+			name: "values decoded from bson compare equal",
+			after: func(s []model.Slide) []model.Slide {
+				s[0].Objects[0].Attributes = map[string]interface{}{"x": int32(1)}
+				s[0].Objects[1].Attributes = map[string]interface{}{"x": 2.0, "points": primitive.A{1.0, 2.0}}
+				return s
+			},
+			want: []string{"s1:modified", "s1/b:modified:points"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := &model.Document{Title: "Doc", Slides: base}
+			after := &model.Document{Title: "Doc", Slides: tt.after(cloneSlides(base))}
+			if tt.title != "" {
+				after.Title = tt.title
+			}
+
+			if got := describe(Documents(before, after)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want bool
+	}{
+		{"same", 1.0, 1.0, true},
+		{"int32 and float64", int32(3), 3.0, true},
+		{"bson and json lists", primitive.A{1.0, "a"}, []interface{}{1.0, "a"}, true},
+		{"bson and json documents", primitive.M{"k": int64(2)}, map[string]interface{}{"k": 2.0}, true},
+		{"different values", 1.0, 2.0, false},
+		{"nil and zero", nil, 0.0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.a, tt.b); got != tt.want {
+				t.Errorf("Equal(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func cloneSlides(slides []model.Slide) []model.Slide {
+	out := make([]model.Slide, len(slides))
+	for i, s := range slides {
+		out[i] = s
+		out[i].Objects = make([]model.Object, len(s.Objects))
+		for j, o := range s.Objects {
+			out[i].Objects[j] = obj(o.ID, o.Attributes["x"].(float64))
+		}
+	}
+	return out
+}
@@ -3,6 +3,7 @@ module document-service
 go 1.25.1
 
 require (
+	Shared v0.0.0
 	github.com/gin-gonic/gin v1.11.0
 	go.mongodb.org/mongo-driver v1.17.4
 )
@@ -47,3 +48,5 @@ require (
 	golang.org/x/tools v0.34.0 // indirect
 	google.golang.org/protobuf v1.36.9 // indirect
 )
+
+replace Shared => ../Shared
@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"document-service/merge"
+	"document-service/model"
+	"document-service/types"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBranchTitleLength caps the title of branches
+const MaxBranchTitleLength = 256
+
+// Route: POST /document/id/:id/branches
+// Copies the document into a new document owned by the user. The current slides are
+// saved as the base version merges compare both sides against.
+func (h DocumentHandler) CreateBranch(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, true) {
+		return
+	}
+
+	var data types.CreateBranchPostData
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&data); err != nil || len(data.Title) > MaxBranchTitleLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid data format"})
+			return
+		}
+	}
+
+	document, err := h.DocumentRepository.FindDocumentByID(c.Request.Context(), docID)
+	if err != nil || document == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving document"})
+		return
+	}
+	if data.Title == "" {
+		data.Title = fmt.Sprintf("%s (branch)", document.Title)
+	}
+
+	base, err := h.VersionRepository.CreateVersion(c, document, fmt.Sprintf("Branch %q", data.Title), model.VersionKindAuto, userId)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error saving the base version"})
+		return
+	}
+
+	copied, err := h.DocumentRepository.CopyDocument(c, document, data.Title, userId)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error creating the branch document"})
+		return
+	}
+	if _, err := h.VersionRepository.CreateVersion(c, &copied, "Created", model.VersionKindAuto, userId); err != nil {
+		fmt.Printf("[DocumentHandler][CreateBranch] Error creating the initial version: %v\n", err)
+		if err := h.DocumentRepository.DeleteDocument(c, copied.ID.Hex()); err != nil {
+			fmt.Printf("[DocumentHandler][CreateBranch] Error deleting the branch document: %v\n", err)
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error creating the branch document"})
+		return
+	}
+
+	branch, err := h.BranchRepository.CreateBranch(c, model.Branch{
+		DocumentID:       docID,
+		BranchDocumentID: copied.ID.Hex(),
+		BaseVersionID:    base.ID.Hex(),
+		Title:            data.Title,
+		Status:           model.BranchStatusOpen,
+		CreatedBy:        userId,
+		CreatedAt:        time.Now(),
+	})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error creating branch"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, branch)
+}
+
+// Route: GET /document/id/:id/branches
+func (h DocumentHandler) GetBranches(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, false) {
+		return
+	}
+
+	branches, err := h.BranchRepository.FindBranches(c, docID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving branches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, branches)
+}
+
+// Route: POST /document/id/:id/branches/:branchId/merge
+// Three-way merges the branch into the document. Without conflicts the merge is
+// applied right away, otherwise it stays pending until every conflict is resolved.
+func (h DocumentHandler) MergeBranch(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, true) {
+		return
+	}
+
+	branch, err := h.BranchRepository.FindBranchByID(c, docID, c.Param("branchId"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid branch id"})
+		return
+	}
+	if branch == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Branch not found"})
+		return
+	}
+	if branch.Status != model.BranchStatusOpen {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Branch already merged"})
+		return
+	}
+
+	m, err := h.BranchRepository.CreateMerge(c, model.Merge{
+		DocumentID: docID,
+		BranchID:   branch.ID.Hex(),
+		Status:     model.MergeStatusPending,
+		Conflicts:  []model.MergeConflict{},
+		CreatedBy:  userId,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error creating merge"})
+		return
+	}
+
+	h.applyMerge(c, branch, &m)
+}
+
+// Route: GET /document/id/:id/merges/:mergeId
+func (h DocumentHandler) GetMerge(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, false) {
+		return
+	}
+
+	m, ok := h.findMerge(c, docID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, m)
+}
+
+// Route: POST /document/id/:id/merges/:mergeId/resolve
+// Sets the resolution ("ours" keeps the document, "theirs" takes the branch) of conflicts
+func (h DocumentHandler) ResolveMerge(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, true) {
+		return
+	}
+
+	var data types.ResolveMergePostData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid data format or missing fields"})
+		return
+	}
+
+	m, ok := h.findMerge(c, docID)
+	if !ok {
+		return
+	}
+	if m.Status != model.MergeStatusPending {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Merge already applied"})
+		return
+	}
+
+	conflicts := make(map[string]*model.MergeConflict, len(m.Conflicts))
+	for i := range m.Conflicts {
+		conflicts[m.Conflicts[i].ID] = &m.Conflicts[i]
+	}
+	for conflictId, resolution := range data.Resolutions {
+		conflict, ok := conflicts[conflictId]
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown conflict %s", conflictId)})
+			return
+		}
+		if resolution != merge.Ours && resolution != merge.Theirs {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Resolutions must be ours or theirs"})
+			return
+		}
+		conflict.Resolution = resolution
+	}
+
+	pending, err := h.BranchRepository.UpdateMerge(c, m)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error saving resolutions"})
+		return
+	}
+	if !pending {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Merge already applied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, m)
+}
+
+// Route: POST /document/id/:id/merges/:mergeId/apply
+// Merges again with the resolutions and applies the result. Both sides may have
+// changed meanwhile: new conflicts leave the merge pending (409).
+func (h DocumentHandler) ApplyMerge(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, true) {
+		return
+	}
+
+	m, ok := h.findMerge(c, docID)
+	if !ok {
+		return
+	}
+	if m.Status != model.MergeStatusPending {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Merge already applied"})
+		return
+	}
+
+	branch, err := h.BranchRepository.FindBranchByID(c, docID, m.BranchID)
+	if err != nil || branch == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving branch"})
+		return
+	}
+	if branch.Status != model.BranchStatusOpen {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Branch already merged"})
+		return
+	}
+
+	h.applyMerge(c, branch, m)
+}
+
+func (h DocumentHandler) findMerge(c *gin.Context, docID string) (*model.Merge, bool) {
+	m, err := h.BranchRepository.FindMergeByID(c, docID, c.Param("mergeId"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid merge id"})
+		return nil, false
+	}
+	if m == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Merge not found"})
+		return nil, false
+	}
+	return m, true
+}
+
+// applyMerge merges the branch into its document with the resolutions of m. With
+// unresolved conflicts m stays pending, otherwise the result is saved as a version
+// and the live document is reset to it like when a version is restored. The live
+// document is flushed first so ours is the document as its clients see it.
+func (h DocumentHandler) applyMerge(c *gin.Context, branch *model.Branch, m *model.Merge) {
+	liveVersion, err := flushLiveDocument(branch.DocumentID)
+	if err != nil {
+		fmt.Printf("[DocumentHandler][applyMerge] %v\n", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "Error saving the live document"})
+		return
+	}
+
+	document, err := h.DocumentRepository.FindDocumentByID(c.Request.Context(), branch.DocumentID)
+	if err != nil || document == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving document"})
+		return
+	}
+	branchDocument, err := h.DocumentRepository.FindDocumentByID(c.Request.Context(), branch.BranchDocumentID)
+	if err != nil || branchDocument == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving the branch document"})
+		return
+	}
+	base, err := h.VersionRepository.FindVersionByID(c, branch.DocumentID, branch.BaseVersionID)
+	if err != nil || base == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving the base version"})
+		return
+	}
+
+	resolutions := map[string]string{}
+	for _, conflict := range m.Conflicts {
+		if conflict.Resolution != "" {
+			resolutions[conflict.ID] = conflict.Resolution
+		}
+	}
+
+	result := merge.Slides(base.Slides, document.Slides, branchDocument.Slides, resolutions)
+	m.Conflicts = result.Conflicts
+	if m.Conflicts == nil {
+		m.Conflicts = []model.MergeConflict{}
+	}
+
+	if result.Unresolved() {
+		pending, err := h.BranchRepository.UpdateMerge(c, m)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error saving merge"})
+			return
+		}
+		if !pending {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Merge already applied"})
+			return
+		}
+		c.JSON(http.StatusConflict, m)
+		return
+	}
+
+	backup, err := h.VersionRepository.CreateVersion(c, document, fmt.Sprintf("Before merging %q", branch.Title), model.VersionKindAuto, m.CreatedBy)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error saving the current version"})
+		return
+	}
+
+	merged := *document
+	merged.Slides = result.Slides
+	version, err := h.VersionRepository.CreateVersion(c, &merged, fmt.Sprintf("Merged %q", branch.Title), model.VersionKindAuto, m.CreatedBy)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error saving the merged version"})
+		return
+	}
+
+	// applied before the reset, a concurrent apply of the same merge stops here
+	now := time.Now()
+	claimed, err := h.BranchRepository.ClaimMerge(c, m.ID, now, backup.ID.Hex())
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error saving merge"})
+		return
+	}
+	if !claimed {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Merge already applied"})
+		return
+	}
+
+	reset := types.DocumentResetPostData{VersionID: version.ID.Hex(), UserID: m.CreatedBy, Slides: result.Slides, BaseVersion: liveVersion}
+	if err := resetLiveDocument(branch.DocumentID, reset); err != nil {
+		if releaseErr := h.BranchRepository.ReleaseMerge(c, m.ID); releaseErr != nil {
+			fmt.Printf("[DocumentHandler][applyMerge] %v\n", releaseErr)
+		}
+		if errors.Is(err, errDocumentChanged) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "The document changed while merging, try again"})
+			return
+		}
+		fmt.Printf("[DocumentHandler][applyMerge] %v\n", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "Error applying merge"})
+		return
+	}
+
+	m.Status = model.MergeStatusApplied
+	m.AppliedAt = &now
+	m.BackupVersionID = backup.ID.Hex()
+	if err := h.BranchRepository.MarkBranchMerged(c, branch.ID, now); err != nil {
+		fmt.Printf("[DocumentHandler][applyMerge] %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, m)
+}
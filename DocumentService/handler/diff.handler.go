@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"document-service/diff"
+	"document-service/model"
+	"document-service/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentState names the live document where a version id is expected
+const currentState = "current"
+
+// loadState returns the document as it is (ref "current") or as a version of it
+func (h DocumentHandler) loadState(c *gin.Context, document *model.Document, ref string) (*model.Document, bool) {
+	if ref == currentState {
+		return document, true
+	}
+
+	version, err := h.VersionRepository.FindVersionByID(c, document.ID.Hex(), ref)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid version id"})
+		return nil, false
+	}
+	if version == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return nil, false
+	}
+
+	state := *document
+	state.Title = version.Title
+	state.Slides = version.Slides
+	return &state, true
+}
+
+// Route: GET /document/id/:id/diff?from=&to=
+// Compares two versions of the document, or a version and the current document when
+// to is "current" (the default). Lists added, removed and reordered slides and added,
+// removed and modified objects with the before and after values of their attributes.
+func (h DocumentHandler) GetDiff(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, false) {
+		return
+	}
+
+	from := c.Query("from")
+	to := c.DefaultQuery("to", currentState)
+	if from == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "from is required"})
+		return
+	}
+
+	document, err := h.DocumentRepository.FindDocumentByID(c.Request.Context(), docID)
+	if err != nil || document == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	before, ok := h.loadState(c, document, from)
+	if !ok {
+		return
+	}
+	after, ok := h.loadState(c, document, to)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, types.DiffDto{From: from, To: to, DocumentDiff: diff.Documents(before, after)})
+}
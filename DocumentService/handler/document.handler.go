@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"Shared/geometry"
+	"document-service/model"
 	"document-service/repository"
 	"document-service/types"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +17,9 @@ import (
 
 type DocumentHandler struct {
 	DocumentRepository *repository.DocumentRepository
+	VersionRepository  *repository.VersionRepository
+	OplogRepository    *repository.OplogRepository
+	BranchRepository   *repository.BranchRepository
 }
 
 // Helper to get authenticated UserID (assuming it's set in a middleware header)
@@ -70,13 +77,39 @@ func (h DocumentHandler) CreateNewDocument(c *gin.Context) {
 		return
 	}
 
+	// The body is optional, documents are slide decks unless asked otherwise
+	data := types.CreateDocumentPostData{Kind: model.DocumentKindSlides}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&data); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid data format"})
+			return
+		}
+	}
+	if data.Kind == "" {
+		data.Kind = model.DocumentKindSlides
+	}
+	if data.Kind != model.DocumentKindSlides && data.Kind != model.DocumentKindWhiteboard {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Unknown document kind"})
+		return
+	}
+
 	// Create document
-	createdDoc, err := h.DocumentRepository.CreateNewDocument(c, "Untitled", userId)
+	createdDoc, err := h.DocumentRepository.CreateNewDocument(c, "Untitled", userId, data.Kind)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error creating document"})
 		return
 	}
 
+	// the empty document is the base playback starts from, a document without it can't be played back
+	if _, err := h.VersionRepository.CreateVersion(c, &createdDoc, "Created", model.VersionKindAuto, userId); err != nil {
+		fmt.Printf("[DocumentHandler][CreateNewDocument] Error creating the initial version: %v\n", err)
+		if err := h.DocumentRepository.DeleteDocument(c, createdDoc.ID.Hex()); err != nil {
+			fmt.Printf("[DocumentHandler][CreateNewDocument] Error deleting the document: %v\n", err)
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error creating document"})
+		return
+	}
+
 	response := types.CreatedResponse{ID: createdDoc.ID.Hex()}
 
 	c.JSON(http.StatusCreated, response) // Use 201 Created status
@@ -167,6 +200,7 @@ func (h DocumentHandler) DeleteDocument(c *gin.Context) {
 }
 
 // Route: GET /document/:id
+// Query: points=verbose returns pen points as plain number arrays instead of the compact encoding
 func (h DocumentHandler) GetDocumentByID(c *gin.Context) {
 	// 1. Get Path Parameter
 	docID := c.Param("id")
@@ -198,6 +232,69 @@ func (h DocumentHandler) GetDocumentByID(c *gin.Context) {
 	// 5. Authorization Check (if not owner, check sharing)
 	// Add logic here to check if userID is the owner or in shared list
 
-	// 6. Return Document
+	// 6. Pen points are stored compact, decode them for clients asking for ?points=verbose
+	if c.Query("points") == "verbose" {
+		if err := geometry.ExpandPenPoints(document); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error decoding pen points"})
+			return
+		}
+	}
+
+	// 7. Return Document
 	c.JSON(http.StatusOK, document)
 }
+
+// Route: GET /document/id/:id/viewport?x=&y=&width=&height=
+// Returns the objects of a whiteboard whose bounds intersect the viewport so clients
+// of large boards only load what they show. Query: points=verbose as for GetDocumentByID.
+func (h DocumentHandler) GetViewport(c *gin.Context) {
+	docID := c.Param("id")
+	if docID == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Document ID is required in the path"})
+		return
+	}
+
+	var viewport geometry.Rect
+	for _, param := range []struct {
+		name  string
+		value *float64
+	}{{"x", &viewport.X}, {"y", &viewport.Y}, {"width", &viewport.Width}, {"height", &viewport.Height}} {
+		v, err := strconv.ParseFloat(c.Query(param.name), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid or missing %s", param.name)})
+			return
+		}
+		*param.value = v
+	}
+	if viewport.Width < 0 || viewport.Height < 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Viewport size must not be negative"})
+		return
+	}
+
+	document, err := h.DocumentRepository.FindDocumentByID(c.Request.Context(), docID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving document"})
+		return
+	}
+	if document == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	if document.Kind != model.DocumentKindWhiteboard || len(document.Slides) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Viewports are only available for whiteboards"})
+		return
+	}
+
+	canvas := document.Slides[0]
+	result := types.ViewportDto{SlideID: canvas.ID, Objects: geometry.ObjectsInViewport(canvas.Objects, viewport)}
+
+	if c.Query("points") == "verbose" {
+		if err := geometry.ExpandObjects(result.Objects); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error decoding pen points"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"Shared/geometry"
+	"document-service/model"
+	"document-service/playback"
+	"document-service/types"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Playback limits
+const (
+	MaxPlaybackOps   = 5000 // ops returned for one range
+	MaxPlaybackSpeed = 1000
+)
+
+// parseTime reads an RFC 3339 timestamp from the query, aborting the request when it is invalid
+func parseTime(c *gin.Context, name string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, c.Query(name))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid or missing %s, expected an RFC 3339 timestamp", name)})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// stateAt rebuilds the document as it was at the given time: the last version taken
+// before it with the recorded ops applied after that version up to the time
+func (h DocumentHandler) stateAt(c *gin.Context, docID string, at time.Time) (*model.Document, int64, bool) {
+	current, err := h.DocumentRepository.FindDocumentByID(c.Request.Context(), docID)
+	if err != nil || current == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return nil, 0, false
+	}
+
+	seq, err := h.OplogRepository.FindSeqAt(c, docID, at)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving the history of the document"})
+		return nil, 0, false
+	}
+
+	base, err := h.VersionRepository.FindBaseVersion(c, docID, seq)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving versions"})
+		return nil, 0, false
+	}
+	if base == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "No snapshot was recorded before this time"})
+		return nil, 0, false
+	}
+
+	entries, err := h.OplogRepository.FindEntries(c, docID, base.Seq, seq, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving the history of the document"})
+		return nil, 0, false
+	}
+
+	// kind and owner don't change, the rest comes from the version
+	document := *current
+	document.Title = base.Title
+	document.Slides = base.Slides
+	playback.Replay(&document, entries)
+	return &document, seq, true
+}
+
+// Route: GET /document/id/:id/playback/state?at=
+// Returns the document as it was at the given time. Query: points=verbose as for GetDocumentByID.
+func (h DocumentHandler) GetPlaybackState(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, false) {
+		return
+	}
+
+	at, ok := parseTime(c, "at")
+	if !ok {
+		return
+	}
+
+	document, seq, ok := h.stateAt(c, docID, at)
+	if !ok {
+		return
+	}
+
+	if c.Query("points") == "verbose" {
+		if err := geometry.ExpandPenPoints(document); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error decoding pen points"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, types.PlaybackStateDto{At: at, Seq: seq, Document: document})
+}
+
+// Route: GET /document/id/:id/playback/ops?from=&to=&speed=
+// Returns the document at from and the ops applied until to, each with the delay
+// after which a player running at speed (1 by default) applies it. The UpdatesService
+// playback websocket streams the same data.
+func (h DocumentHandler) GetPlaybackOps(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, false) {
+		return
+	}
+
+	from, ok := parseTime(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTime(c, "to")
+	if !ok {
+		return
+	}
+	if to.Before(from) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	speed := 1.0
+	if raw := c.Query("speed"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) || v <= 0 || v > MaxPlaybackSpeed {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("speed must be a number in (0, %d]", MaxPlaybackSpeed)})
+			return
+		}
+		speed = v
+	}
+
+	document, seq, ok := h.stateAt(c, docID, from)
+	if !ok {
+		return
+	}
+
+	lastSeq, err := h.OplogRepository.FindSeqAt(c, docID, to)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving the history of the document"})
+		return
+	}
+
+	// one more than the limit tells whether the range was cut
+	entries, err := h.OplogRepository.FindEntries(c, docID, seq, lastSeq, MaxPlaybackOps+1)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving the history of the document"})
+		return
+	}
+
+	result := types.PlaybackOpsDto{From: from, To: to, Speed: speed, Seq: seq, Document: document, Ops: []types.PlaybackOp{}}
+	if len(entries) > MaxPlaybackOps {
+		entries = entries[:MaxPlaybackOps]
+		result.Truncated = true
+	}
+	for _, entry := range entries {
+		// kafka timestamps of concurrent producers may be slightly out of seq order
+		offset := entry.Timestamp.Sub(from).Milliseconds()
+		if offset < 0 {
+			offset = 0
+		}
+		result.Ops = append(result.Ops, types.PlaybackOp{OplogEntry: entry, Offset: offset, Delay: int64(float64(offset) / speed)})
+	}
+
+	if c.Query("points") == "verbose" {
+		if err := geometry.ExpandPenPoints(document); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error decoding pen points"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
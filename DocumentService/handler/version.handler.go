@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"Shared/geometry"
+	"bytes"
+	"document-service/model"
+	"document-service/repository"
+	"document-service/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// updatesServiceURL is the internal api of UpdatesService, it has its own port which is
+// only reachable from the other services
+const updatesServiceURL = "http://canvas-live-updates-service:8084/internal"
+
+// MaxVersionNameLength caps the name of named versions
+const MaxVersionNameLength = 256
+
+// checkAccess aborts the request unless the user may read (or, with edit, change) the document
+func (h DocumentHandler) checkAccess(c *gin.Context, userId string, docID string, edit bool) bool {
+	access, err := h.DocumentRepository.FindAccessType(c, userId, docID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return false
+	}
+
+	allowed := access == repository.AccessOwner || access == repository.AccessEditor
+	if !edit {
+		allowed = allowed || access == repository.AccessViewer
+	}
+	if !allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You don't have access to this document"})
+		return false
+	}
+	return true
+}
+
+// Route: POST /document/id/:id/versions
+// Saves the current slides of the document as a named version
+func (h DocumentHandler) CreateVersion(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, true) {
+		return
+	}
+
+	var data types.CreateVersionPostData
+	if err := c.ShouldBindJSON(&data); err != nil || len(data.Name) > MaxVersionNameLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid data format or missing fields"})
+		return
+	}
+
+	document, err := h.DocumentRepository.FindDocumentByID(c.Request.Context(), docID)
+	if err != nil || document == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving document"})
+		return
+	}
+
+	version, err := h.VersionRepository.CreateVersion(c, document, data.Name, model.VersionKindNamed, userId)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error creating version"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.CreatedResponse{ID: version.ID.Hex()})
+}
+
+// Route: GET /document/id/:id/versions
+// Lists the named versions and automatic snapshots of the document, newest first
+func (h DocumentHandler) GetVersions(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, false) {
+		return
+	}
+
+	versions, err := h.VersionRepository.FindVersions(c, docID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// Route: GET /document/id/:id/versions/:versionId
+// Returns a version with its slides for previews. Query: points=verbose as for GetDocumentByID.
+func (h DocumentHandler) GetVersion(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, false) {
+		return
+	}
+
+	version, err := h.VersionRepository.FindVersionByID(c, docID, c.Param("versionId"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid version id"})
+		return
+	}
+	if version == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	if c.Query("points") == "verbose" {
+		for i := range version.Slides {
+			if err := geometry.ExpandObjects(version.Slides[i].Objects); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error decoding pen points"})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// Route: POST /document/id/:id/versions/:versionId/restore
+// Replaces the slides of the document by the ones of the version. The current slides
+// are saved as an automatic version first, then UpdatesService resets the live document:
+// connected clients receive a document_reset and the reset is persisted in op order.
+// The live document is flushed before the current slides are read, a change made
+// after that fails the restore (409) instead of missing from the backup.
+func (h DocumentHandler) RestoreVersion(c *gin.Context) {
+	userId, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+
+	docID := c.Param("id")
+	if !h.checkAccess(c, userId, docID, true) {
+		return
+	}
+
+	version, err := h.VersionRepository.FindVersionByID(c, docID, c.Param("versionId"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid version id"})
+		return
+	}
+	if version == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	liveVersion, err := flushLiveDocument(docID)
+	if err != nil {
+		fmt.Printf("[DocumentHandler][RestoreVersion] %v\n", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "Error saving the live document"})
+		return
+	}
+
+	document, err := h.DocumentRepository.FindDocumentByID(c.Request.Context(), docID)
+	if err != nil || document == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving document"})
+		return
+	}
+
+	backup, err := h.VersionRepository.CreateVersion(c, document, fmt.Sprintf("Before restoring %q", version.Name), model.VersionKindAuto, userId)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error saving the current version"})
+		return
+	}
+
+	reset := types.DocumentResetPostData{VersionID: version.ID.Hex(), UserID: userId, Slides: version.Slides, BaseVersion: liveVersion}
+	if err := resetLiveDocument(docID, reset); errors.Is(err, errDocumentChanged) {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "The document changed while restoring, try again"})
+		return
+	} else if err != nil {
+		fmt.Printf("[DocumentHandler][RestoreVersion] %v\n", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "Error restoring version"})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.RestoreVersionDto{BackupVersionID: backup.ID.Hex()})
+}
+
+// errDocumentChanged is returned by resetLiveDocument when the live document applied
+// changes since the base version of the reset
+var errDocumentChanged = errors.New("the live document changed")
+
+// flushLiveDocument waits until UpdatesService has persisted the ops of the document
+// and returns the version of the live document then. The document read afterwards is
+// the live one as long as the version doesn't change, see resetLiveDocument.
+func flushLiveDocument(docID string) (uint64, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(fmt.Sprintf("%s/document/%s/flush", updatesServiceURL, docID), "application/json", nil)
+	if err != nil {
+		return 0, fmt.Errorf("flush request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var flushed types.DocumentFlushDto
+	if err := json.NewDecoder(resp.Body).Decode(&flushed); err != nil {
+		return 0, fmt.Errorf("failed to decode flush: %w", err)
+	}
+	return flushed.Version, nil
+}
+
+// resetLiveDocument asks UpdatesService to replace the slides of the document. It
+// fails with errDocumentChanged when the live document moved past reset.BaseVersion.
+func resetLiveDocument(docID string, reset types.DocumentResetPostData) error {
+	body, err := json.Marshal(reset)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reset: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(fmt.Sprintf("%s/document/%s/reset", updatesServiceURL, docID), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("reset request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return errDocumentChanged
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -26,8 +26,32 @@ func main() {
 		config.MongoConfig.SharedDocRecordCollectionName,
 	)
 
+	VersionRepository := repository.NewVersionRepository(
+		client,
+		config.MongoConfig.DatabaseName,
+		config.MongoConfig.VersionCollectionName,
+	)
+
+	OplogRepository := repository.NewOplogRepository(
+		client,
+		config.MongoConfig.DatabaseName,
+		config.MongoConfig.OplogCollectionName,
+	)
+
+	BranchRepository := repository.NewBranchRepository(
+		client,
+		config.MongoConfig.DatabaseName,
+		config.MongoConfig.BranchCollectionName,
+		config.MongoConfig.MergeCollectionName,
+	)
+
 	// Set up Handlers
-	documentHandler := handler.DocumentHandler{DocumentRepository: DocumentRepository}
+	documentHandler := handler.DocumentHandler{
+		DocumentRepository: DocumentRepository,
+		VersionRepository:  VersionRepository,
+		OplogRepository:    OplogRepository,
+		BranchRepository:   BranchRepository,
+	}
 
 	// ===============================================
 	// GIN ROUTER SETUP
@@ -58,6 +82,48 @@ func main() {
 
 		// GET /document/id/:id
 		documentGroup.GET("/id/:id", documentHandler.GetDocumentByID)
+
+		// GET /document/id/:id/viewport
+		documentGroup.GET("/id/:id/viewport", documentHandler.GetViewport)
+
+		// POST /document/id/:id/versions
+		documentGroup.POST("/id/:id/versions", documentHandler.CreateVersion)
+
+		// GET /document/id/:id/versions
+		documentGroup.GET("/id/:id/versions", documentHandler.GetVersions)
+
+		// GET /document/id/:id/versions/:versionId
+		documentGroup.GET("/id/:id/versions/:versionId", documentHandler.GetVersion)
+
+		// POST /document/id/:id/versions/:versionId/restore
+		documentGroup.POST("/id/:id/versions/:versionId/restore", documentHandler.RestoreVersion)
+
+		// GET /document/id/:id/playback/state
+		documentGroup.GET("/id/:id/playback/state", documentHandler.GetPlaybackState)
+
+		// GET /document/id/:id/playback/ops
+		documentGroup.GET("/id/:id/playback/ops", documentHandler.GetPlaybackOps)
+
+		// GET /document/id/:id/diff
+		documentGroup.GET("/id/:id/diff", documentHandler.GetDiff)
+
+		// POST /document/id/:id/branches
+		documentGroup.POST("/id/:id/branches", documentHandler.CreateBranch)
+
+		// GET /document/id/:id/branches
+		documentGroup.GET("/id/:id/branches", documentHandler.GetBranches)
+
+		// POST /document/id/:id/branches/:branchId/merge
+		documentGroup.POST("/id/:id/branches/:branchId/merge", documentHandler.MergeBranch)
+
+		// GET /document/id/:id/merges/:mergeId
+		documentGroup.GET("/id/:id/merges/:mergeId", documentHandler.GetMerge)
+
+		// POST /document/id/:id/merges/:mergeId/resolve
+		documentGroup.POST("/id/:id/merges/:mergeId/resolve", documentHandler.ResolveMerge)
+
+		// POST /document/id/:id/merges/:mergeId/apply
+		documentGroup.POST("/id/:id/merges/:mergeId/apply", documentHandler.ApplyMerge)
 	}
 
 	// Optional: Simple health check route
@@ -0,0 +1,418 @@
+// Package merge merges the slides of a branch back into the document it was
+// branched from, against the version the branch started at
+package merge
+
+import (
+	"document-service/diff"
+	"document-service/model"
+	"sort"
+)
+
+// Resolutions of a conflict
+const (
+	Ours   = "ours"   // keep the document
+	Theirs = "theirs" // take the branch
+)
+
+// Kinds of conflicts
+const (
+	KindModified = "modified" // both sides changed the same properties or attributes differently
+	KindRemoved  = "removed"  // one side removed what the other changed
+)
+
+// Result is the merged slides and the conflicts met. Unresolved conflicts are
+// merged as Ours.
+type Result struct {
+	Slides    []model.Slide
+	Conflicts []model.MergeConflict
+}
+
+// Unresolved tells whether some conflict has no resolution
+func (r Result) Unresolved() bool {
+	for _, c := range r.Conflicts {
+		if c.Resolution == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Slides merges theirs into ours. Changes made on one side only are taken, changes
+// made on both sides are conflicts settled by resolutions (conflict id -> Ours or Theirs).
+func Slides(base, ours, theirs []model.Slide, resolutions map[string]string) Result {
+	var result Result
+
+	baseById, oursById, theirsById := slideMap(base), slideMap(ours), slideMap(theirs)
+	kept := map[string]*model.Slide{}
+
+	for _, id := range unionIds(slideIds(base), slideIds(ours), slideIds(theirs)) {
+		b, o, t := baseById[id], oursById[id], theirsById[id]
+
+		switch {
+		case b == nil:
+			// added on one side (or both, ours wins)
+			if o != nil {
+				kept[id] = o
+			} else {
+				kept[id] = t
+			}
+
+		case o == nil && t == nil:
+
+		case o == nil || t == nil:
+			// removed on one side, a conflict when the other side changed it
+			remaining := o
+			if remaining == nil {
+				remaining = t
+			}
+			if diff.Equal(b, remaining) {
+				continue
+			}
+			conflict := model.MergeConflict{ID: id, SlideID: id, Kind: KindRemoved, Resolution: resolutions[id]}
+			result.Conflicts = append(result.Conflicts, conflict)
+			if (conflict.Resolution == Theirs) == (t != nil) {
+				kept[id] = remaining
+			}
+
+		default:
+			slide, conflicts := mergeSlide(b, o, t, resolutions)
+			result.Conflicts = append(result.Conflicts, conflicts...)
+			kept[id] = &slide
+		}
+	}
+
+	for _, id := range mergeOrder(slideIds(base), slideIds(ours), slideIds(theirs), kept) {
+		result.Slides = append(result.Slides, *kept[id])
+	}
+	if result.Slides == nil {
+		result.Slides = []model.Slide{}
+	}
+	return result
+}
+
+// mergeSlide merges the properties and top-level objects of a slide both sides kept
+func mergeSlide(b, o, t *model.Slide, resolutions map[string]string) (model.Slide, []model.MergeConflict) {
+	var conflicts []model.MergeConflict
+	merged := *o
+
+	slideConflict := model.MergeConflict{ID: o.ID, SlideID: o.ID, Kind: KindModified, Resolution: resolutions[o.ID]}
+	baseProps, oursProps, theirsProps := slideProperties(b), slideProperties(o), slideProperties(t)
+	mergedProps := map[string]interface{}{}
+	for key := range oursProps {
+		value, conflict := pick(baseProps[key], oursProps[key], theirsProps[key])
+		if conflict {
+			if slideConflict.Properties == nil {
+				slideConflict.Properties = map[string]model.MergeValues{}
+			}
+			slideConflict.Properties[key] = model.MergeValues{Base: baseProps[key], Ours: oursProps[key], Theirs: theirsProps[key]}
+			if slideConflict.Resolution == Theirs {
+				value = theirsProps[key]
+			}
+		}
+		mergedProps[key] = value
+	}
+	setSlideProperties(&merged, mergedProps)
+	if slideConflict.Properties != nil {
+		conflicts = append(conflicts, slideConflict)
+	}
+
+	baseById, oursById, theirsById := objectMap(b.Objects), objectMap(o.Objects), objectMap(t.Objects)
+	kept := map[string]*model.Object{}
+	for _, id := range unionIds(objectIds(b.Objects), objectIds(o.Objects), objectIds(t.Objects)) {
+		bo, oo, to := baseById[id], oursById[id], theirsById[id]
+		conflictId := o.ID + "/" + id
+
+		switch {
+		case bo == nil:
+			if oo != nil {
+				kept[id] = oo
+			} else {
+				kept[id] = to
+			}
+
+		case oo == nil && to == nil:
+
+		case oo == nil || to == nil:
+			remaining := oo
+			if remaining == nil {
+				remaining = to
+			}
+			if diff.Equal(bo, remaining) {
+				continue
+			}
+			conflict := model.MergeConflict{ID: conflictId, SlideID: o.ID, ObjectID: id, Kind: KindRemoved, Resolution: resolutions[conflictId]}
+			conflicts = append(conflicts, conflict)
+			if (conflict.Resolution == Theirs) == (to != nil) {
+				kept[id] = remaining
+			}
+
+		default:
+			obj, conflict := mergeObject(bo, oo, to, resolutions[conflictId])
+			if conflict != nil {
+				conflict.ID, conflict.SlideID = conflictId, o.ID
+				conflicts = append(conflicts, *conflict)
+			}
+			kept[id] = &obj
+		}
+	}
+
+	merged.Objects = make([]model.Object, 0, len(kept))
+	for _, id := range mergeOrder(objectIds(b.Objects), objectIds(o.Objects), objectIds(t.Objects), kept) {
+		merged.Objects = append(merged.Objects, *kept[id])
+	}
+	return merged, conflicts
+}
+
+// mergeObject merges an object both sides kept. Children of groups and frames are
+// merged as a whole.
+func mergeObject(b, o, t *model.Object, resolution string) (model.Object, *model.MergeConflict) {
+	conflict := &model.MergeConflict{ObjectID: o.ID, Kind: KindModified, Resolution: resolution}
+	merged := *o
+
+	properties := []struct {
+		name    string
+		b, o, t interface{}
+	}{
+		{"type", b.Type, o.Type, t.Type},
+		{"layerId", b.LayerID, o.LayerID, t.LayerID},
+		{"children", b.Children, o.Children, t.Children},
+	}
+	for _, p := range properties {
+		value, conflicting := pick(p.b, p.o, p.t)
+		if conflicting {
+			if conflict.Properties == nil {
+				conflict.Properties = map[string]model.MergeValues{}
+			}
+			conflict.Properties[p.name] = model.MergeValues{Base: p.b, Ours: p.o, Theirs: p.t}
+			if resolution == Theirs {
+				value = p.t
+			}
+		}
+		switch p.name {
+		case "type":
+			merged.Type = value.(string)
+		case "layerId":
+			merged.LayerID = value.(string)
+		case "children":
+			merged.Children = value.([]model.Object)
+		}
+	}
+
+	merged.Attributes = map[string]interface{}{}
+	merged.Stamps = copyStamps(o.Stamps)
+	for _, key := range attributeKeys(o.Attributes, t.Attributes) {
+		bv, inBase := b.Attributes[key]
+		ov, inOurs := o.Attributes[key]
+		tv, inTheirs := t.Attributes[key]
+
+		useTheirs := false
+		switch {
+		case inOurs == inTheirs && diff.Equal(ov, tv):
+		case inBase == inOurs && diff.Equal(bv, ov):
+			useTheirs = true
+		case inBase == inTheirs && diff.Equal(bv, tv):
+		default:
+			if conflict.Attributes == nil {
+				conflict.Attributes = map[string]model.MergeValues{}
+			}
+			conflict.Attributes[key] = model.MergeValues{Base: bv, Ours: ov, Theirs: tv}
+			useTheirs = resolution == Theirs
+		}
+
+		if !useTheirs {
+			if inOurs {
+				merged.Attributes[key] = ov
+			}
+			continue
+		}
+		if inTheirs {
+			merged.Attributes[key] = tv
+		}
+		if stamp, ok := t.Stamps[key]; ok {
+			if merged.Stamps == nil {
+				merged.Stamps = map[string]string{}
+			}
+			merged.Stamps[key] = stamp
+		} else {
+			delete(merged.Stamps, key)
+		}
+	}
+
+	if conflict.Properties == nil && conflict.Attributes == nil {
+		return merged, nil
+	}
+	return merged, conflict
+}
+
+// pick returns the three-way merge of a value and whether both sides changed it
+// differently, in which case ours is returned
+func pick(b, o, t interface{}) (interface{}, bool) {
+	switch {
+	case diff.Equal(o, t), diff.Equal(b, t):
+		return o, false
+	case diff.Equal(b, o):
+		return t, false
+	}
+	return o, true
+}
+
+// mergeOrder orders the ids kept by the merge. The side which reordered the items
+// both it and the base have gives the order (ours when both did), the items the
+// other side added follow the item they follow there.
+func mergeOrder[T any](base, ours, theirs []string, kept map[string]*T) []string {
+	primary, secondary := ours, theirs
+	if reordered(base, theirs) && !reordered(base, ours) {
+		primary, secondary = theirs, ours
+	}
+
+	var order []string
+	placed := map[string]bool{}
+	for _, id := range primary {
+		if kept[id] != nil {
+			order = append(order, id)
+			placed[id] = true
+		}
+	}
+
+	insertAt := 0
+	for _, id := range secondary {
+		if placed[id] {
+			for i := range order {
+				if order[i] == id {
+					insertAt = i + 1
+				}
+			}
+			continue
+		}
+		if kept[id] == nil {
+			continue
+		}
+		order = append(order, "")
+		copy(order[insertAt+1:], order[insertAt:])
+		order[insertAt] = id
+		placed[id] = true
+		insertAt++
+	}
+	return order
+}
+
+// reordered tells whether the items side has in common with base are in another order
+func reordered(base, side []string) bool {
+	inSide := map[string]bool{}
+	for _, id := range side {
+		inSide[id] = true
+	}
+	inBase := map[string]bool{}
+	var common []string
+	for _, id := range base {
+		inBase[id] = true
+		if inSide[id] {
+			common = append(common, id)
+		}
+	}
+
+	i := 0
+	for _, id := range side {
+		if !inBase[id] {
+			continue
+		}
+		if common[i] != id {
+			return true
+		}
+		i++
+	}
+	return false
+}
+
+func unionIds(lists ...[]string) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, list := range lists {
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func slideIds(slides []model.Slide) []string {
+	ids := make([]string, len(slides))
+	for i := range slides {
+		ids[i] = slides[i].ID
+	}
+	return ids
+}
+
+func slideMap(slides []model.Slide) map[string]*model.Slide {
+	m := make(map[string]*model.Slide, len(slides))
+	for i := range slides {
+		m[slides[i].ID] = &slides[i]
+	}
+	return m
+}
+
+func objectIds(objects []model.Object) []string {
+	ids := make([]string, len(objects))
+	for i := range objects {
+		ids[i] = objects[i].ID
+	}
+	return ids
+}
+
+func objectMap(objects []model.Object) map[string]*model.Object {
+	m := make(map[string]*model.Object, len(objects))
+	for i := range objects {
+		m[objects[i].ID] = &objects[i]
+	}
+	return m
+}
+
+func slideProperties(s *model.Slide) map[string]interface{} {
+	return map[string]interface{}{
+		"background":      s.Background,
+		"backgroundImage": s.BackgroundImage,
+		"title":           s.Title,
+		"notes":           s.Notes,
+		"width":           s.Width,
+		"height":          s.Height,
+		"layers":          s.Layers,
+	}
+}
+
+func setSlideProperties(s *model.Slide, props map[string]interface{}) {
+	s.Background = props["background"].(string)
+	s.BackgroundImage = props["backgroundImage"].(string)
+	s.Title = props["title"].(string)
+	s.Notes = props["notes"].(string)
+	s.Width = props["width"].(float64)
+	s.Height = props["height"].(float64)
+	s.Layers = props["layers"].([]model.Layer)
+}
+
+func attributeKeys(ours map[string]interface{}, theirs map[string]interface{}) []string {
+	keys := make([]string, 0, len(ours)+len(theirs))
+	for key := range ours {
+		keys = append(keys, key)
+	}
+	for key := range theirs {
+		if _, ok := ours[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyStamps(stamps map[string]string) map[string]string {
+	if stamps == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(stamps))
+	for key, stamp := range stamps {
+		copied[key] = stamp
+	}
+	return copied
+}
@@ -0,0 +1,198 @@
+package merge
+
+import (
+	"document-service/model"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func obj(id string, attr map[string]interface{}) model.Object {
+	return model.Object{ID: id, Type: "rect", Attributes: attr}
+}
+
+// baseSlides is the version the branch started at: s1 holds a and b, s2 is empty
+func baseSlides() []model.Slide {
+	return []model.Slide{
+		{ID: "s1", Background: "#fff", Objects: []model.Object{
+			obj("a", map[string]interface{}{"x": 1.0, "fill": "red"}),
+			obj("b", map[string]interface{}{"x": 2.0}),
+		}},
+		{ID: "s2", Background: "#fff", Objects: []model.Object{}},
+	}
+}
+
+// describe lists "slide[background]" then "slide/object{attributes}" for the merged
+// slides, attribute keys sorted
+func describe(slides []model.Slide) []string {
+	out := []string{}
+	for _, s := range slides {
+		out = append(out, fmt.Sprintf("%s[%s]", s.ID, s.Background))
+		for _, o := range s.Objects {
+			keys := make([]string, 0, len(o.Attributes))
+			for key := range o.Attributes {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			attrs := ""
+			for _, key := range keys {
+				attrs += fmt.Sprintf(" %s=%v", key, o.Attributes[key])
+			}
+			out = append(out, fmt.Sprintf("%s/%s{%s }", s.ID, o.ID, attrs))
+		}
+	}
+	return out
+}
+
+func conflictsOf(r Result) []string {
+	out := []string{}
+	for _, c := range r.Conflicts {
+		line := c.ID + ":" + c.Kind
+		for key := range c.Properties {
+			line += ":" + key
+		}
+		for key := range c.Attributes {
+			line += ":" + key
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func TestSlides(t *testing.T) {
+	tests := []struct {
+		name          string
+		ours, theirs  func(s []model.Slide) []model.Slide
+		resolutions   map[string]string
+		want          []string
+		wantConflicts []string
+		unresolved    bool
+	}{
+		{
+			name:          "no changes",
+			ours:          func(s []model.Slide) []model.Slide { return s },
+			theirs:        func(s []model.Slide) []model.Slide { return s },
+			want:          []string{"s1[#fff]", "s1/a{ fill=red x=1 }", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{},
+		},
+		{
+			name:          "different attributes of an object",
+			ours:          func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 5.0; return s },
+			theirs:        func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["fill"] = "blue"; return s },
+			want:          []string{"s1[#fff]", "s1/a{ fill=blue x=5 }", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{},
+		},
+		{
+			name:          "same change on both sides",
+			ours:          func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 5.0; return s },
+			theirs:        func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 5.0; return s },
+			want:          []string{"s1[#fff]", "s1/a{ fill=red x=5 }", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{},
+		},
+		{
+			name:          "attribute conflict, unresolved keeps ours",
+			ours:          func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 5.0; return s },
+			theirs:        func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 7.0; return s },
+			want:          []string{"s1[#fff]", "s1/a{ fill=red x=5 }", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{"s1/a:modified:x"},
+			unresolved:    true,
+		},
+		{
+			name:          "attribute conflict resolved as theirs",
+			ours:          func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 5.0; return s },
+			theirs:        func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 7.0; return s },
+			resolutions:   map[string]string{"s1/a": Theirs},
+			want:          []string{"s1[#fff]", "s1/a{ fill=red x=7 }", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{"s1/a:modified:x"},
+		},
+		{
+			name:          "attribute deleted on one side",
+			ours:          func(s []model.Slide) []model.Slide { return s },
+			theirs:        func(s []model.Slide) []model.Slide { delete(s[0].Objects[0].Attributes, "fill"); return s },
+			want:          []string{"s1[#fff]", "s1/a{ x=1 }", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{},
+		},
+		{
+			name:          "object removed on one side",
+			ours:          func(s []model.Slide) []model.Slide { return s },
+			theirs:        func(s []model.Slide) []model.Slide { s[0].Objects = s[0].Objects[1:]; return s },
+			want:          []string{"s1[#fff]", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{},
+		},
+		{
+			name:          "object removed by ours and changed by theirs, unresolved",
+			ours:          func(s []model.Slide) []model.Slide { s[0].Objects = s[0].Objects[1:]; return s },
+			theirs:        func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 7.0; return s },
+			want:          []string{"s1[#fff]", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{"s1/a:removed"},
+			unresolved:    true,
+		},
+		{
+			name:          "object removed by ours and changed by theirs, resolved as theirs",
+			ours:          func(s []model.Slide) []model.Slide { s[0].Objects = s[0].Objects[1:]; return s },
+			theirs:        func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 7.0; return s },
+			resolutions:   map[string]string{"s1/a": Theirs},
+			want:          []string{"s1[#fff]", "s1/a{ fill=red x=7 }", "s1/b{ x=2 }", "s2[#fff]"},
+			wantConflicts: []string{"s1/a:removed"},
+		},
+		{
+			name: "objects added on both sides",
+			ours: func(s []model.Slide) []model.Slide { s[0].Objects = append(s[0].Objects, obj("c", nil)); return s },
+			theirs: func(s []model.Slide) []model.Slide {
+				s[0].Objects = append(s[0].Objects[:1:1], obj("d", nil), s[0].Objects[1])
+				return s
+			},
+			want:          []string{"s1[#fff]", "s1/a{ fill=red x=1 }", "s1/d{ }", "s1/b{ x=2 }", "s1/c{ }", "s2[#fff]"},
+			wantConflicts: []string{},
+		},
+		{
+			name: "objects reordered by theirs",
+			ours: func(s []model.Slide) []model.Slide { s[0].Objects[0].Attributes["x"] = 5.0; return s },
+			theirs: func(s []model.Slide) []model.Slide {
+				s[0].Objects[0], s[0].Objects[1] = s[0].Objects[1], s[0].Objects[0]
+				return s
+			},
+			want:          []string{"s1[#fff]", "s1/b{ x=2 }", "s1/a{ fill=red x=5 }", "s2[#fff]"},
+			wantConflicts: []string{},
+		},
+		{
+			name:          "slide property conflict",
+			ours:          func(s []model.Slide) []model.Slide { s[1].Background = "#000"; return s },
+			theirs:        func(s []model.Slide) []model.Slide { s[1].Background = "#f00"; return s },
+			resolutions:   map[string]string{"s2": Theirs},
+			want:          []string{"s1[#fff]", "s1/a{ fill=red x=1 }", "s1/b{ x=2 }", "s2[#f00]"},
+			wantConflicts: []string{"s2:modified:background"},
+		},
+		{
+			name:          "slide removed by theirs and changed by ours",
+			ours:          func(s []model.Slide) []model.Slide { s[1].Background = "#000"; return s },
+			theirs:        func(s []model.Slide) []model.Slide { return s[:1] },
+			want:          []string{"s1[#fff]", "s1/a{ fill=red x=1 }", "s1/b{ x=2 }", "s2[#000]"},
+			wantConflicts: []string{"s2:removed"},
+			unresolved:    true,
+		},
+		{
+			name:          "slide added by ours, slides moved by theirs",
+			ours:          func(s []model.Slide) []model.Slide { return append(s, model.Slide{ID: "s3", Background: "#fff"}) },
+			theirs:        func(s []model.Slide) []model.Slide { return []model.Slide{s[1], s[0]} },
+			want:          []string{"s2[#fff]", "s3[#fff]", "s1[#fff]", "s1/a{ fill=red x=1 }", "s1/b{ x=2 }"}, // s3 follows s2 like in ours
+			wantConflicts: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Slides(baseSlides(), tt.ours(baseSlides()), tt.theirs(baseSlides()), tt.resolutions)
+			if got := describe(result.Slides); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("slides: got %v, want %v", got, tt.want)
+			}
+			if got := conflictsOf(result); !reflect.DeepEqual(got, tt.wantConflicts) {
+				t.Errorf("conflicts: got %v, want %v", got, tt.wantConflicts)
+			}
+			if got := result.Unresolved(); got != tt.unresolved {
+				t.Errorf("unresolved: got %v, want %v", got, tt.unresolved)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statuses of branches and merges
+const (
+	BranchStatusOpen   = "open"
+	BranchStatusMerged = "merged"
+
+	MergeStatusPending = "pending" // waiting for conflict resolutions
+	MergeStatusApplied = "applied"
+)
+
+// Branch is a copy of a document edited independently and merged back later.
+// BaseVersionID is the version of the document the copy was made from, merges
+// compare both sides against it.
+type Branch struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	DocumentID       string             `bson:"documentId" json:"documentId"`
+	BranchDocumentID string             `bson:"branchDocumentId" json:"branchDocumentId"`
+	BaseVersionID    string             `bson:"baseVersionId" json:"baseVersionId"`
+	Title            string             `bson:"title" json:"title"`
+	Status           string             `bson:"status" json:"status"`
+	CreatedBy        string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	MergedAt         *time.Time         `bson:"mergedAt,omitempty" json:"mergedAt,omitempty"`
+}
+
+// Merge of a branch into its document. The merge is computed again when it is
+// applied, the stored conflicts are the ones met last time with their resolutions.
+type Merge struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	DocumentID      string             `bson:"documentId" json:"documentId"`
+	BranchID        string             `bson:"branchId" json:"branchId"`
+	Status          string             `bson:"status" json:"status"`
+	Conflicts       []MergeConflict    `bson:"conflicts" json:"conflicts"`
+	CreatedBy       string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+	AppliedAt       *time.Time         `bson:"appliedAt,omitempty" json:"appliedAt,omitempty"`
+	BackupVersionID string             `bson:"backupVersionId,omitempty" json:"backupVersionId,omitempty"` // document before the merge
+}
+
+// MergeValues are the values of a property on the base version, the document (ours)
+// and the branch (theirs). A side is omitted when the property isn't set on it.
+type MergeValues struct {
+	Base   interface{} `bson:"base,omitempty" json:"base,omitempty"`
+	Ours   interface{} `bson:"ours,omitempty" json:"ours,omitempty"`
+	Theirs interface{} `bson:"theirs,omitempty" json:"theirs,omitempty"`
+}
+
+// MergeConflict is a slide or an object changed on both sides. ObjectID is empty for
+// slide conflicts, Properties holds slide properties or the type, layerId and
+// children of an object, Attributes the attributes of an object.
+type MergeConflict struct {
+	ID         string                 `bson:"id" json:"id"` // slideId, or slideId/objectId
+	SlideID    string                 `bson:"slideId" json:"slideId"`
+	ObjectID   string                 `bson:"objectId,omitempty" json:"objectId,omitempty"`
+	Kind       string                 `bson:"kind" json:"kind"`
+	Properties map[string]MergeValues `bson:"properties,omitempty" json:"properties,omitempty"`
+	Attributes map[string]MergeValues `bson:"attributes,omitempty" json:"attributes,omitempty"`
+	Resolution string                 `bson:"resolution,omitempty" json:"resolution,omitempty"` // "ours", "theirs" or empty while unresolved
+}
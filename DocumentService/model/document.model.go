@@ -1,23 +1,16 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import shared "Shared/model"
 
-type Object struct {
-	ID         string                 `bson:"_id" json:"id"`
-	Type       string                 `bson:"type" json:"type"`
-	Attributes map[string]interface{} `bson:"attributes" json:"attributes"`
-}
+// The document model is shared by every service, see Shared/model
+type (
+	Document = shared.Document
+	Slide    = shared.Slide
+	Layer    = shared.Layer
+	Object   = shared.Object
+)
 
-type Slide struct {
-	// ID         string   `bson:"_id,omitemtpy" json:"id,omitempty"`
-	ID         string   `bson:"_id" json:"id"`
-	Background string   `bson:"background" json:"background"`
-	Objects    []Object `bson:"objects" json:"objects"`
-}
-
-type Document struct {
-	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Title   string             `bson:"title" json:"title"`
-	OwnerID string             `bson:"ownerId" json:"ownerId"`
-	Slides  []Slide            `bson:"slides" json:"slides"`
-}
+const (
+	DocumentKindSlides     = shared.DocumentKindSlides
+	DocumentKindWhiteboard = shared.DocumentKindWhiteboard
+)
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of versions. Named versions are created by users, automatic snapshots
+// are taken by the DocumentUpdatesConsumer while a document is being edited.
+const (
+	VersionKindNamed = "named"
+	VersionKindAuto  = "auto"
+)
+
+// Version is a copy of the slides of a document at some point in time
+type Version struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	DocumentID string             `bson:"documentId" json:"documentId"`
+	Name       string             `bson:"name" json:"name"`
+	Kind       string             `bson:"kind" json:"kind"`
+	CreatedBy  string             `bson:"createdBy,omitempty" json:"createdBy,omitempty"` // empty for automatic snapshots
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	Seq        int64              `bson:"seq" json:"seq"` // last oplog seq of the document when the copy was made
+	Title      string             `bson:"title" json:"title"`
+	Slides     []Slide            `bson:"slides,omitempty" json:"slides,omitempty"` // left out when versions are listed
+}
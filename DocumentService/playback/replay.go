@@ -0,0 +1,37 @@
+package playback
+
+import (
+	"Shared/operation"
+	"document-service/model"
+	"encoding/json"
+	"fmt"
+)
+
+// Replay applies recorded ops to a document in seq order. Only ops the consumer
+// managed to apply are recorded, one failing here is logged and skipped.
+func Replay(doc *model.Document, entries []model.OplogEntry) {
+	for _, entry := range entries {
+		if err := Apply(doc, entry.Payload); err != nil {
+			fmt.Printf("[Playback][Replay] op %d of %s: %v\n", entry.Seq, entry.DocumentID, err)
+		}
+	}
+}
+
+// Apply mirrors what the consumer does with each action
+func Apply(doc *model.Document, action map[string]interface{}) error {
+	// the payload is reused by the response, ops change the maps they get
+	return operation.ApplyAction(doc, cloneAction(action))
+}
+
+// cloneAction deep copies a decoded payload
+func cloneAction(action map[string]interface{}) map[string]interface{} {
+	encoded, err := json.Marshal(action)
+	if err != nil {
+		return action
+	}
+	var clone map[string]interface{}
+	if err := json.Unmarshal(encoded, &clone); err != nil {
+		return action
+	}
+	return clone
+}
@@ -0,0 +1,124 @@
+package playback
+
+import (
+	"document-service/model"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func entries(payloads ...map[string]interface{}) []model.OplogEntry {
+	out := make([]model.OplogEntry, len(payloads))
+	for i, payload := range payloads {
+		out[i] = model.OplogEntry{DocumentID: "doc", Seq: int64(i + 1), Action: payload["action"].(string), Payload: payload}
+	}
+	return out
+}
+
+func rect(id string, x float64) map[string]interface{} {
+	return map[string]interface{}{"action": "create", "slideId": "s1", "objectId": id, "objectType": "rect", "attributes": map[string]interface{}{"x": x}}
+}
+
+// summary lists "slide:object=x" for every object, enough to compare replays
+func summary(doc *model.Document) []string {
+	out := []string{}
+	for _, slide := range doc.Slides {
+		out = append(out, slide.ID+":"+slide.Background)
+		for _, obj := range slide.Objects {
+			out = append(out, fmt.Sprintf("%s:%s=%v", slide.ID, obj.ID, obj.Attributes["x"]))
+		}
+	}
+	return out
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []map[string]interface{}
+		want []string
+	}{
+		{
+			name: "slides get the default background",
+			ops:  []map[string]interface{}{{"action": "add_slide", "slideId": "s2"}},
+			want: []string{"s1:#fff", "s2:#fff"},
+		},
+		{
+			name: "create, update and delete",
+			ops: []map[string]interface{}{
+				rect("a", 1),
+				rect("b", 2),
+				{"action": "update", "slideId": "s1", "objectId": "a", "updatedAttributes": map[string]interface{}{"x": 5.0}},
+				{"action": "delete", "slideId": "s1", "objectId": "b"},
+			},
+			want: []string{"s1:#fff", "s1:a=5"},
+		},
+		{
+			name: "failing ops are skipped",
+			ops: []map[string]interface{}{
+				{"action": "update", "slideId": "s1", "objectId": "missing", "updatedAttributes": map[string]interface{}{"x": 5.0}},
+				rect("a", 1),
+			},
+			want: []string{"s1:#fff", "s1:a=1"},
+		},
+		{
+			name: "batch and reorder",
+			ops: []map[string]interface{}{
+				{"action": "batch", "ops": []interface{}{rect("a", 1), rect("b", 2)}},
+				{"action": "reorder", "slideId": "s1", "objectId": "b", "op": "to_back"},
+			},
+			want: []string{"s1:#fff", "s1:b=2", "s1:a=1"},
+		},
+		{
+			name: "payloads decoded from mongo",
+			ops: []map[string]interface{}{
+				{"action": "create", "slideId": "s1", "objectId": "a", "objectType": "rect", "attributes": primitive.M{"x": 3.0}},
+				{"action": "batch", "ops": primitive.A{primitive.M{"action": "update", "slideId": "s1", "objectId": "a", "updatedAttributes": primitive.M{"x": 4.0}}}},
+			},
+			want: []string{"s1:#fff", "s1:a=4"},
+		},
+		{
+			name: "document reset",
+			ops: []map[string]interface{}{
+				rect("a", 1),
+				{"action": "document_reset", "slides": []interface{}{map[string]interface{}{"id": "r1", "background": "#000", "objects": []interface{}{}}}},
+			},
+			want: []string{"r1:#000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &model.Document{Slides: []model.Slide{{ID: "s1", Background: "#fff", Objects: []model.Object{}}}}
+			Replay(doc, entries(tt.ops...))
+			if got := summary(doc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayKeepsPayloads(t *testing.T) {
+	stroke := map[string]interface{}{
+		"action":     "stroke_end",
+		"slideId":    "s1",
+		"objectId":   "p",
+		"objectType": "pen",
+		"attributes": map[string]interface{}{"points": []interface{}{0.0, 0.0, 5.0, 0.0, 10.0, 0.0}},
+	}
+	doc := &model.Document{Slides: []model.Slide{{ID: "s1", Objects: []model.Object{}}}}
+	Replay(doc, entries(stroke))
+
+	if len(doc.Slides[0].Objects) != 1 {
+		t.Fatalf("stroke not replayed: %+v", doc.Slides[0])
+	}
+	// the entries are sent to clients after the replay, they must be unchanged
+	if stroke["action"] != "stroke_end" {
+		t.Errorf("payload action changed to %v", stroke["action"])
+	}
+	points := stroke["attributes"].(map[string]interface{})["points"]
+	if !reflect.DeepEqual(points, []interface{}{0.0, 0.0, 5.0, 0.0, 10.0, 0.0}) {
+		t.Errorf("payload points changed to %v", points)
+	}
+}
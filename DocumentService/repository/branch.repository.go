@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"document-service/model"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BranchRepository struct {
+	branches *mongo.Collection
+	merges   *mongo.Collection
+}
+
+func NewBranchRepository(client *mongo.Client, database string, branches string, merges string) *BranchRepository {
+	db := client.Database(database)
+	return &BranchRepository{
+		branches: db.Collection(branches),
+		merges:   db.Collection(merges),
+	}
+}
+
+func (r *BranchRepository) CreateBranch(ctx context.Context, branch model.Branch) (model.Branch, error) {
+	result, err := r.branches.InsertOne(ctx, branch)
+	if err != nil {
+		return model.Branch{}, fmt.Errorf("[BranchRepository][CreateBranch] %w", err)
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		branch.ID = oid
+	}
+	return branch, nil
+}
+
+// FindBranches lists the branches of a document, newest first
+func (r *BranchRepository) FindBranches(ctx context.Context, docId string) ([]model.Branch, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.branches.Find(ctx, bson.M{"documentId": docId}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[BranchRepository][FindBranches] %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	branches := []model.Branch{}
+	if err := cursor.All(ctx, &branches); err != nil {
+		return nil, fmt.Errorf("[BranchRepository][FindBranches] %w", err)
+	}
+	return branches, nil
+}
+
+// FindBranchByID returns a branch of the document, nil when it doesn't exist
+func (r *BranchRepository) FindBranchByID(ctx context.Context, docId string, branchId string) (*model.Branch, error) {
+	objectId, err := primitive.ObjectIDFromHex(branchId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid branch ID format: %w", err)
+	}
+
+	var branch model.Branch
+	err = r.branches.FindOne(ctx, bson.M{"_id": objectId, "documentId": docId}).Decode(&branch)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[BranchRepository][FindBranchByID] %w", err)
+	}
+	return &branch, nil
+}
+
+// MarkBranchMerged closes the branch once a merge of it was applied
+func (r *BranchRepository) MarkBranchMerged(ctx context.Context, branchId primitive.ObjectID, at time.Time) error {
+	update := bson.M{"$set": bson.M{"status": model.BranchStatusMerged, "mergedAt": at}}
+	if _, err := r.branches.UpdateOne(ctx, bson.M{"_id": branchId}, update); err != nil {
+		return fmt.Errorf("[BranchRepository][MarkBranchMerged] %w", err)
+	}
+	return nil
+}
+
+func (r *BranchRepository) CreateMerge(ctx context.Context, m model.Merge) (model.Merge, error) {
+	result, err := r.merges.InsertOne(ctx, m)
+	if err != nil {
+		return model.Merge{}, fmt.Errorf("[BranchRepository][CreateMerge] %w", err)
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		m.ID = oid
+	}
+	return m, nil
+}
+
+// FindMergeByID returns a merge into the document, nil when it doesn't exist
+func (r *BranchRepository) FindMergeByID(ctx context.Context, docId string, mergeId string) (*model.Merge, error) {
+	objectId, err := primitive.ObjectIDFromHex(mergeId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge ID format: %w", err)
+	}
+
+	var m model.Merge
+	err = r.merges.FindOne(ctx, bson.M{"_id": objectId, "documentId": docId}).Decode(&m)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[BranchRepository][FindMergeByID] %w", err)
+	}
+	return &m, nil
+}
+
+// UpdateMerge stores the conflicts and resolutions of a pending merge. It returns
+// false when the merge isn't pending anymore.
+func (r *BranchRepository) UpdateMerge(ctx context.Context, m *model.Merge) (bool, error) {
+	result, err := r.merges.ReplaceOne(ctx, bson.M{"_id": m.ID, "status": model.MergeStatusPending}, m)
+	if err != nil {
+		return false, fmt.Errorf("[BranchRepository][UpdateMerge] %w", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// ClaimMerge marks a pending merge applied with its backup version. It returns false
+// when the merge isn't pending anymore, another request applied it.
+func (r *BranchRepository) ClaimMerge(ctx context.Context, mergeId primitive.ObjectID, at time.Time, backupVersionId string) (bool, error) {
+	update := bson.M{"$set": bson.M{
+		"status":          model.MergeStatusApplied,
+		"appliedAt":       at,
+		"backupVersionId": backupVersionId,
+	}}
+	result, err := r.merges.UpdateOne(ctx, bson.M{"_id": mergeId, "status": model.MergeStatusPending}, update)
+	if err != nil {
+		return false, fmt.Errorf("[BranchRepository][ClaimMerge] %w", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// ReleaseMerge puts a merge claimed by ClaimMerge back to pending when it couldn't be applied
+func (r *BranchRepository) ReleaseMerge(ctx context.Context, mergeId primitive.ObjectID) error {
+	update := bson.M{
+		"$set":   bson.M{"status": model.MergeStatusPending},
+		"$unset": bson.M{"appliedAt": "", "backupVersionId": ""},
+	}
+	if _, err := r.merges.UpdateOne(ctx, bson.M{"_id": mergeId, "status": model.MergeStatusApplied}, update); err != nil {
+		return fmt.Errorf("[BranchRepository][ReleaseMerge] %w", err)
+	}
+	return nil
+}
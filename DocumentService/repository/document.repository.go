@@ -63,19 +63,22 @@ func (r *DocumentRepository) FindDocumentByID(ctx context.Context, docID string)
 	return &document, nil
 }
 
-func (r *DocumentRepository) CreateNewDocument(ctx context.Context, title string, ownerId string) (model.Document, error) {
+// CreateNewDocument creates a document of the given kind with one empty slide,
+// for whiteboards this slide is the canvas
+func (r *DocumentRepository) CreateNewDocument(ctx context.Context, title string, ownerId string, kind string) (model.Document, error) {
 
 	// Create a Document
 	emptyDocument := model.Document{
 		Title:   title,
 		OwnerID: ownerId,
+		Kind:    kind,
 		// Slides:  make([]model.Slide, 0),
 		Slides: []model.Slide{
 			{
 				ID:         primitive.NewObjectID().Hex(),
 				Background: "#FFFFFF",
 				// Objects:    make([]model.Object, 0, 1),
-				Objects:    make([]model.Object, 0),
+				Objects: make([]model.Object, 0),
 			},
 		},
 	}
@@ -183,9 +186,8 @@ func (r *DocumentRepository) FindSharedDocuments(ctx context.Context, userId str
 		return []model.Document{}, err
 	}
 	defer cursor.Close(ctx)
-	
 
-	documents:= []model.Document{}
+	documents := []model.Document{}
 
 	if err = cursor.All(ctx, &documents); err != nil {
 		fmt.Printf("[DocumentRepository][FindSharedDocuments] Error decoding documents: %v\n", err)
@@ -241,3 +243,55 @@ func (r *DocumentRepository) CreateCollaborationRecord(ctx context.Context, coll
 
 	return sharedDocRecord, nil
 }
+
+// Access types of a user on a document, collaborators get theirs from their collaboration record
+const (
+	AccessOwner  = "Owner"
+	AccessEditor = "Editor"
+	AccessViewer = "Viewer"
+)
+
+// FindAccessType returns AccessOwner for the owner of the document, the access type of
+// the collaboration record for a collaborator and "" for anyone else
+func (r *DocumentRepository) FindAccessType(ctx context.Context, userId string, documentId string) (string, error) {
+	isOwner, err := r.IsDocumentOwnedByUser(ctx, userId, documentId)
+	if err != nil {
+		return "", err
+	}
+	if isOwner {
+		return AccessOwner, nil
+	}
+
+	var record model.CollaborationRecord
+	err = r.sharedDocRecordCollection.FindOne(ctx, bson.M{"userId": userId, "documentId": documentId}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		fmt.Printf("[DocumentRepository][FindAccessType] Error retrieving collaboration record: %v\n", err)
+		return "", err
+	}
+	return record.AccessType, nil
+}
+
+// CopyDocument creates a document owned by ownerId with the slides of doc. Slide
+// and object ids are kept so the copy can be merged back.
+func (r *DocumentRepository) CopyDocument(ctx context.Context, doc *model.Document, title string, ownerId string) (model.Document, error) {
+	copied := model.Document{
+		Title:           title,
+		OwnerID:         ownerId,
+		Slides:          doc.Slides,
+		Kind:            doc.Kind,
+		ConcurrencyMode: doc.ConcurrencyMode,
+	}
+
+	result, err := r.collection.InsertOne(ctx, copied)
+	if err != nil {
+		fmt.Printf("[DocumentRepository][CopyDocument] Error inserting document: %v\n", err)
+		return model.Document{}, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		copied.ID = oid
+	}
+	return copied, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"document-service/model"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OplogRepository reads the ops recorded by the DocumentUpdatesConsumer
+type OplogRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOplogRepository(client *mongo.Client, database string, collection string) *OplogRepository {
+	return &OplogRepository{
+		collection: client.Database(database).Collection(collection),
+	}
+}
+
+// FindSeqAt returns the seq of the last op applied at or before the given time, 0 if none was
+func (r *OplogRepository) FindSeqAt(ctx context.Context, docId string, at time.Time) (int64, error) {
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "seq", Value: -1}}).
+		SetProjection(bson.M{"seq": 1})
+
+	var entry model.OplogEntry
+	err := r.collection.FindOne(ctx, bson.M{"documentId": docId, "timestamp": bson.M{"$lte": at}}, opts).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("[OplogRepository][FindSeqAt] %w", err)
+	}
+	return entry.Seq, nil
+}
+
+// FindEntries returns the ops with afterSeq < seq <= untilSeq in seq order. A limit
+// of 0 returns all of them.
+func (r *OplogRepository) FindEntries(ctx context.Context, docId string, afterSeq int64, untilSeq int64, limit int64) ([]model.OplogEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	filter := bson.M{"documentId": docId, "seq": bson.M{"$gt": afterSeq, "$lte": untilSeq}}
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[OplogRepository][FindEntries] %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []model.OplogEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("[OplogRepository][FindEntries] %w", err)
+	}
+	return entries, nil
+}
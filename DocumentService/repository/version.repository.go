@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"document-service/model"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type VersionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewVersionRepository(client *mongo.Client, database string, collection string) *VersionRepository {
+	return &VersionRepository{
+		collection: client.Database(database).Collection(collection),
+	}
+}
+
+// CreateVersion stores a copy of the slides of the document. The seq of the version
+// is the one of the document, read with the slides.
+func (r *VersionRepository) CreateVersion(ctx context.Context, document *model.Document, name string, kind string, createdBy string) (model.Version, error) {
+	docId := document.ID.Hex()
+	version := model.Version{
+		DocumentID: docId,
+		Name:       name,
+		Kind:       kind,
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now(),
+		Seq:        document.Seq,
+		Title:      document.Title,
+		Slides:     document.Slides,
+	}
+
+	result, err := r.collection.InsertOne(ctx, version)
+	if err != nil {
+		return model.Version{}, fmt.Errorf("[VersionRepository][CreateVersion] %w", err)
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		version.ID = oid
+	}
+	return version, nil
+}
+
+// FindVersions lists the versions of a document, newest first, without their slides
+func (r *VersionRepository) FindVersions(ctx context.Context, docId string) ([]model.Version, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetProjection(bson.M{"slides": 0})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"documentId": docId}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[VersionRepository][FindVersions] %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	versions := []model.Version{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("[VersionRepository][FindVersions] %w", err)
+	}
+	return versions, nil
+}
+
+// FindVersionByID returns a version of the document with its slides, nil when it doesn't exist
+func (r *VersionRepository) FindVersionByID(ctx context.Context, docId string, versionId string) (*model.Version, error) {
+	objectId, err := primitive.ObjectIDFromHex(versionId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version ID format: %w", err)
+	}
+
+	var version model.Version
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectId, "documentId": docId}).Decode(&version)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[VersionRepository][FindVersionByID] %w", err)
+	}
+	return &version, nil
+}
+
+// FindBaseVersion returns the most recent version taken at or before the given
+// oplog seq with its slides, nil when there is none
+func (r *VersionRepository) FindBaseVersion(ctx context.Context, docId string, seq int64) (*model.Version, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}, {Key: "createdAt", Value: -1}})
+
+	var version model.Version
+	err := r.collection.FindOne(ctx, bson.M{"documentId": docId, "seq": bson.M{"$lte": seq}}, opts).Decode(&version)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[VersionRepository][FindBaseVersion] %w", err)
+	}
+	return &version, nil
+}
@@ -1,7 +1,9 @@
 package types
 
 import (
+	"document-service/diff"
 	"document-service/model"
+	"time"
 )
 
 // Dtos
@@ -20,6 +22,87 @@ type ShareDocumentPostData struct {
 	AccessType         string `json:"accessType"`
 }
 
+// CreateDocumentPostData is the optional body of POST /document/create
+type CreateDocumentPostData struct {
+	Kind string `json:"kind"` // "slides" (default) or "whiteboard"
+}
+
+// ViewportDto holds the objects of a whiteboard intersecting the requested viewport
+type ViewportDto struct {
+	SlideID string         `json:"slideId"`
+	Objects []model.Object `json:"objects"`
+}
+
+// CreateVersionPostData is the body of POST /document/id/:id/versions
+type CreateVersionPostData struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// RestoreVersionDto is returned once a version has been restored. The slides the
+// document had before are kept as an automatic version.
+type RestoreVersionDto struct {
+	BackupVersionID string `json:"backupVersionId"`
+}
+
+// DocumentResetPostData is sent to UpdatesService, which replaces the slides of the
+// live document and has the reset persisted through the updates pipeline
+type DocumentResetPostData struct {
+	VersionID string        `json:"versionId"`
+	UserID    string        `json:"userId"`
+	Slides    []model.Slide `json:"slides"`
+	// BaseVersion is the version of the live document the slides were computed from,
+	// see DocumentFlushDto. UpdatesService refuses the reset once it moved on.
+	BaseVersion uint64 `json:"baseVersion"`
+}
+
+// DocumentFlushDto is returned by UpdatesService once the ops of the document are
+// persisted, Version is the version of the live document then
+type DocumentFlushDto struct {
+	Version uint64 `json:"version"`
+}
+
 type DeleteDocumentPostData struct {
 	DocumentID string `json:"documentId"`
 }
+
+// PlaybackStateDto is the document as it was at a point in time
+type PlaybackStateDto struct {
+	At       time.Time       `json:"at"`
+	Seq      int64           `json:"seq"` // last op included in the state
+	Document *model.Document `json:"document"`
+}
+
+// PlaybackOp is a recorded op with its timing in the replayed range
+type PlaybackOp struct {
+	model.OplogEntry
+	Offset int64 `json:"offset"` // milliseconds between the start of the range and the op
+	Delay  int64 `json:"delay"`  // offset at the requested speed
+}
+
+// PlaybackOpsDto holds the state at the start of a range and the ops applied during it
+type PlaybackOpsDto struct {
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Speed     float64         `json:"speed"`
+	Seq       int64           `json:"seq"` // last op included in the document
+	Document  *model.Document `json:"document"`
+	Ops       []PlaybackOp    `json:"ops"`
+	Truncated bool            `json:"truncated"` // more ops than MaxPlaybackOps happened in the range
+}
+
+// DiffDto is the change review between two states of a document
+type DiffDto struct {
+	From string `json:"from"` // version id or "current"
+	To   string `json:"to"`
+	diff.DocumentDiff
+}
+
+// CreateBranchPostData is the optional body of POST /document/id/:id/branches
+type CreateBranchPostData struct {
+	Title string `json:"title"`
+}
+
+// ResolveMergePostData settles conflicts of a merge, conflict id -> "ours" or "theirs"
+type ResolveMergePostData struct {
+	Resolutions map[string]string `json:"resolutions" binding:"required"`
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type Config struct {
 }
 
@@ -9,6 +11,9 @@ type MongoConfigStruct struct {
 	UserCollectionName            string
 	DocumentCollectionName        string
 	SharedDocRecordCollectionName string
+	OplogCollectionName           string
+	OplogCounterCollectionName    string // seqs before they moved to the documents, read once by OplogRepository.MigrateCounters
+	VersionCollectionName         string
 }
 
 var MongoConfig = MongoConfigStruct{
@@ -17,4 +22,32 @@ var MongoConfig = MongoConfigStruct{
 	UserCollectionName:            "user",
 	DocumentCollectionName:        "document",
 	SharedDocRecordCollectionName: "sharedDocRecordCollection",
+	OplogCollectionName:           "oplog",
+	OplogCounterCollectionName:    "oplogCounters",
+	VersionCollectionName:         "versions",
+}
+
+// SnapshotConfigStruct sets how often automatic versions of edited documents are taken.
+// A snapshot is due after EveryOps ops or, when the document changed, after Interval.
+type SnapshotConfigStruct struct {
+	EveryOps int64
+	Interval time.Duration
+	Keep     int64 // automatic snapshots kept per document, older ones are deleted except the first one
+}
+
+var SnapshotConfig = SnapshotConfigStruct{
+	EveryOps: 500,
+	Interval: 10 * time.Minute,
+	Keep:     20,
+}
+
+// QueueConfigStruct sets how messages are dispatched, see handler.Queue
+type QueueConfigStruct struct {
+	Workers int // documents applied concurrently
+	Size    int // messages buffered per worker
+}
+
+var QueueConfig = QueueConfigStruct{
+	Workers: 16,
+	Size:    64,
 }
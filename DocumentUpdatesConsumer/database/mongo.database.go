@@ -15,7 +15,8 @@ func ConnectDB(uri string) *mongo.Client {
 	defer cancel()
 
 	// Parse the URI and setup client options
-	clientOptions := options.Client().ApplyURI(uri)
+	// Nested documents are decoded as maps so object attributes can be edited in memory
+	clientOptions := options.Client().ApplyURI(uri).SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true})
 
 	// connect
 	client, err := mongo.Connect(ctx, clientOptions)
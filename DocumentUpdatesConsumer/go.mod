@@ -3,6 +3,7 @@ module DocumentUpdatesConsumer
 go 1.25.1
 
 require (
+	Shared v0.0.0
 	github.com/confluentinc/confluent-kafka-go v1.9.2
 	go.mongodb.org/mongo-driver v1.17.6
 )
@@ -19,3 +20,5 @@ require (
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 )
+
+replace Shared => ../Shared
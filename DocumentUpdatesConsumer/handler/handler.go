@@ -3,13 +3,33 @@ package handler
 import (
 	"DocumentUpdatesConsumer/model"
 	"DocumentUpdatesConsumer/repository"
+	"DocumentUpdatesConsumer/results"
 	"DocumentUpdatesConsumer/types"
+	"Shared/operation"
 	"context"
 	"encoding/json"
 	"fmt"
 )
 
-func DocumentUpdatesHandler(ctx context.Context, r *repository.DocumentRepository, msg types.Message) {
+// appliedActions are computed from the current document state, see operation.Apply
+var appliedActions = map[string]bool{
+	"reorder":           true,
+	"text_edit":         true,
+	"group":             true,
+	"ungroup":           true,
+	"add_to_frame":      true,
+	"remove_from_frame": true,
+	"set_layer":         true,
+	"add_layer":         true,
+	"update_layer":      true,
+	"remove_layer":      true,
+	"move_layer":        true,
+	"move_slide":        true,
+	"duplicate_slide":   true,
+	"restore_objects":   true,
+}
+
+func DocumentUpdatesHandler(ctx context.Context, r *repository.DocumentRepository, o *repository.OplogRepository, v *repository.VersionRepository, p *results.Publisher, msg types.Message) {
 
 	var actionMsg map[string]interface{}
 	err := json.Unmarshal([]byte(msg.Body), &actionMsg)
@@ -21,32 +41,123 @@ func DocumentUpdatesHandler(ctx context.Context, r *repository.DocumentRepositor
 	// fmt.Printf("\n ============ Action Msg ============= \n %v\n", actionMsg)
 
 	actVal := actionMsg["action"].(string) // it is always possible as only validated data is pushed to kafka
+	result := types.ResultMessage{OpID: msg.OpID, DocumentID: msg.DocumentID, UserID: msg.UserID, Action: actVal}
+	seq, err := applyMessage(ctx, r, msg, actionMsg, actVal)
+	if err != nil {
+		fmt.Printf("[DocumentUpdatesHandler] Error applying %s: %s\n", actVal, err)
+		// the op was broadcast already, clients revert it with the inverse
+		result.Status = types.ResultFailed
+		result.Error = err.Error()
+		result.Inverse = msg.Inverse
+		p.Publish(result)
+		return
+	}
+
+	// only ops which have been applied reach the oplog
+	entry := model.OplogEntry{
+		DocumentID: msg.DocumentID,
+		Seq:        seq,
+		UserID:     msg.UserID,
+		Username:   msg.Username,
+		Timestamp:  msg.Timestamp,
+		Action:     actVal,
+		Payload:    actionMsg,
+		Inverse:    msg.Inverse,
+	}
+	appendErr := o.Append(ctx, entry)
+	if appendErr != nil {
+		fmt.Printf("[DocumentUpdatesHandler] Error appending op %d to the oplog: %s\n", seq, appendErr)
+	}
+
+	// published once the oplog append is settled. The document has the op even when the
+	// append failed, reverting it on clients would make them diverge from the stored state.
+	result.Status = types.ResultApplied
+	result.Seq = seq
+	p.Publish(result)
+	if appendErr != nil {
+		return
+	}
+
+	// automatic snapshots
+	due, err := v.SnapshotDue(ctx, msg.DocumentID, seq)
+	if err != nil {
+		fmt.Printf("[DocumentUpdatesHandler] Error checking snapshot: %s\n", err)
+		return
+	}
+	if due {
+		doc, err := r.GetDocument(ctx, msg.DocumentID)
+		if err != nil {
+			fmt.Printf("[DocumentUpdatesHandler] Error reading document for snapshot: %s\n", err)
+			return
+		}
+		// the document may already include later ops, its own seq is the one of its slides
+		if err := v.Snapshot(ctx, doc, doc.Seq); err != nil {
+			fmt.Printf("[DocumentUpdatesHandler] Error taking snapshot: %s\n", err)
+		}
+	}
+}
+
+// applyMessage writes the op of a message to the document and returns the seq the write gave to it
+func applyMessage(ctx context.Context, r *repository.DocumentRepository, msg types.Message, actionMsg map[string]interface{}, actVal string) (int64, error) {
+	var seq int64
+	var err error
 	if actVal == "add_slide" {
 		fmt.Printf("[DocumentUpdatesHandler] AddSlide message received by consumer")
 		slideId, ok := actionMsg["slideId"].(string)
 		if !ok {
-			fmt.Printf("[DocumentUpdatesHandler] slideId missing")
-			return
+			return 0, fmt.Errorf("slideId missing")
 		}
 
-		err := r.AddNewSlide(ctx, msg.DocumentID, slideId)
+		background, _ := actionMsg["background"].(string)
+		index := -1
+		if i, ok := actionMsg["index"].(float64); ok {
+			index = int(i)
+		}
+
+		seq, err = r.AddNewSlide(ctx, msg.DocumentID, slideId, background, index)
 		if err != nil {
-			fmt.Printf("[DocumentUpdatesHandler] Error adding new slide")
-			return
+			return 0, fmt.Errorf("error adding new slide: %w", err)
 		}
 
 	} else if actVal == "remove_slide" {
 		fmt.Printf("[DocumentUpdatesHandler] RemoveSlide message received by consumer")
 		slideId, ok := actionMsg["slideId"].(string)
 		if !ok {
-			fmt.Printf("[DocumentUpdatesHandler] slideId missing")
-			return
+			return 0, fmt.Errorf("slideId missing")
 		}
 
-		err := r.RemoveSlide(ctx, msg.DocumentID, slideId)
+		seq, err = r.RemoveSlide(ctx, msg.DocumentID, slideId)
 		if err != nil {
-			fmt.Printf("[DocumentUpdatesHandler] Error adding new slide")
-			return
+			return 0, fmt.Errorf("error removing slide: %w", err)
+		}
+
+	} else if actVal == "update_slide" {
+		fmt.Printf("[DocumentUpdatesHandler] UpdateSlide message received by consumer")
+		slideId, ok := actionMsg["slideId"].(string)
+		if !ok {
+			return 0, fmt.Errorf("slideId missing")
+		}
+
+		updatedProperties, ok := actionMsg["updatedProperties"].(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("updatedProperties missing")
+		}
+
+		seq, err = r.UpdateSlide(ctx, msg.DocumentID, slideId, updatedProperties)
+		if err != nil {
+			return 0, fmt.Errorf("error updating slide: %w", err)
+		}
+
+	} else if actVal == "set_concurrency_mode" {
+		fmt.Printf("[DocumentUpdatesHandler] SetConcurrencyMode message received by consumer")
+		mode, ok := actionMsg["mode"].(string)
+		if !ok {
+			return 0, fmt.Errorf("mode missing")
+		}
+
+		seq, err = r.SetConcurrencyMode(ctx, msg.DocumentID, mode)
+		if err != nil {
+			return 0, fmt.Errorf("error setting concurrency mode: %w", err)
 		}
 
 	} else if actVal == "delete" {
@@ -55,13 +166,12 @@ func DocumentUpdatesHandler(ctx context.Context, r *repository.DocumentRepositor
 		docId := msg.DocumentID
 		slideId := actionMsg["slideId"].(string)
 		objectId := actionMsg["objectId"].(string)
-		err := r.DeleteElement(ctx, docId, slideId, objectId)
+		seq, err = r.DeleteElement(ctx, docId, slideId, objectId)
 		if err != nil {
-			fmt.Printf("[DocumentUpdatesHandler] Error deleting object")
-			return
+			return 0, fmt.Errorf("error deleting object: %w", err)
 		}
 
-	} else if actVal == "update" {
+	} else if actVal == "update" || actVal == "commit_update" {
 		fmt.Printf("[DocumentUpdatesHandler] Update message received by consumer")
 		// msg contains the docId; the actionMsg must contain slideId and objectId
 		docId := msg.DocumentID
@@ -71,17 +181,23 @@ func DocumentUpdatesHandler(ctx context.Context, r *repository.DocumentRepositor
 		// updated fields actionMsg["updatedAttributes"] is of type interface it need to be converted to map[string]interface
 		updatedFields, ok := actionMsg["updatedAttributes"].(map[string]interface{})
 		if !ok {
-			fmt.Printf("[DocumentUpdatesHandler] Error converting updatedAttributes to map[string]interface{}: %s\n", err)
-			return
+			return 0, fmt.Errorf("updatedAttributes missing")
 		}
 
-		err := r.UpdateElement(ctx, docId, slideId, objectId, updatedFields)
+		objectType, _ := actionMsg["objectType"].(string)
+		operation.PrepareAttributes(objectType, updatedFields)
+
+		// lww updates are resolved against the stamps stored on the object
+		if _, ok := actionMsg["stamp"].(string); ok {
+			seq, err = r.ApplyOperation(ctx, docId, actionMsg)
+		} else {
+			seq, err = r.UpdateElement(ctx, docId, slideId, objectId, updatedFields)
+		}
 		if err != nil {
-			fmt.Printf("[DocumentUpdatesHandler] Error updating object: %s\n", err)
-			return
+			return 0, fmt.Errorf("error updating object: %w", err)
 		}
 
-	} else if actVal == "create" {
+	} else if actVal == "create" || actVal == "stroke_end" {
 		fmt.Printf("[DocumentUpdatesHandler] Create message received by consumer")
 		// msg contains the docId; the actionMsg must contain slideId and objectId
 		docId := msg.DocumentID
@@ -92,23 +208,73 @@ func DocumentUpdatesHandler(ctx context.Context, r *repository.DocumentRepositor
 		// updated fields actionMsg["updatedAttributes"] is of type interface it need to be converted to map[string]interface
 		attr, ok := actionMsg["attributes"].(map[string]interface{})
 		if !ok {
-			fmt.Printf("[DocumentUpdatesHandler] Error converting updatedAttributes to map[string]interface{}:- %s\n", err)
-			return
+			return 0, fmt.Errorf("attributes missing")
 		}
 
+		operation.PrepareAttributes(objectType, attr)
+
 		// create model.Object
+		layerId, _ := actionMsg["layerId"].(string)
 		obj := model.Object{
 			ID:         objectId,
 			Type:       objectType,
 			Attributes: attr,
+			LayerID:    layerId,
 		}
 
-		err := r.CreateElement(ctx, docId, slideId, obj)
+		seq, err = r.CreateElement(ctx, docId, slideId, obj)
 		if err != nil {
-			fmt.Printf("[DocumentUpdatesHandler] Error creating object:- %s\n", err)
-			return
+			return 0, fmt.Errorf("error creating object: %w", err)
 		}
+	} else if appliedActions[actVal] {
+		fmt.Printf("[DocumentUpdatesHandler] %s message received by consumer", actVal)
+		seq, err = r.ApplyOperation(ctx, msg.DocumentID, actionMsg)
+		if err != nil {
+			return 0, fmt.Errorf("error applying %s: %w", actVal, err)
+		}
+
+	} else if actVal == "batch" {
+		fmt.Printf("[DocumentUpdatesHandler] Batch message received by consumer")
+		rawOps, ok := actionMsg["ops"].([]interface{})
+		if !ok {
+			return 0, fmt.Errorf("ops missing")
+		}
+
+		ops := make([]map[string]interface{}, 0, len(rawOps))
+		for _, rawOp := range rawOps {
+			op, ok := rawOp.(map[string]interface{})
+			if !ok {
+				return 0, fmt.Errorf("invalid op in batch")
+			}
+
+			objectType, _ := op["objectType"].(string)
+			if attr, ok := op["attributes"].(map[string]interface{}); ok {
+				operation.PrepareAttributes(objectType, attr)
+			}
+			if updatedFields, ok := op["updatedAttributes"].(map[string]interface{}); ok {
+				operation.PrepareAttributes(objectType, updatedFields)
+			}
+			ops = append(ops, op)
+		}
+
+		seq, err = r.ApplyBatch(ctx, msg.DocumentID, ops)
+		if err != nil {
+			return 0, fmt.Errorf("error applying batch: %w", err)
+		}
+	} else if actVal == "document_reset" {
+		fmt.Printf("[DocumentUpdatesHandler] DocumentReset message received by consumer")
+		slides, err := operation.DecodeSlides(actionMsg["slides"])
+		if err != nil {
+			return 0, err
+		}
+
+		seq, err = r.ResetSlides(ctx, msg.DocumentID, slides)
+		if err != nil {
+			return 0, fmt.Errorf("error resetting document: %w", err)
+		}
+
 	} else {
-		fmt.Printf("[DocumentUpdatesHandler] Unknown message received by consumer")
+		return 0, fmt.Errorf("unknown action %s", actVal)
 	}
+	return seq, nil
 }
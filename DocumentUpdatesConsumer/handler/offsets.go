@@ -0,0 +1,55 @@
+package handler
+
+import "sync"
+
+// Offsets follows the messages handed to the workers of a Queue, per kafka partition.
+// Workers finish out of order: the offset of a partition only moves past a message
+// once it and every message before it are handled, a restart reads the others again.
+type Offsets struct {
+	mu         sync.Mutex
+	partitions map[int32]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	pending []int64 // offsets handed to the workers and not committed yet, in order
+	done    map[int64]bool
+}
+
+func NewOffsets() *Offsets {
+	return &Offsets{partitions: make(map[int32]*partitionOffsets)}
+}
+
+// Start records a message handed to the workers. An offset which doesn't follow the
+// pending ones is read again after a rebalance, what was pending is forgotten.
+func (o *Offsets) Start(partition int32, offset int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p, ok := o.partitions[partition]
+	if !ok || (len(p.pending) > 0 && offset <= p.pending[len(p.pending)-1]) {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		o.partitions[partition] = p
+	}
+	p.pending = append(p.pending, offset)
+}
+
+// Done marks a message handled. It returns the offset to commit for the partition (the
+// next one to read) and false when it didn't move, a message before it is still pending.
+func (o *Offsets) Done(partition int32, offset int64) (int64, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p, ok := o.partitions[partition]
+	if !ok {
+		return 0, false
+	}
+	p.done[offset] = true
+
+	next, moved := int64(0), false
+	for len(p.pending) > 0 && p.done[p.pending[0]] {
+		delete(p.done, p.pending[0])
+		next, moved = p.pending[0]+1, true
+		p.pending = p.pending[1:]
+	}
+	return next, moved
+}
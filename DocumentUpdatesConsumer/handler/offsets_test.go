@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOffsets(t *testing.T) {
+	type event struct {
+		start     bool // Start, otherwise Done
+		partition int32
+		offset    int64
+	}
+	start := func(partition int32, offset int64) event { return event{true, partition, offset} }
+	done := func(partition int32, offset int64) event { return event{false, partition, offset} }
+
+	tests := []struct {
+		name   string
+		events []event
+		want   []int64 // offsets to commit, in order
+	}{
+		{
+			name:   "handled in order",
+			events: []event{start(0, 1), start(0, 2), done(0, 1), done(0, 2)},
+			want:   []int64{2, 3},
+		},
+		{
+			name:   "a later message waits for the ones before it",
+			events: []event{start(0, 1), start(0, 2), start(0, 3), done(0, 3), done(0, 2), done(0, 1)},
+			want:   []int64{4},
+		},
+		{
+			name:   "partitions are independent",
+			events: []event{start(0, 1), start(1, 7), done(1, 7), done(0, 1)},
+			want:   []int64{8, 2},
+		},
+		{
+			name:   "read again after a rebalance",
+			events: []event{start(0, 5), start(0, 6), start(0, 5), done(0, 6), done(0, 5)},
+			want:   []int64{6},
+		},
+		{
+			name:   "unknown partition",
+			events: []event{done(3, 1)},
+			want:   []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOffsets()
+			got := []int64{}
+			for _, e := range tt.events {
+				if e.start {
+					o.Start(e.partition, e.offset)
+				} else if next, ok := o.Done(e.partition, e.offset); ok {
+					got = append(got, next)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"DocumentUpdatesConsumer/types"
+	"hash/fnv"
+	"sync"
+)
+
+// Queue hands messages to a fixed set of workers. Messages of a document always go
+// to the same worker, they are applied one after the other in the order kafka
+// delivered them while different documents are applied concurrently.
+type Queue struct {
+	workers []chan types.Message
+	wg      sync.WaitGroup
+}
+
+func NewQueue(workers int, size int, handle func(msg types.Message)) *Queue {
+	q := &Queue{workers: make([]chan types.Message, workers)}
+	for i := range q.workers {
+		ch := make(chan types.Message, size)
+		q.workers[i] = ch
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for msg := range ch {
+				handle(msg)
+			}
+		}()
+	}
+	return q
+}
+
+// Push queues the message on the worker of its document, it blocks while the worker is full
+func (q *Queue) Push(msg types.Message) {
+	h := fnv.New32a()
+	h.Write([]byte(msg.DocumentID))
+	q.workers[h.Sum32()%uint32(len(q.workers))] <- msg
+}
+
+// Close waits until the queued messages are handled, Push must not be called afterwards
+func (q *Queue) Close() {
+	for _, ch := range q.workers {
+		close(ch)
+	}
+	q.wg.Wait()
+}
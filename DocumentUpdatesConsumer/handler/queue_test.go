@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"DocumentUpdatesConsumer/types"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestQueueKeepsDocumentOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		workers   int
+		documents int
+		messages  int
+	}{
+		{"one worker", 1, 3, 50},
+		{"more documents than workers", 4, 10, 50},
+		{"more workers than documents", 16, 2, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			handled := map[string][]string{}
+			q := NewQueue(tt.workers, 1, func(msg types.Message) {
+				mu.Lock()
+				defer mu.Unlock()
+				handled[msg.DocumentID] = append(handled[msg.DocumentID], msg.OpID)
+			})
+
+			for i := 0; i < tt.messages; i++ {
+				for d := 0; d < tt.documents; d++ {
+					q.Push(types.Message{DocumentID: fmt.Sprintf("doc-%d", d), OpID: fmt.Sprint(i)})
+				}
+			}
+			q.Close()
+
+			for d := 0; d < tt.documents; d++ {
+				ops := handled[fmt.Sprintf("doc-%d", d)]
+				if len(ops) != tt.messages {
+					t.Fatalf("doc-%d: handled %d messages, want %d", d, len(ops), tt.messages)
+				}
+				for i, op := range ops {
+					if op != fmt.Sprint(i) {
+						t.Fatalf("doc-%d: message %d is op %s", d, i, op)
+					}
+				}
+			}
+		})
+	}
+}
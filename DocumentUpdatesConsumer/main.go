@@ -5,6 +5,7 @@ import (
 	"DocumentUpdatesConsumer/database"
 	"DocumentUpdatesConsumer/handler"
 	"DocumentUpdatesConsumer/repository"
+	"DocumentUpdatesConsumer/results"
 	"DocumentUpdatesConsumer/types"
 	"context"
 	"encoding/json"
@@ -39,6 +40,9 @@ func connectConsumerWithRetry(brokers, group string) *kafka.Consumer {
 			"session.timeout.ms":       30000,
 			"heartbeat.interval.ms":    3000,
 			"allow.auto.create.topics": true,
+			// offsets are stored once their message is handled (see handler.Offsets),
+			// the stored offsets are committed periodically and on close
+			"enable.auto.offset.store": false,
 		})
 
 		if err == nil {
@@ -56,6 +60,31 @@ func connectConsumerWithRetry(brokers, group string) *kafka.Consumer {
 	}
 }
 
+// connectProducerWithRetry loops until the producer of op results reaches a broker
+func connectProducerWithRetry(brokers string) *kafka.Producer {
+	retryInterval := 5 * time.Second
+
+	for {
+		fmt.Printf("Attempting to connect producer to %s...\n", brokers)
+		producer, err := kafka.NewProducer(&kafka.ConfigMap{
+			"bootstrap.servers": brokers,
+		})
+
+		if err == nil {
+			// NewProducer is lazy, metadata forces a network call
+			_, err = producer.GetMetadata(nil, false, 10000)
+			if err == nil {
+				fmt.Println("Successfully connected producer to Kafka Broker!")
+				return producer
+			}
+			producer.Close()
+		}
+
+		fmt.Printf("Producer connection failed: %v. Retrying in %v...\n", err, retryInterval)
+		time.Sleep(retryInterval)
+	}
+}
+
 // subscribeWithRetry attempts to subscribe to the topic with retry logic
 func subscribeWithRetry(consumer *kafka.Consumer, topic string) {
 	retryInterval := 5 * time.Second
@@ -138,6 +167,29 @@ func main() {
 		config.MongoConfig.DatabaseName,
 		config.MongoConfig.DocumentCollectionName,
 	)
+	o := repository.NewOplogRepository(
+		client,
+		config.MongoConfig.DatabaseName,
+		config.MongoConfig.OplogCollectionName,
+		config.MongoConfig.OplogCounterCollectionName,
+	)
+	v := repository.NewVersionRepository(
+		client,
+		config.MongoConfig.DatabaseName,
+		config.MongoConfig.VersionCollectionName,
+	)
+
+	indexCtx, indexCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := o.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Warning: Could not create oplog indexes: %v", err)
+	}
+	indexCancel()
+
+	migrateCtx, migrateCancel := context.WithTimeout(context.Background(), time.Minute)
+	if err := o.MigrateCounters(migrateCtx, r); err != nil {
+		log.Printf("Warning: Could not migrate oplog counters: %v", err)
+	}
+	migrateCancel()
 
 	// Ensure topic exists before creating consumer
 	fmt.Println("Ensuring Kafka topic exists...")
@@ -146,6 +198,15 @@ func main() {
 		log.Println("Continuing anyway - topic may be auto-created on first message")
 	}
 
+	if err := ensureTopicExists(kafkaBroker, results.Topic); err != nil {
+		log.Printf("Warning: Could not ensure topic %s exists: %v", results.Topic, err)
+	}
+
+	// Results of ops are published for UpdatesService
+	producer := connectProducerWithRetry(kafkaBroker)
+	defer producer.Close()
+	p := results.NewPublisher(producer, results.Topic)
+
 	// Create Kafka consumer
 	fmt.Println("Trying to connect to Kafka!")
 	c := connectConsumerWithRetry(kafkaBroker, groupID)
@@ -156,6 +217,28 @@ func main() {
 	subscribeWithRetry(c, topic)
 	fmt.Printf("Subscribed to topic %s. Waiting for messages...\n", topic)
 
+	// Ops of a document are applied in order, their seqs follow the kafka order. The
+	// offset of a message is stored once it and the messages before it are handled.
+	offsets := handler.NewOffsets()
+	updatesTopic := topic
+	storeOffset := func(msg types.Message) {
+		next, ok := offsets.Done(msg.Partition, msg.Offset)
+		if !ok {
+			return
+		}
+		_, err := c.StoreOffsets([]kafka.TopicPartition{{Topic: &updatesTopic, Partition: msg.Partition, Offset: kafka.Offset(next)}})
+		if err != nil {
+			fmt.Printf("[Error] Can't store offset %d of partition %d: %v\n", next, msg.Partition, err)
+		}
+	}
+	q := handler.NewQueue(config.QueueConfig.Workers, config.QueueConfig.Size, func(msg types.Message) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		handler.DocumentUpdatesHandler(ctx, r, o, v, p, msg)
+		storeOffset(msg)
+	})
+	defer q.Close()
+
 	// Setup graceful shutdown
 	sigchan := make(chan os.Signal, 1)
 	signal.Notify(sigchan, os.Interrupt)
@@ -183,16 +266,18 @@ func main() {
 
 				// Parse message into struct
 				var msg types.Message
-				if err := json.Unmarshal(e.Value, &msg); err != nil {
+				err := json.Unmarshal(e.Value, &msg)
+				msg.Partition = e.TopicPartition.Partition
+				msg.Offset = int64(e.TopicPartition.Offset)
+				offsets.Start(msg.Partition, msg.Offset)
+				if err != nil {
 					fmt.Printf("[Error] Can't unmarshal message: %v\n", err)
+					storeOffset(msg)
 					continue
 				}
+				msg.Timestamp = e.Timestamp
 
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				go func() {
-					defer cancel()
-					handler.DocumentUpdatesHandler(ctx, r, msg)
-				}()
+				q.Push(msg)
 
 			case kafka.Error:
 				// Handle Kafka errors
@@ -1,22 +1,16 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import shared "Shared/model"
 
-type Slide struct {
-	ID         string   `bson:"_id" json:"id"`
-	Background string   `bson:"background" json:"background"`
-	Objects    []Object `bson:"objects" json:"objects"`
-}
+// The document model is shared by every service, see Shared/model
+type (
+	Document = shared.Document
+	Slide    = shared.Slide
+	Layer    = shared.Layer
+	Object   = shared.Object
+)
 
-type Document struct {
-	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Title   string             `bson:"title" json:"title"`
-	OwnerID string             `bson:"ownerId" json:"ownerId"`
-	Slides  []Slide            `bson:"slides" json:"slides"`
-}
-
-type Object struct {
-	ID         string                 `bson:"_id" json:"id"`
-	Type       string                 `bson:"type" json:"type"`
-	Attributes map[string]interface{} `bson:"attributes" json:"attributes"`
-}
+const (
+	DocumentKindSlides     = shared.DocumentKindSlides
+	DocumentKindWhiteboard = shared.DocumentKindWhiteboard
+)
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OplogEntry records an op applied to a document. Seq numbers the ops of a
+// document from 1 in the order they were applied.
+type OplogEntry struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	DocumentID string                 `bson:"documentId" json:"documentId"`
+	Seq        int64                  `bson:"seq" json:"seq"`
+	UserID     string                 `bson:"userId" json:"userId"` // empty for ops derived by the server
+	Username   string                 `bson:"username,omitempty" json:"username,omitempty"`
+	Timestamp  time.Time              `bson:"timestamp" json:"timestamp"`
+	Action     string                 `bson:"action" json:"action"`
+	Payload    map[string]interface{} `bson:"payload" json:"payload"`
+	Inverse    map[string]interface{} `bson:"inverse,omitempty" json:"inverse,omitempty"` // missing when the op can't be reverted
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"DocumentUpdatesConsumer/model"
+	"Shared/operation"
 	"context"
 	"fmt"
 
@@ -22,11 +23,33 @@ func NewDocumentRepository(client *mongo.Client, database string, collection str
 	}
 }
 
-func (r *DocumentRepository) AddNewSlide(ctx context.Context, documentId string, slideId string) error {
+// incSeq is part of the update of every op: version is used for optimistic concurrency
+// and seq numbers the op, the write and its seq are atomic
+var incSeq = bson.E{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}, {Key: "seq", Value: 1}}}
+
+// writeOp applies the update of an op to the document matched by filter and returns the
+// seq the update gave to the op, mongo.ErrNoDocuments when nothing matched
+func (r *DocumentRepository) writeOp(ctx context.Context, filter bson.M, update bson.D, arrayFilters []interface{}) (int64, error) {
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"seq": 1})
+	if arrayFilters != nil {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+	}
+
+	var doc model.Document
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc); err != nil {
+		return 0, err
+	}
+	return doc.Seq, nil
+}
+
+// AddNewSlide inserts a blank slide at index, -1 appends it at the end
+func (r *DocumentRepository) AddNewSlide(ctx context.Context, documentId string, slideId string, background string, index int) (int64, error) {
 	objectId, err := primitive.ObjectIDFromHex(documentId)
 	if err != nil {
 		fmt.Printf("[DocumentRepository] Invalid document id: %v\n", err)
-		return err
+		return 0, err
 	}
 
 	// check if document exists or not
@@ -35,47 +58,54 @@ func (r *DocumentRepository) AddNewSlide(ctx context.Context, documentId string,
 	err = r.collection.FindOne(ctx, filter).Decode(&doc)
 	if err != nil {
 		fmt.Printf("[DocumentRepository][FindOwnedDocuments] Error decoding documents: %v\n", err)
-		return err
+		return 0, err
 	}
 
 	// document exists
 	// create new slide
+	if background == "" {
+		background = operation.DefaultSlideBackground
+	}
 	newSlide := model.Slide{
 		ID:         slideId,
-		Background: "#fff",
+		Background: background,
 		Objects:    make([]model.Object, 0, 1),
 	}
 
+	push := bson.D{{Key: "$each", Value: bson.A{newSlide}}}
+	if index >= 0 {
+		push = append(push, bson.E{Key: "$position", Value: index})
+	}
+
 	update := bson.D{
 		{Key: "$push", Value: bson.D{
-			{Key: "slides", Value: newSlide},
+			{Key: "slides", Value: push},
 		}},
+		incSeq,
 	}
 
-	// Execute the UpdateOne
-	result, err := r.collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return fmt.Errorf("update failed: %w", err)
+	// Execute the update
+	seq, err := r.writeOp(ctx, filter, update, nil)
+	if err == mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("document not found with ID: %s", documentId)
 	}
-
-	// 4. Check the result
-	if result.ModifiedCount == 1 {
-		fmt.Println("Successfully pushed new slide to the document list.")
-	} else if result.MatchedCount == 0 {
-		return fmt.Errorf("document not found with ID: %s", documentId)
+	if err != nil {
+		return 0, fmt.Errorf("update failed: %w", err)
 	}
 
-	return nil
+	fmt.Println("Successfully pushed new slide to the document list.")
+	return seq, nil
 }
 
-func (r *DocumentRepository) RemoveSlide(ctx context.Context, docId string, slideId string) error {
+func (r *DocumentRepository) RemoveSlide(ctx context.Context, docId string, slideId string) (int64, error) {
 
 	// --- 1. Top-Level FILTER: Find the Document ---
 	docObjectID, err := primitive.ObjectIDFromHex(docId)
 	if err != nil {
-		return fmt.Errorf("invalid Document ID format: %w", err)
+		return 0, fmt.Errorf("invalid Document ID format: %w", err)
 	}
-	docFilter := bson.M{"_id": docObjectID}
+	// The target must exist in the filter, the version $inc alone always modifies the document
+	docFilter := bson.M{"_id": docObjectID, "slides._id": slideId}
 
 	// --- 2. Construct the $pull Update
 	update := bson.D{
@@ -84,37 +114,90 @@ func (r *DocumentRepository) RemoveSlide(ctx context.Context, docId string, slid
 			// Value: The query that identifies the element(s) to remove.
 			{Key: "slides", Value: bson.M{"_id": slideId}},
 		}},
+		incSeq,
+	}
+
+	// --- 3. Execute the update (No Array Filters Required) ---
+	seq, err := r.writeOp(ctx, docFilter, update, nil)
+	if err == mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("[Repository][RemoveSlide] Slide was not found or document ID is incorrect")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("[Repository][RemoveSlide] database update failed: %w", err)
 	}
 
-	// --- 3. Execute UpdateOne (No Array Filters Required) ---
-	// We pass nil for the options since arrayFilters is not needed.
-	result, err := r.collection.UpdateOne(
-		ctx,
-		docFilter,
-		update,
-		// options.Update() is optional here, as no complex options are used
-	)
+	fmt.Printf("[Repository][RemoveSlide] Successfully deleted slide %s\n", slideId)
+	return seq, nil
+}
 
+// UpdateSlide sets slide level properties (background, title, notes, size, ...)
+func (r *DocumentRepository) UpdateSlide(ctx context.Context, docId string, slideId string, updatedProperties map[string]interface{}) (int64, error) {
+	docObjectID, err := primitive.ObjectIDFromHex(docId)
 	if err != nil {
-		return fmt.Errorf("[Repository][RemoveSlide] database update failed: %w", err)
+		return 0, fmt.Errorf("invalid Document ID format: %w", err)
 	}
+	// The target must exist in the filter, the version $inc alone always modifies the document
+	docFilter := bson.M{"_id": docObjectID, "slides._id": slideId}
 
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("[Repository][RemoveSlide] Slide was not found or document ID is incorrect")
+	arrayFilters := []interface{}{
+		bson.M{"elem._id": slideId},
 	}
 
-	fmt.Printf("[Repository][RemoveSlide] Successfully deleted slide %s. Modified: %d\n", slideId, result.ModifiedCount)
-	return nil
+	setStage := bson.D{}
+	for key, value := range updatedProperties {
+		setStage = append(setStage, bson.E{Key: fmt.Sprintf("slides.$[elem].%s", key), Value: value})
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: setStage},
+		incSeq,
+	}
+
+	seq, err := r.writeOp(ctx, docFilter, update, arrayFilters)
+	if err == mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("[Repository][UpdateSlide] slide %s was not found", slideId)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("[Repository][UpdateSlide] database update failed: %w", err)
+	}
+
+	fmt.Printf("[Repository][UpdateSlide] Successfully updated slide %s\n", slideId)
+	return seq, nil
+}
+
+// SetConcurrencyMode persists the concurrency mode ("lock" or "lww") of the document
+func (r *DocumentRepository) SetConcurrencyMode(ctx context.Context, docId string, mode string) (int64, error) {
+	docObjectID, err := primitive.ObjectIDFromHex(docId)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Document ID format: %w", err)
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "concurrencyMode", Value: mode}}},
+		incSeq,
+	}
+
+	seq, err := r.writeOp(ctx, bson.M{"_id": docObjectID}, update, nil)
+	if err == mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("[Repository][SetConcurrencyMode] document %s was not found", docId)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("[Repository][SetConcurrencyMode] database update failed: %w", err)
+	}
+
+	fmt.Printf("[Repository][SetConcurrencyMode] Document %s uses %s\n", docId, mode)
+	return seq, nil
 }
 
-func (r *DocumentRepository) UpdateElement(ctx context.Context, docId string, slideId string, elementId string, updatedFields map[string]interface{}) error {
+func (r *DocumentRepository) UpdateElement(ctx context.Context, docId string, slideId string, elementId string, updatedFields map[string]interface{}) (int64, error) {
 
 	// --- 1. Top-Level FILTER: Find the Document ---
 	docObjectID, err := primitive.ObjectIDFromHex(docId)
 	if err != nil {
-		return fmt.Errorf("invalid Document ID format: %w", err)
+		return 0, fmt.Errorf("invalid Document ID format: %w", err)
 	}
-	docFilter := bson.M{"_id": docObjectID}
+	// The target must exist in the filter, the version $inc alone always modifies the document
+	docFilter := bson.M{"_id": docObjectID, "slides": bson.M{"$elemMatch": bson.M{"_id": slideId, "objects._id": elementId}}}
 
 	// --- 2. ARRAY FILTERS: Target the Slide and the Element ---
 	// Array Filters are defined using a slice of BSON documents.
@@ -147,47 +230,46 @@ func (r *DocumentRepository) UpdateElement(ctx context.Context, docId string, sl
 
 	update := bson.D{
 		{Key: "$set", Value: setStage},
+		incSeq,
 	}
 
-	// --- 4. Execute UpdateOne with Array Filters ---
-	result, err := r.collection.UpdateOne(
-		ctx,
-		docFilter,
-		update,
-		options.Update().SetArrayFilters(options.ArrayFilters{Filters: arrayFilters}),
-	)
-
-	if err != nil {
-		return fmt.Errorf("[Repository][UpdateElement] database update failed: %w", err)
+	// --- 4. Execute the update with Array Filters ---
+	seq, err := r.writeOp(ctx, docFilter, update, arrayFilters)
+
+	// Not a top level object, it may be the child of a group
+	if err == mongo.ErrNoDocuments {
+		return r.ApplyOperation(ctx, docId, map[string]interface{}{
+			"action":            "update",
+			"slideId":           slideId,
+			"objectId":          elementId,
+			"updatedAttributes": updatedFields,
+		})
 	}
-
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("[Repository][UpdateElement] no element was found or modified (IDs may be incorrect)")
+	if err != nil {
+		return 0, fmt.Errorf("[Repository][UpdateElement] database update failed: %w", err)
 	}
 
-	fmt.Printf("[Repository][UpdateElement] Successfully updated 1 element. Matched: %d, Modified: %d\n",
-		result.MatchedCount, result.ModifiedCount)
-	return nil
+	fmt.Printf("[Repository][UpdateElement] Successfully updated element %s\n", elementId)
+	return seq, nil
 }
 
-func (r *DocumentRepository) CreateElement(ctx context.Context, docId string, slideId string, newElementData model.Object) error {
+func (r *DocumentRepository) CreateElement(ctx context.Context, docId string, slideId string, newElementData model.Object) (int64, error) {
 	docObjectId, err := primitive.ObjectIDFromHex(docId)
 	if err != nil {
 		fmt.Printf("[DocumentRepository][CreateElement] Invalid document id: %v\n", err)
-		return err
+		return 0, err
 	}
 
 	// --- 1. Top-Level Filter: Find the Document ---
 	// Match the main document by its ID.
-	docFilter := bson.M{"_id": docObjectId}
+	// The target must exist in the filter, the version $inc alone always modifies the document
+	docFilter := bson.M{"_id": docObjectId, "slides._id": slideId}
 
 	// --- 2. ARRAY FILTERS: Target the Slide ---
 	// Define a filter to find the correct slide within the "slides" array.
-	arrayFilters := options.ArrayFilters{
-		Filters: []interface{}{
-			// The identifier 'elem' will point to the matching slide sub-document.
-			bson.M{"elem._id": slideId},
-		},
+	arrayFilters := []interface{}{
+		// The identifier 'elem' will point to the matching slide sub-document.
+		bson.M{"elem._id": slideId},
 	}
 
 	// --- 3. Construct the $PUSH Update ---
@@ -202,46 +284,37 @@ func (r *DocumentRepository) CreateElement(ctx context.Context, docId string, sl
 			// $push to the specific path defined by the positional filtered identifier '$[elem]'
 			{Key: updatePath, Value: newElementData},
 		}},
+		incSeq,
 	}
 
-	result, err := r.collection.UpdateOne(
-		ctx,
-		docFilter,
-		update,
-		options.Update().SetArrayFilters(arrayFilters),
-	)
-
-	if err != nil {
-		return fmt.Errorf("[Repository][CreateElement] database update failed: %w", err)
+	seq, err := r.writeOp(ctx, docFilter, update, arrayFilters)
+	if err == mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("[Repository][CreateElement] no element was created (IDs may be incorrect)")
 	}
-
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("[Repository][CreateElement] no element was created (IDs may be incorrect)")
+	if err != nil {
+		return 0, fmt.Errorf("[Repository][CreateElement] database update failed: %w", err)
 	}
 
-	fmt.Printf("[Repository][CreateElement] Successfully created 1 element. Matched: %d, Modified: %d\n",
-		result.MatchedCount, result.ModifiedCount)
-
-	return nil
+	fmt.Printf("[Repository][CreateElement] Successfully created element %s\n", newElementData.ID)
+	return seq, nil
 }
 
-func (r *DocumentRepository) DeleteElement(ctx context.Context, docId string, slideId string, elementId string) error {
+func (r *DocumentRepository) DeleteElement(ctx context.Context, docId string, slideId string, elementId string) (int64, error) {
 	docObjectId, err := primitive.ObjectIDFromHex(docId)
 	if err != nil {
 		fmt.Printf("[DocumentRepository][CreateElement] Invalid document id: %v\n", err)
-		return err
+		return 0, err
 	}
 
 	// --- 1. Top-Level Filter: Find the Document ---
-	docFilter := bson.M{"_id": docObjectId}
+	// The target must exist in the filter, the version $inc alone always modifies the document
+	docFilter := bson.M{"_id": docObjectId, "slides": bson.M{"$elemMatch": bson.M{"_id": slideId, "objects._id": elementId}}}
 
 	// --- 2. ARRAY FILTERS: Target the Slide ---
 	// We use the identifier 'elem' to find the specific slide based on its ID.
-	arrayFilters := options.ArrayFilters{
-		Filters: []interface{}{
-			// Filter 1: Find the slide that matches the slideID.
-			bson.M{"elem._id": slideId},
-		},
+	arrayFilters := []interface{}{
+		// Filter 1: Find the slide that matches the slideID.
+		bson.M{"elem._id": slideId},
 	}
 
 	// --- 3. Construct the $PULL Update ---
@@ -257,25 +330,143 @@ func (r *DocumentRepository) DeleteElement(ctx context.Context, docId string, sl
 			// $pull from the target array field (updatePath)
 			{Key: updatePath, Value: bson.M{"_id": elementId}},
 		}},
+		incSeq,
 	}
 
-	// --- 4. Execute UpdateOne with Array Filters ---
-	result, err := r.collection.UpdateOne(
-		ctx,
-		docFilter,
-		update,
-		options.Update().SetArrayFilters(arrayFilters),
-	)
+	// --- 4. Execute the update with Array Filters ---
+	seq, err := r.writeOp(ctx, docFilter, update, arrayFilters)
 
+	// Not a top level object, it may be the child of a group
+	if err == mongo.ErrNoDocuments {
+		return r.ApplyOperation(ctx, docId, map[string]interface{}{
+			"action":   "delete",
+			"slideId":  slideId,
+			"objectId": elementId,
+		})
+	}
 	if err != nil {
-		return fmt.Errorf("database $pull update failed: %w", err)
+		return 0, fmt.Errorf("database $pull update failed: %w", err)
 	}
 
-	if result.ModifiedCount == 0 {
-		// This means either the document, slide, or element wasn't found/deleted.
-		return fmt.Errorf("element not found or deleted (Element ID: %s)", elementId)
+	fmt.Printf("Successfully deleted element %s from slide %s.\n", elementId, slideId)
+	return seq, nil
+}
+
+// maxMutateRetries bounds how often MutateDocument re-reads the document after losing a race
+const maxMutateRetries = 5
+
+// MutateDocument loads the document, lets mutate change it in memory and writes the
+// slides back in a single update. The write only succeeds if the document version is
+// unchanged since it was read, otherwise the whole read-modify-write is retried.
+// It returns the seq of the op.
+func (r *DocumentRepository) MutateDocument(ctx context.Context, docId string, mutate func(doc *model.Document) error) (int64, error) {
+	docObjectId, err := primitive.ObjectIDFromHex(docId)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Document ID format: %w", err)
 	}
 
-	fmt.Printf("Successfully deleted element %s from slide %s.\n", elementId, slideId)
+	for attempt := 0; attempt < maxMutateRetries; attempt++ {
+		var doc model.Document
+		if err := r.collection.FindOne(ctx, bson.M{"_id": docObjectId}).Decode(&doc); err != nil {
+			return 0, fmt.Errorf("[Repository][MutateDocument] error retrieving document: %w", err)
+		}
+
+		if err := mutate(&doc); err != nil {
+			return 0, err
+		}
+
+		// documents written before versioning have no version field, null matches them
+		versionFilter := interface{}(doc.Version)
+		if doc.Version == 0 {
+			versionFilter = bson.M{"$in": bson.A{0, nil}}
+		}
+		filter := bson.M{"_id": docObjectId, "version": versionFilter}
+
+		update := bson.D{
+			{Key: "$set", Value: bson.D{{Key: "slides", Value: doc.Slides}}},
+			incSeq,
+		}
+
+		seq, err := r.writeOp(ctx, filter, update, nil)
+		if err == nil {
+			return seq, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return 0, fmt.Errorf("[Repository][MutateDocument] database update failed: %w", err)
+		}
+
+		fmt.Printf("[Repository][MutateDocument] document %s changed concurrently, retrying (%d/%d)\n", docId, attempt+1, maxMutateRetries)
+	}
+
+	return 0, fmt.Errorf("[Repository][MutateDocument] gave up after %d concurrent modifications", maxMutateRetries)
+}
+
+// ApplyBatch applies all ops of a batch message as one atomic update.
+// If any op fails (missing slide or object) nothing is written.
+func (r *DocumentRepository) ApplyBatch(ctx context.Context, docId string, ops []map[string]interface{}) (int64, error) {
+	seq, err := r.MutateDocument(ctx, docId, func(doc *model.Document) error {
+		for i, op := range ops {
+			if err := operation.Apply(doc, op); err != nil {
+				return fmt.Errorf("[Repository][ApplyBatch] op %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	fmt.Printf("[Repository][ApplyBatch] Successfully applied %d ops\n", len(ops))
+	return seq, nil
+}
+
+// ApplyOperation applies a structural op (reorder, group, ungroup, ...) which has to be
+// computed from the current document state, see operation.Apply for the supported actions.
+func (r *DocumentRepository) ApplyOperation(ctx context.Context, docId string, op map[string]interface{}) (int64, error) {
+	seq, err := r.MutateDocument(ctx, docId, func(doc *model.Document) error {
+		return operation.Apply(doc, op)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("[Repository][ApplyOperation] %w", err)
+	}
+
+	fmt.Printf("[Repository][ApplyOperation] Successfully applied %v on %v\n", op["action"], op["objectId"])
+	return seq, nil
+}
+
+// GetDocument reads the whole document
+func (r *DocumentRepository) GetDocument(ctx context.Context, docId string) (*model.Document, error) {
+	docObjectId, err := primitive.ObjectIDFromHex(docId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Document ID format: %w", err)
+	}
+
+	var doc model.Document
+	if err := r.collection.FindOne(ctx, bson.M{"_id": docObjectId}).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("[Repository][GetDocument] error retrieving document: %w", err)
+	}
+	return &doc, nil
+}
+
+// ResetSlides replaces every slide of the document, used when a version is restored
+func (r *DocumentRepository) ResetSlides(ctx context.Context, docId string, slides []model.Slide) (int64, error) {
+	return r.MutateDocument(ctx, docId, func(doc *model.Document) error {
+		doc.Slides = slides
+		return nil
+	})
+}
+
+// SeedSeq raises the seq of the document to at least seq, used to move the seqs of
+// documents edited before they were stored on the document
+func (r *DocumentRepository) SeedSeq(ctx context.Context, docId string, seq int64) error {
+	docObjectId, err := primitive.ObjectIDFromHex(docId)
+	if err != nil {
+		return fmt.Errorf("invalid Document ID format: %w", err)
+	}
+
+	update := bson.M{"$max": bson.M{"seq": seq}}
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": docObjectId}, update); err != nil {
+		return fmt.Errorf("[Repository][SeedSeq] %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"DocumentUpdatesConsumer/model"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OplogRepository struct {
+	collection *mongo.Collection
+	counters   *mongo.Collection // last seq of every document before seqs moved to the documents
+}
+
+func NewOplogRepository(client *mongo.Client, database string, collection string, counters string) *OplogRepository {
+	db := client.Database(database)
+	return &OplogRepository{
+		collection: db.Collection(collection),
+		counters:   db.Collection(counters),
+	}
+}
+
+// EnsureIndexes creates the (documentId, seq) index used by per-document range queries
+func (r *OplogRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "documentId", Value: 1}, {Key: "seq", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("[OplogRepository][EnsureIndexes] %w", err)
+	}
+	return nil
+}
+
+// maxAppendRetries bounds how often Append retries a failed insert
+const maxAppendRetries = 3
+
+// Append stores the entry, its seq is the one the write of the op gave it. Failed
+// inserts are retried, an entry which is already stored counts as appended.
+func (r *OplogRepository) Append(ctx context.Context, entry model.OplogEntry) error {
+	var err error
+	for attempt := 0; attempt < maxAppendRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("[OplogRepository][Append] %w", ctx.Err())
+			case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+			}
+		}
+
+		_, err = r.collection.InsertOne(ctx, entry)
+		if err == nil || mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		fmt.Printf("[OplogRepository][Append] op %d of %s: %v, retrying (%d/%d)\n", entry.Seq, entry.DocumentID, err, attempt+1, maxAppendRetries)
+	}
+	return fmt.Errorf("[OplogRepository][Append] %w", err)
+}
+
+// MigrateCounters moves the seqs of the counters collection to the documents, the
+// writes of ops increment the seq stored on the document since. A counter is deleted
+// once its document is seeded.
+func (r *OplogRepository) MigrateCounters(ctx context.Context, documents *DocumentRepository) error {
+	cursor, err := r.counters.Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("[OplogRepository][MigrateCounters] %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var counter struct {
+			DocumentID string `bson:"_id"`
+			Seq        int64  `bson:"seq"`
+		}
+		if err := cursor.Decode(&counter); err != nil {
+			return fmt.Errorf("[OplogRepository][MigrateCounters] %w", err)
+		}
+		if err := documents.SeedSeq(ctx, counter.DocumentID, counter.Seq); err != nil {
+			return fmt.Errorf("[OplogRepository][MigrateCounters] %w", err)
+		}
+		if _, err := r.counters.DeleteOne(ctx, bson.M{"_id": counter.DocumentID}); err != nil {
+			return fmt.Errorf("[OplogRepository][MigrateCounters] %w", err)
+		}
+	}
+	return cursor.Err()
+}
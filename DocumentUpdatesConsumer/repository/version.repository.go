@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"DocumentUpdatesConsumer/config"
+	"DocumentUpdatesConsumer/model"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// snapshotMark is the last automatic snapshot of a document
+type snapshotMark struct {
+	seq int64
+	at  time.Time
+}
+
+type VersionRepository struct {
+	collection *mongo.Collection
+
+	mu    sync.Mutex
+	marks map[string]snapshotMark // by document, loaded from the database on the first op
+}
+
+func NewVersionRepository(client *mongo.Client, database string, collection string) *VersionRepository {
+	return &VersionRepository{
+		collection: client.Database(database).Collection(collection),
+		marks:      make(map[string]snapshotMark),
+	}
+}
+
+// automaticFilter matches the snapshots taken by the consumer. Automatic versions
+// created by users (the copy saved before a restore) are never pruned.
+func automaticFilter(docId string) bson.M {
+	return bson.M{"documentId": docId, "kind": model.VersionKindAuto, "createdBy": bson.M{"$exists": false}}
+}
+
+// SnapshotDue tells whether an automatic snapshot should be taken now that the
+// document reached seq. When it is, the snapshot is claimed so concurrent ops of
+// the same document don't take it twice.
+func (r *VersionRepository) SnapshotDue(ctx context.Context, docId string, seq int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mark, ok := r.marks[docId]
+	if !ok {
+		var last model.Version
+		opts := options.FindOne().
+			SetSort(bson.D{{Key: "seq", Value: -1}}).
+			SetProjection(bson.M{"seq": 1, "createdAt": 1})
+		err := r.collection.FindOne(ctx, automaticFilter(docId), opts).Decode(&last)
+		switch {
+		case err == mongo.ErrNoDocuments:
+			// the first op of a document without snapshots takes one, playback needs a recent base
+			mark = snapshotMark{}
+		case err != nil:
+			return false, fmt.Errorf("[VersionRepository][SnapshotDue] %w", err)
+		default:
+			mark = snapshotMark{seq: last.Seq, at: last.CreatedAt}
+		}
+		r.marks[docId] = mark
+	}
+
+	if seq <= mark.seq {
+		return false, nil
+	}
+	if seq-mark.seq < config.SnapshotConfig.EveryOps && time.Since(mark.at) < config.SnapshotConfig.Interval {
+		return false, nil
+	}
+
+	r.marks[docId] = snapshotMark{seq: seq, at: time.Now()}
+	return true, nil
+}
+
+// Snapshot stores an automatic version of the document taken at seq and deletes the
+// automatic snapshots beyond the config.SnapshotConfig.Keep most recent ones. The
+// oldest snapshot is never deleted, playback of the ops before the kept ones starts
+// from it.
+func (r *VersionRepository) Snapshot(ctx context.Context, doc *model.Document, seq int64) error {
+	now := time.Now()
+	version := model.Version{
+		DocumentID: doc.ID.Hex(),
+		Name:       fmt.Sprintf("Snapshot %s", now.UTC().Format(time.RFC3339)),
+		Kind:       model.VersionKindAuto,
+		CreatedAt:  now,
+		Seq:        seq,
+		Title:      doc.Title,
+		Slides:     doc.Slides,
+	}
+	if _, err := r.collection.InsertOne(ctx, version); err != nil {
+		return fmt.Errorf("[VersionRepository][Snapshot] %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "seq", Value: -1}}).
+		SetSkip(config.SnapshotConfig.Keep).
+		SetProjection(bson.M{"_id": 1})
+	cursor, err := r.collection.Find(ctx, automaticFilter(version.DocumentID), opts)
+	if err != nil {
+		return fmt.Errorf("[VersionRepository][Snapshot] %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stale []model.Version
+	if err := cursor.All(ctx, &stale); err != nil {
+		return fmt.Errorf("[VersionRepository][Snapshot] %w", err)
+	}
+	// stale is sorted newest first, its last snapshot is the oldest one
+	if len(stale) <= 1 {
+		return nil
+	}
+	stale = stale[:len(stale)-1]
+
+	ids := make([]interface{}, 0, len(stale))
+	for _, v := range stale {
+		ids = append(ids, v.ID)
+	}
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return fmt.Errorf("[VersionRepository][Snapshot] %w", err)
+	}
+	return nil
+}
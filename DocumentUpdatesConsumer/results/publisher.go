@@ -0,0 +1,48 @@
+package results
+
+import (
+	"DocumentUpdatesConsumer/types"
+	"encoding/json"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Topic the results of ops are published to, UpdatesService relays them to the rooms
+const Topic = "document-results"
+
+// Publisher publishes what became of every op the consumer received
+type Publisher struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+func NewPublisher(p *kafka.Producer, topic string) *Publisher {
+	return &Publisher{producer: p, topic: topic}
+}
+
+// Publish sends the result keyed by document so the results of a document stay in order
+func (p *Publisher) Publish(result types.ResultMessage) {
+	value, err := json.Marshal(result)
+	if err != nil {
+		fmt.Printf("[Publisher][Publish] json Marshalling error: %v\n", err)
+		return
+	}
+
+	message := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		Key:            []byte(result.DocumentID),
+		Value:          value,
+	}
+
+	deliveryChan := make(chan kafka.Event, 1)
+	if err := p.producer.Produce(message, deliveryChan); err != nil {
+		fmt.Printf("[Publisher][Publish] Error producing result: %v\n", err)
+		return
+	}
+
+	e := <-deliveryChan
+	if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+		fmt.Printf("[Publisher][Publish] Delivery failed: %v\n", m.TopicPartition.Error)
+	}
+}
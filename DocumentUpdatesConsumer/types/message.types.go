@@ -1,9 +1,35 @@
 package types
 
+import "time"
+
 type Message struct {
-	DocumentID string `json:"documentId"`
-	UserID     string `json:"userId"`
-	Username   string `json:"username"`
-	Type       int    `json:"type"`
-	Body       string `json:"body"`
+	DocumentID string                 `json:"documentId"`
+	UserID     string                 `json:"userId"`
+	Username   string                 `json:"username"`
+	Type       int                    `json:"type"`
+	Body       string                 `json:"body"`
+	OpID       string                 `json:"opId,omitempty"`    // set by UpdatesService, results refer to it
+	Inverse    map[string]interface{} `json:"inverse,omitempty"` // op reverting Body, computed by UpdatesService
+	Timestamp  time.Time              `json:"-"`                 // time the message was produced to kafka
+	Partition  int32                  `json:"-"`                 // kafka partition and offset, see handler.Offsets
+	Offset     int64                  `json:"-"`
+}
+
+// Result statuses
+const (
+	ResultApplied = "applied"
+	ResultFailed  = "failed"
+)
+
+// ResultMessage tells UpdatesService what became of an op. Failed ops carry the
+// inverse UpdatesService computed so clients can revert them.
+type ResultMessage struct {
+	OpID       string                 `json:"opId"`
+	DocumentID string                 `json:"documentId"`
+	UserID     string                 `json:"userId"`
+	Action     string                 `json:"action"`
+	Status     string                 `json:"status"`
+	Seq        int64                  `json:"seq,omitempty"` // oplog seq of applied ops
+	Error      string                 `json:"error,omitempty"`
+	Inverse    map[string]interface{} `json:"inverse,omitempty"`
 }
@@ -0,0 +1,201 @@
+package geometry
+
+import (
+	"Shared/model"
+	"math"
+)
+
+// Rect is an axis aligned rectangle in canvas coordinates
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (r Rect) Center() (float64, float64) {
+	return r.X + r.Width/2, r.Y + r.Height/2
+}
+
+// Union returns the smallest rect containing both rects
+func (r Rect) Union(o Rect) Rect {
+	minX := math.Min(r.X, o.X)
+	minY := math.Min(r.Y, o.Y)
+	maxX := math.Max(r.X+r.Width, o.X+o.Width)
+	maxY := math.Max(r.Y+r.Height, o.Y+o.Height)
+	return Rect{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
+
+// Intersects reports whether the rects overlap, touching edges count
+func (r Rect) Intersects(o Rect) bool {
+	return r.X <= o.X+o.Width && o.X <= r.X+r.Width &&
+		r.Y <= o.Y+o.Height && o.Y <= r.Y+r.Height
+}
+
+// LocalBounds returns the bounds of an object before its own transform is applied
+func LocalBounds(obj *model.Object) (Rect, bool) {
+	attr := obj.Attributes
+
+	switch obj.Type {
+	case "circle":
+		r := ToFloat(attr["radius"])
+		return Rect{X: ToFloat(attr["cx"]) - r, Y: ToFloat(attr["cy"]) - r, Width: 2 * r, Height: 2 * r}, true
+
+	case "text":
+		return Rect{X: ToFloat(attr["bx"]), Y: ToFloat(attr["by"]), Width: ToFloat(attr["width"]), Height: ToFloat(attr["height"])}, true
+
+	case "pen":
+		return pointsBounds(attr)
+
+	case "group":
+		var bounds Rect
+		found := false
+		for i := range obj.Children {
+			childBounds, ok := Bounds(&obj.Children[i])
+			if !ok {
+				continue
+			}
+			if !found {
+				bounds = childBounds
+				found = true
+			} else {
+				bounds = bounds.Union(childBounds)
+			}
+		}
+		// children are relative to the group offset
+		bounds.X += ToFloat(attr["x"])
+		bounds.Y += ToFloat(attr["y"])
+		return bounds, found
+	}
+
+	// rectangle, line, arrow, image
+	if _, ok := attr["x"]; !ok {
+		return Rect{}, false
+	}
+	return normalize(Rect{X: ToFloat(attr["x"]), Y: ToFloat(attr["y"]), Width: ToFloat(attr["width"]), Height: ToFloat(attr["height"])}), true
+}
+
+// Bounds returns the axis aligned bounding box of an object with its transform applied
+func Bounds(obj *model.Object) (Rect, bool) {
+	local, ok := LocalBounds(obj)
+	if !ok {
+		return Rect{}, false
+	}
+
+	t := ReadTransform(obj.Attributes)
+	if t.Rotation == 0 && t.ScaleX == 1 && t.ScaleY == 1 {
+		// opacity and flips around the center do not change the box
+		return local, true
+	}
+
+	cx, cy := local.Center()
+	return transformRect(local, t, cx, cy), true
+}
+
+// ObjectBounds returns the slide space bounds of the object with the given id,
+// objects inside groups are moved by the offsets and transforms of their groups
+func ObjectBounds(objects []model.Object, objectId string) (Rect, bool) {
+	for i := range objects {
+		obj := &objects[i]
+		if obj.ID == objectId {
+			return Bounds(obj)
+		}
+		// children of groups and frames are relative to their container
+		if obj.Type != "group" && obj.Type != "frame" {
+			continue
+		}
+
+		bounds, ok := ObjectBounds(obj.Children, objectId)
+		if !ok {
+			continue
+		}
+		bounds.X += ToFloat(obj.Attributes["x"])
+		bounds.Y += ToFloat(obj.Attributes["y"])
+
+		if t := ReadTransform(obj.Attributes); t != IdentityTransform {
+			groupBounds, _ := LocalBounds(obj)
+			cx, cy := groupBounds.Center()
+			bounds = transformRect(bounds, t, cx, cy)
+		}
+		return bounds, true
+	}
+	return Rect{}, false
+}
+
+// transformRect returns the axis aligned box of r mapped through t pivoting on (cx, cy)
+func transformRect(r Rect, t Transform, cx, cy float64) Rect {
+	corners := [4][2]float64{
+		{r.X, r.Y},
+		{r.X + r.Width, r.Y},
+		{r.X, r.Y + r.Height},
+		{r.X + r.Width, r.Y + r.Height},
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range corners {
+		x, y := t.Apply(corner[0], corner[1], cx, cy)
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+
+	return Rect{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
+
+func pointsBounds(attr map[string]interface{}) (Rect, bool) {
+	var points []float64
+
+	if encoded, ok := attr["points"].(string); ok {
+		decoded, err := DecodePoints(encoded, ToFloat(attr["pointsScale"]))
+		if err != nil {
+			return Rect{}, false
+		}
+		points = decoded
+	} else if raw, ok := asList(attr["points"]); ok {
+		points = make([]float64, len(raw))
+		for i, v := range raw {
+			points[i] = ToFloat(v)
+		}
+	}
+
+	if len(points) < 2 {
+		return Rect{}, false
+	}
+
+	minX, minY := points[0], points[1]
+	maxX, maxY := minX, minY
+	for i := 2; i+1 < len(points); i += 2 {
+		minX, maxX = math.Min(minX, points[i]), math.Max(maxX, points[i])
+		minY, maxY = math.Min(minY, points[i+1]), math.Max(maxY, points[i+1])
+	}
+
+	// the stroke width reaches outside of the points
+	half := ToFloat(attr["strokeWidth"]) / 2
+	return Rect{X: minX - half, Y: minY - half, Width: maxX - minX + 2*half, Height: maxY - minY + 2*half}, true
+}
+
+// normalize makes width and height positive (lines drawn right to left have negative sizes)
+func normalize(r Rect) Rect {
+	if r.Width < 0 {
+		r.X += r.Width
+		r.Width = -r.Width
+	}
+	if r.Height < 0 {
+		r.Y += r.Height
+		r.Height = -r.Height
+	}
+	return r
+}
+
+// ObjectsInViewport returns the objects whose bounds intersect the viewport, in z-order.
+// Objects without bounds are always returned.
+func ObjectsInViewport(objects []model.Object, viewport Rect) []model.Object {
+	visible := make([]model.Object, 0)
+	for i := range objects {
+		bounds, ok := Bounds(&objects[i])
+		if !ok || bounds.Intersects(viewport) {
+			visible = append(visible, objects[i])
+		}
+	}
+	return visible
+}
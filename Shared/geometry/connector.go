@@ -0,0 +1,115 @@
+package geometry
+
+import (
+	"Shared/model"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Connectors are line and arrow objects whose ends may be bound to other objects
+// of the same slide through the optional "startBinding" / "endBinding" attributes:
+//
+//	{"objectId": "...", "anchor": "top"|"right"|"bottom"|"left"|"center", "offset": 0..1}
+//
+// The start of a line is (x, y), its end (x+width, y+height). The ends of bound
+// connectors are recomputed by the server whenever the objects they are bound to change.
+
+var ConnectorBindingKeys = [2]string{"startBinding", "endBinding"}
+
+// Binding attaches one end of a connector to an object
+type Binding struct {
+	ObjectID string
+	Anchor   string  // side of the target's bounds
+	Offset   float64 // position along the side, 0.5 (the middle) when omitted
+}
+
+func IsConnector(obj *model.Object) bool {
+	return obj.Type == "line" || obj.Type == "arrow"
+}
+
+// ReadBinding reads attr[key], a missing or null binding leaves that end free
+func ReadBinding(attr map[string]interface{}, key string) (Binding, bool) {
+	var block map[string]interface{}
+	switch m := attr[key].(type) {
+	case map[string]interface{}:
+		block = m
+	case primitive.M:
+		block = m
+	default:
+		return Binding{}, false
+	}
+
+	objectId, ok := block["objectId"].(string)
+	if !ok || objectId == "" {
+		return Binding{}, false
+	}
+
+	b := Binding{ObjectID: objectId, Anchor: "center", Offset: 0.5}
+	if anchor, ok := block["anchor"].(string); ok {
+		b.Anchor = anchor
+	}
+	if offset, ok := block["offset"]; ok {
+		b.Offset = ToFloat(offset)
+	}
+	return b, true
+}
+
+// AnchorPoint returns the point of r the binding attaches to
+func (b Binding) AnchorPoint(r Rect) (float64, float64) {
+	switch b.Anchor {
+	case "top":
+		return r.X + b.Offset*r.Width, r.Y
+	case "bottom":
+		return r.X + b.Offset*r.Width, r.Y + r.Height
+	case "left":
+		return r.X, r.Y + b.Offset*r.Height
+	case "right":
+		return r.X + r.Width, r.Y + b.Offset*r.Height
+	}
+	return r.Center()
+}
+
+// connectorEpsilon ignores rounding noise when comparing endpoints
+const connectorEpsilon = 0.01
+
+// RouteConnector recomputes the ends of a connector of the given slide objects from the
+// objects it is bound to. It returns the attributes to update, nil when nothing changed.
+// A binding whose target no longer exists is removed and that end stays where it was.
+// The connector's own transform is not taken into account.
+func RouteConnector(objects []model.Object, connector *model.Object) map[string]interface{} {
+	attr := connector.Attributes
+	x, y := ToFloat(attr["x"]), ToFloat(attr["y"])
+	ends := [2][2]float64{{x, y}, {x + ToFloat(attr["width"]), y + ToFloat(attr["height"])}}
+
+	updated := make(map[string]interface{})
+	for i, key := range ConnectorBindingKeys {
+		b, ok := ReadBinding(attr, key)
+		if !ok || b.ObjectID == connector.ID {
+			continue
+		}
+
+		target, ok := ObjectBounds(objects, b.ObjectID)
+		if !ok {
+			updated[key] = nil
+			continue
+		}
+		ends[i][0], ends[i][1] = b.AnchorPoint(target)
+	}
+
+	newX, newY := ends[0][0], ends[0][1]
+	newWidth, newHeight := ends[1][0]-newX, ends[1][1]-newY
+	if math.Abs(newX-x) > connectorEpsilon || math.Abs(newY-y) > connectorEpsilon ||
+		math.Abs(newWidth-ToFloat(attr["width"])) > connectorEpsilon ||
+		math.Abs(newHeight-ToFloat(attr["height"])) > connectorEpsilon {
+		updated["x"] = newX
+		updated["y"] = newY
+		updated["width"] = newWidth
+		updated["height"] = newHeight
+	}
+
+	if len(updated) == 0 {
+		return nil
+	}
+	return updated
+}
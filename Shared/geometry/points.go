@@ -0,0 +1,140 @@
+package geometry
+
+import (
+	"Shared/model"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// PointsEncoding identifies compact pen points stored as a base64 string of
+// zigzag varints. Coordinates are quantized with pointsScale (value*scale
+// rounded) and every x/y is stored as the delta from the previous x/y.
+const PointsEncoding = "delta-varint"
+
+// EncodePoints packs a flat [x0, y0, x1, y1, ...] list into the compact encoding
+func EncodePoints(points []float64, scale float64) string {
+	buf := make([]byte, 0, len(points)*2)
+	tmp := make([]byte, binary.MaxVarintLen64)
+
+	var prevX, prevY int64
+	for i, v := range points {
+		q := int64(math.Round(v * scale))
+		var delta int64
+		if i%2 == 0 {
+			delta = q - prevX
+			prevX = q
+		} else {
+			delta = q - prevY
+			prevY = q
+		}
+		n := binary.PutVarint(tmp, delta) // PutVarint uses zigzag encoding
+		buf = append(buf, tmp[:n]...)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// DecodePoints turns compact points back into a flat [x0, y0, x1, y1, ...] list
+func DecodePoints(encoded string, scale float64) ([]float64, error) {
+	buf, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid points encoding: %w", err)
+	}
+
+	points := make([]float64, 0, len(buf))
+	var prevX, prevY int64
+	for i := 0; len(buf) > 0; i++ {
+		delta, n := binary.Varint(buf)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid points varint at index %d", i)
+		}
+		buf = buf[n:]
+
+		if i%2 == 0 {
+			prevX += delta
+			points = append(points, float64(prevX)/scale)
+		} else {
+			prevY += delta
+			points = append(points, float64(prevY)/scale)
+		}
+	}
+
+	return points, nil
+}
+
+// ExpandPenPoints replaces compact pen points of every object in the document
+// with the verbose number list
+func ExpandPenPoints(doc *model.Document) error {
+	for i := range doc.Slides {
+		if err := ExpandObjects(doc.Slides[i].Objects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpandObjects decodes the compact pen points of the objects and of their children
+func ExpandObjects(objects []model.Object) error {
+	for _, obj := range objects {
+		if err := ExpandObjectPoints(obj.Attributes); err != nil {
+			return fmt.Errorf("object %s: %w", obj.ID, err)
+		}
+		if err := ExpandObjects(obj.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpandObjectPoints decodes attr["points"] in place if it uses the compact encoding
+func ExpandObjectPoints(attr map[string]interface{}) error {
+	if encoding, _ := attr["pointsEncoding"].(string); encoding != PointsEncoding {
+		return nil
+	}
+
+	encoded, ok := attr["points"].(string)
+	if !ok {
+		return fmt.Errorf("compact points are not a string")
+	}
+
+	scale := ToFloat(attr["pointsScale"])
+	if scale == 0 {
+		return fmt.Errorf("missing pointsScale")
+	}
+
+	points, err := DecodePoints(encoded, scale)
+	if err != nil {
+		return err
+	}
+
+	attr["points"] = points
+	delete(attr, "pointsEncoding")
+	delete(attr, "pointsScale")
+	return nil
+}
+
+// CompactPenPoints simplifies and encodes attr["points"] in place. Attributes
+// whose points are not a flat list of numbers are left untouched.
+func CompactPenPoints(attr map[string]interface{}, tolerance float64, scale float64) {
+	raw, ok := attr["points"].([]interface{})
+	if !ok || len(raw)%2 != 0 {
+		return
+	}
+
+	points := make([]float64, 0, len(raw))
+	for _, v := range raw {
+		f, ok := v.(float64)
+		if !ok {
+			return
+		}
+		points = append(points, f)
+	}
+
+	points = SimplifyPoints(points, tolerance)
+
+	attr["points"] = EncodePoints(points, scale)
+	attr["pointsEncoding"] = PointsEncoding
+	attr["pointsScale"] = scale
+}
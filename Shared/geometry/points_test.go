@@ -0,0 +1,81 @@
+package geometry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodePoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []float64
+		scale  float64
+		want   []float64
+	}{
+		{"empty", []float64{}, 10, []float64{}},
+		{"single point", []float64{12.5, -3.2}, 10, []float64{12.5, -3.2}},
+		{"negative deltas", []float64{100, 100, 50.5, 20.1, -75.3, 300}, 10, []float64{100, 100, 50.5, 20.1, -75.3, 300}},
+		{"quantized to the scale", []float64{1.234, 5.678}, 10, []float64{1.2, 5.7}},
+		{"large coordinates", []float64{1e7, -1e7, 1e7 + 0.1, -1e7 - 0.1}, 10, []float64{1e7, -1e7, 1e7 + 0.1, -1e7 - 0.1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodePoints(EncodePoints(tt.points, tt.scale), tt.scale)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePointsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"not base64", "%%%"},
+		{"truncated varint", "gA=="}, // 0x80 announces a byte which is missing
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodePoints(tt.encoded, 10); err == nil {
+				t.Errorf("decoding %q succeeded", tt.encoded)
+			}
+		})
+	}
+}
+
+func TestCompactAndExpandPenPoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    interface{}
+		tolerance float64
+		want      interface{} // points after a compact and expand round trip
+	}{
+		{"straight line is simplified", []interface{}{0.0, 0.0, 5.0, 0.0, 10.0, 0.0}, 0.5, []float64{0, 0, 10, 0}},
+		{"no tolerance keeps every point", []interface{}{0.0, 0.0, 5.0, 0.0, 10.0, 0.0}, 0, []float64{0, 0, 5, 0, 10, 0}},
+		{"odd length is left untouched", []interface{}{0.0, 0.0, 5.0}, 0.5, []interface{}{0.0, 0.0, 5.0}},
+		{"non numbers are left untouched", []interface{}{"a", "b"}, 0.5, []interface{}{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attr := map[string]interface{}{"points": tt.points}
+			CompactPenPoints(attr, tt.tolerance, 10)
+			if err := ExpandObjectPoints(attr); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(attr["points"], tt.want) {
+				t.Errorf("got %v, want %v", attr["points"], tt.want)
+			}
+			if _, ok := attr["pointsEncoding"]; ok {
+				t.Errorf("pointsEncoding left after expanding: %v", attr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package geometry
+
+import "math"
+
+// SimplifyPoints reduces a flat [x0, y0, x1, y1, ...] polyline with the
+// Ramer-Douglas-Peucker algorithm. Points closer than tolerance to the
+// simplified line are dropped, the first and last points are always kept.
+func SimplifyPoints(points []float64, tolerance float64) []float64 {
+	n := len(points) / 2
+	if tolerance <= 0 || n < 3 {
+		return points
+	}
+
+	keep := make([]bool, n)
+	keep[0] = true
+	keep[n-1] = true
+
+	// iterative version, strokes can have thousands of points
+	stack := [][2]int{{0, n - 1}}
+	for len(stack) > 0 {
+		seg := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		first, last := seg[0], seg[1]
+
+		maxDist := 0.0
+		index := -1
+		for i := first + 1; i < last; i++ {
+			d := perpendicularDistance(
+				points[2*i], points[2*i+1],
+				points[2*first], points[2*first+1],
+				points[2*last], points[2*last+1],
+			)
+			if d > maxDist {
+				maxDist = d
+				index = i
+			}
+		}
+
+		if index != -1 && maxDist > tolerance {
+			keep[index] = true
+			stack = append(stack, [2]int{first, index}, [2]int{index, last})
+		}
+	}
+
+	simplified := make([]float64, 0, len(points))
+	for i := 0; i < n; i++ {
+		if keep[i] {
+			simplified = append(simplified, points[2*i], points[2*i+1])
+		}
+	}
+	return simplified
+}
+
+// perpendicularDistance returns the distance of (px, py) from the segment (ax, ay)-(bx, by)
+func perpendicularDistance(px, py, ax, ay, bx, by float64) float64 {
+	dx := bx - ax
+	dy := by - ay
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return math.Hypot(px-ax, py-ay)
+	}
+	return math.Abs(dy*px-dx*py+bx*ay-by*ax) / length
+}
@@ -0,0 +1,32 @@
+package geometry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimplifyPoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    []float64
+		tolerance float64
+		want      []float64
+	}{
+		{"fewer than three points", []float64{0, 0, 10, 10}, 1, []float64{0, 0, 10, 10}},
+		{"zero tolerance", []float64{0, 0, 5, 0.1, 10, 0}, 0, []float64{0, 0, 5, 0.1, 10, 0}},
+		{"collinear points are dropped", []float64{0, 0, 1, 1, 2, 2, 3, 3}, 0.5, []float64{0, 0, 3, 3}},
+		{"deviation within tolerance", []float64{0, 0, 5, 0.4, 10, 0}, 0.5, []float64{0, 0, 10, 0}},
+		{"deviation beyond tolerance", []float64{0, 0, 5, 2, 10, 0}, 0.5, []float64{0, 0, 5, 2, 10, 0}},
+		{"only the corner is kept", []float64{0, 0, 5, 0, 10, 0, 10, 5, 10, 10}, 0.5, []float64{0, 0, 10, 0, 10, 10}},
+		{"closed stroke keeps its farthest point", []float64{0, 0, 10, 0, 10, 10, 0, 0}, 0.5, []float64{0, 0, 10, 0, 10, 10, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SimplifyPoints(tt.points, tt.tolerance)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package geometry
+
+import (
+	"Shared/model"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Transform is the optional "transform" attribute block shared by every object type.
+// It is applied around the center of the untransformed bounds: scale and flip first,
+// then the rotation (degrees, clockwise).
+type Transform struct {
+	Rotation float64
+	Opacity  float64
+	ScaleX   float64
+	ScaleY   float64
+	FlipX    bool
+	FlipY    bool
+}
+
+// IdentityTransform is the transform of objects without a transform block
+var IdentityTransform = Transform{Opacity: 1, ScaleX: 1, ScaleY: 1}
+
+// ReadTransform reads attr["transform"], missing values take the identity defaults
+func ReadTransform(attr map[string]interface{}) Transform {
+	t := IdentityTransform
+
+	var block map[string]interface{}
+	switch m := attr["transform"].(type) {
+	case map[string]interface{}:
+		block = m
+	case primitive.M:
+		block = m
+	default:
+		return t
+	}
+
+	if v, ok := block["rotation"]; ok {
+		t.Rotation = ToFloat(v)
+	}
+	if v, ok := block["opacity"]; ok {
+		t.Opacity = ToFloat(v)
+	}
+	if v, ok := block["scaleX"]; ok {
+		t.ScaleX = ToFloat(v)
+	}
+	if v, ok := block["scaleY"]; ok {
+		t.ScaleY = ToFloat(v)
+	}
+	t.FlipX, _ = block["flipX"].(bool)
+	t.FlipY, _ = block["flipY"].(bool)
+	return t
+}
+
+// WriteTransform stores t as attr["transform"]
+func WriteTransform(attr map[string]interface{}, t Transform) {
+	attr["transform"] = map[string]interface{}{
+		"rotation": t.Rotation,
+		"opacity":  t.Opacity,
+		"scaleX":   t.ScaleX,
+		"scaleY":   t.ScaleY,
+		"flipX":    t.FlipX,
+		"flipY":    t.FlipY,
+	}
+}
+
+// Apply maps the point (x, y) through the transform pivoting on (cx, cy)
+func (t Transform) Apply(x, y, cx, cy float64) (float64, float64) {
+	dx := (x - cx) * t.ScaleX
+	dy := (y - cy) * t.ScaleY
+	if t.FlipX {
+		dx = -dx
+	}
+	if t.FlipY {
+		dy = -dy
+	}
+
+	rad := t.Rotation * math.Pi / 180
+	sin, cos := math.Sincos(rad)
+	return cx + dx*cos - dy*sin, cy + dx*sin + dy*cos
+}
+
+// ApplyParentTransform bakes the transform t of a parent group, pivoting on (cx, cy),
+// into a child which is already in slide coordinates. The child is moved so that its
+// center follows the parent transform and t is composed into the child's own transform.
+// Non uniform parent scales on rotated children are approximated.
+func ApplyParentTransform(obj *model.Object, t Transform, cx, cy float64) {
+	if t == IdentityTransform {
+		return
+	}
+
+	if bounds, ok := LocalBounds(obj); ok {
+		x, y := bounds.Center()
+		newX, newY := t.Apply(x, y, cx, cy)
+		Translate(obj, newX-x, newY-y)
+	}
+
+	ct := ReadTransform(obj.Attributes)
+	// a single mirror axis reverses the direction of the child's rotation
+	if t.FlipX != t.FlipY {
+		ct.Rotation = -ct.Rotation
+	}
+	ct.Rotation = math.Mod(ct.Rotation+t.Rotation, 360)
+	ct.FlipX = ct.FlipX != t.FlipX
+	ct.FlipY = ct.FlipY != t.FlipY
+	ct.ScaleX *= t.ScaleX
+	ct.ScaleY *= t.ScaleY
+	ct.Opacity *= t.Opacity
+
+	WriteTransform(obj.Attributes, ct)
+}
@@ -0,0 +1,101 @@
+package geometry
+
+import (
+	"Shared/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// positionKeys lists the attributes holding the position of each object type
+var positionKeys = map[string][2]string{
+	"circle": {"cx", "cy"},
+	"text":   {"bx", "by"},
+}
+
+// Translate moves an object by (dx, dy). Group children are relative to
+// the group so moving a group only changes the group's own offset.
+func Translate(obj *model.Object, dx, dy float64) {
+	if dx == 0 && dy == 0 {
+		return
+	}
+	if obj.Attributes == nil {
+		obj.Attributes = make(map[string]interface{})
+	}
+	attr := obj.Attributes
+
+	if obj.Type == "pen" {
+		translatePoints(attr, dx, dy)
+		return
+	}
+
+	keys, ok := positionKeys[obj.Type]
+	if !ok {
+		keys = [2]string{"x", "y"} // rectangle, line, arrow, image, group
+	}
+	attr[keys[0]] = ToFloat(attr[keys[0]]) + dx
+	attr[keys[1]] = ToFloat(attr[keys[1]]) + dy
+}
+
+func translatePoints(attr map[string]interface{}, dx, dy float64) {
+	if encoded, ok := attr["points"].(string); ok {
+		scale := ToFloat(attr["pointsScale"])
+		if scale == 0 {
+			return
+		}
+		decoded, err := DecodePoints(encoded, scale)
+		if err != nil {
+			return
+		}
+		for i := range decoded {
+			if i%2 == 0 {
+				decoded[i] += dx
+			} else {
+				decoded[i] += dy
+			}
+		}
+		attr["points"] = EncodePoints(decoded, scale)
+		return
+	}
+
+	raw, ok := asList(attr["points"])
+	if !ok {
+		return
+	}
+	points := make([]float64, len(raw))
+	for i, v := range raw {
+		points[i] = ToFloat(v)
+		if i%2 == 0 {
+			points[i] += dx
+		} else {
+			points[i] += dy
+		}
+	}
+	attr["points"] = points
+}
+
+// ToFloat reads a number regardless of the type json or bson decoded it into
+func ToFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int:
+		return float64(n)
+	}
+	return 0
+}
+
+func asList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, true
+	case primitive.A:
+		return l, true
+	}
+	return nil, false
+}
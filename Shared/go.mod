@@ -0,0 +1,5 @@
+module Shared
+
+go 1.25.1
+
+require go.mongodb.org/mongo-driver v1.17.4
@@ -0,0 +1,55 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type Slide struct {
+	ID         string   `bson:"_id" json:"id"`
+	Background string   `bson:"background" json:"background"`
+	Objects    []Object `bson:"objects" json:"objects"`
+
+	// Optional slide properties, set through update_slide
+	BackgroundImage string  `bson:"backgroundImage,omitempty" json:"backgroundImage,omitempty"` // asset id or url drawn over the background color
+	Title           string  `bson:"title,omitempty" json:"title,omitempty"`
+	Notes           string  `bson:"notes,omitempty" json:"notes,omitempty"` // speaker notes
+	Width           float64 `bson:"width,omitempty" json:"width,omitempty"` // custom size, the deck default when 0
+	Height          float64 `bson:"height,omitempty" json:"height,omitempty"`
+
+	// Named layers from bottom to top, objects without a layerId are on the
+	// implicit base layer below them (always visible and unlocked)
+	Layers []Layer `bson:"layers,omitempty" json:"layers,omitempty"`
+}
+
+type Layer struct {
+	ID      string `bson:"_id" json:"id"`
+	Name    string `bson:"name" json:"name"`
+	Visible bool   `bson:"visible" json:"visible"`
+	Locked  bool   `bson:"locked" json:"locked"` // objects of a locked layer can't be changed
+}
+
+type Document struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Title   string             `bson:"title" json:"title"`
+	OwnerID string             `bson:"ownerId" json:"ownerId"`
+	Slides  []Slide            `bson:"slides" json:"slides"`
+	Version int64              `bson:"version" json:"version"` // incremented by every write, used for optimistic concurrency
+	Seq     int64              `bson:"seq" json:"seq"`         // incremented with version by every op, the oplog entry of the last op has this seq
+
+	ConcurrencyMode string `bson:"concurrencyMode,omitempty" json:"concurrencyMode,omitempty"` // "lock" (default) or "lww"
+	Kind            string `bson:"kind,omitempty" json:"kind,omitempty"`                       // DocumentKindSlides (default) or DocumentKindWhiteboard
+}
+
+// Document kinds. A whiteboard has a single slide used as an unbounded canvas:
+// it has no size and objects may be placed at any (also negative) coordinates.
+const (
+	DocumentKindSlides     = "slides"
+	DocumentKindWhiteboard = "whiteboard"
+)
+
+type Object struct {
+	ID         string                 `bson:"_id" json:"id"`
+	Type       string                 `bson:"type" json:"type"`
+	Attributes map[string]interface{} `bson:"attributes" json:"attributes"`
+	Children   []Object               `bson:"children,omitempty" json:"children,omitempty"` // members of a group or contents of a frame
+	LayerID    string                 `bson:"layerId,omitempty" json:"layerId,omitempty"`   // only set on the objects of a slide, not on children
+	Stamps     map[string]string      `bson:"stamps,omitempty" json:"stamps,omitempty"`     // lww mode: stamp of the last write of each attribute
+}
@@ -0,0 +1,195 @@
+package operation
+
+import (
+	"Shared/geometry"
+	"Shared/model"
+	"Shared/text"
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultSlideBackground is the background of slides added without one
+const DefaultSlideBackground = "#fff"
+
+// PenOptions sets how pen strokes are stored. Every service prepares attributes with
+// the same values, in-memory copies of a document then match the stored one.
+var PenOptions = struct {
+	SimplifyTolerance float64 // max deviation (in canvas px) allowed when simplifying strokes, 0 disables it
+	PointScale        float64 // points are quantized to 1/PointScale px
+}{
+	SimplifyTolerance: 0.5,
+	PointScale:        10,
+}
+
+// PrepareAttributes converts created or updated attributes to their stored form:
+// pen strokes are simplified and use the compact points encoding, rich text
+// gets its plain text copied to value.
+func PrepareAttributes(objectType string, attr map[string]interface{}) {
+	switch objectType {
+	case "pen":
+		geometry.CompactPenPoints(attr, PenOptions.SimplifyTolerance, PenOptions.PointScale)
+	case "text":
+		if richText, ok := attr["richText"]; ok {
+			if value, ok := text.PlainText(richText); ok {
+				attr["value"] = value
+			}
+		}
+	}
+}
+
+// prepareOp prepares the attributes a create or update op carries
+func prepareOp(op map[string]interface{}) {
+	objectType, _ := op["objectType"].(string)
+	for _, key := range []string{"attributes", "updatedAttributes"} {
+		if attr, ok := op[key].(map[string]interface{}); ok {
+			PrepareAttributes(objectType, attr)
+		}
+	}
+}
+
+// ApplyAction applies an action the way the consumer persists it: slide ops, batches,
+// document resets, stroke_end and commit_update (stored as create and update) and the
+// ops handled by Apply. The action and its attributes are changed in place.
+func ApplyAction(doc *model.Document, action map[string]interface{}) error {
+	actVal, _ := action["action"].(string)
+	slideId, _ := action["slideId"].(string)
+
+	switch actVal {
+	case "add_slide":
+		background, _ := action["background"].(string)
+		index := -1
+		if action["index"] != nil {
+			index = int(geometry.ToFloat(action["index"]))
+		}
+		AddSlide(doc, slideId, background, index)
+		return nil
+
+	case "remove_slide":
+		return RemoveSlide(doc, slideId)
+
+	case "update_slide":
+		updatedProperties, _ := AsMap(action["updatedProperties"])
+		return UpdateSlide(doc, slideId, updatedProperties)
+
+	case "set_concurrency_mode":
+		doc.ConcurrencyMode, _ = action["mode"].(string)
+		return nil
+
+	case "document_reset":
+		slides, err := DecodeSlides(action["slides"])
+		if err != nil {
+			return err
+		}
+		doc.Slides = slides
+		return nil
+
+	case "batch":
+		return applyBatch(doc, action)
+
+	case "stroke_end":
+		action["action"] = "create"
+
+	case "commit_update":
+		action["action"] = "update"
+	}
+
+	prepareOp(action)
+	return Apply(doc, action)
+}
+
+// applyBatch applies the ops of a batch all or nothing: they are applied to copies of
+// the slides they touch, which replace the slides of doc once every op succeeded
+func applyBatch(doc *model.Document, action map[string]interface{}) error {
+	scratch := *doc
+	scratch.Slides = append([]model.Slide(nil), doc.Slides...)
+	cloned := map[string]bool{}
+
+	ops, _ := AsList(action["ops"])
+	for _, o := range ops {
+		op, ok := AsMap(o)
+		if !ok {
+			return fmt.Errorf("invalid op in batch")
+		}
+		slideId, _ := op["slideId"].(string)
+		if i := slideIndex(&scratch, slideId); i != -1 && !cloned[slideId] {
+			scratch.Slides[i] = cloneSlide(scratch.Slides[i])
+			cloned[slideId] = true
+		}
+
+		prepareOp(op)
+		if err := Apply(&scratch, op); err != nil {
+			return err
+		}
+	}
+
+	doc.Slides = scratch.Slides
+	return nil
+}
+
+// AddSlide inserts a blank slide at index, -1 (or an index past the end) appends it
+func AddSlide(doc *model.Document, slideId string, background string, index int) {
+	if background == "" {
+		background = DefaultSlideBackground
+	}
+	doc.Slides = insertSlide(doc.Slides, model.Slide{
+		ID:         slideId,
+		Background: background,
+		Objects:    []model.Object{},
+	}, index)
+}
+
+// RemoveSlide removes the slide from the document
+func RemoveSlide(doc *model.Document, slideId string) error {
+	i := slideIndex(doc, slideId)
+	if i == -1 {
+		return fmt.Errorf("slide %s not found", slideId)
+	}
+	doc.Slides = append(doc.Slides[:i], doc.Slides[i+1:]...)
+	return nil
+}
+
+// UpdateSlide sets slide level properties (background, title, notes, size)
+func UpdateSlide(doc *model.Document, slideId string, updatedProperties map[string]interface{}) error {
+	slide := FindSlide(doc, slideId)
+	if slide == nil {
+		return fmt.Errorf("slide %s not found", slideId)
+	}
+	for key, value := range updatedProperties {
+		SetSlideProperty(slide, key, value)
+	}
+	return nil
+}
+
+// SetSlideProperty sets one property of update_slide, unknown keys are ignored
+func SetSlideProperty(slide *model.Slide, key string, value interface{}) {
+	switch key {
+	case "background":
+		slide.Background, _ = value.(string)
+	case "backgroundImage":
+		slide.BackgroundImage, _ = value.(string)
+	case "title":
+		slide.Title, _ = value.(string)
+	case "notes":
+		slide.Notes, _ = value.(string)
+	case "width":
+		slide.Width = geometry.ToFloat(value)
+	case "height":
+		slide.Height = geometry.ToFloat(value)
+	}
+}
+
+// DecodeSlides reads the slides of a document_reset, decoded from json or from mongo
+func DecodeSlides(v interface{}) ([]model.Slide, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode slides: %w", err)
+	}
+	var slides []model.Slide
+	if err := json.Unmarshal(encoded, &slides); err != nil {
+		return nil, fmt.Errorf("failed to decode slides: %w", err)
+	}
+	if slides == nil {
+		return nil, fmt.Errorf("slides missing")
+	}
+	return slides, nil
+}
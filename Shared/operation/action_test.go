@@ -0,0 +1,67 @@
+package operation
+
+import (
+	"Shared/model"
+	"reflect"
+	"testing"
+)
+
+func TestApplyBatch(t *testing.T) {
+	move := func(slideId string, objectId string, x float64) map[string]interface{} {
+		return map[string]interface{}{"action": "update", "slideId": slideId, "objectId": objectId, "updatedAttributes": map[string]interface{}{"x": x}}
+	}
+
+	tests := []struct {
+		name    string
+		ops     []interface{}
+		wantErr bool
+		want    []float64 // x of a on s1 and of b on s2
+	}{
+		{
+			name: "every op applied",
+			ops:  []interface{}{move("s1", "a", 5), move("s2", "b", 6)},
+			want: []float64{5, 6},
+		},
+		{
+			name:    "a failing op leaves every slide unchanged",
+			ops:     []interface{}{move("s1", "a", 5), move("s2", "b", 6), move("s2", "missing", 7)},
+			wantErr: true,
+			want:    []float64{1, 2},
+		},
+		{
+			name:    "an invalid op leaves every slide unchanged",
+			ops:     []interface{}{move("s1", "a", 5), "not an op"},
+			wantErr: true,
+			want:    []float64{1, 2},
+		},
+		{
+			name: "ops on a slide moved by the batch",
+			ops:  []interface{}{map[string]interface{}{"action": "move_slide", "slideId": "s2", "index": 0.0}, move("s2", "b", 6)},
+			want: []float64{1, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &model.Document{Slides: []model.Slide{
+				{ID: "s1", Objects: []model.Object{{ID: "a", Attributes: map[string]interface{}{"x": 1.0}}}},
+				{ID: "s2", Objects: []model.Object{{ID: "b", Attributes: map[string]interface{}{"x": 2.0}}}},
+			}}
+			slides := doc.Slides
+
+			err := ApplyAction(doc, map[string]interface{}{"action": "batch", "ops": tt.ops})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error=%v", err, tt.wantErr)
+			}
+
+			a := FindSlide(doc, "s1").Objects[0].Attributes["x"]
+			b := FindSlide(doc, "s2").Objects[0].Attributes["x"]
+			if got := []float64{a.(float64), b.(float64)}; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if tt.wantErr && !reflect.DeepEqual(doc.Slides, slides) {
+				t.Errorf("slides replaced by a failed batch: %+v", doc.Slides)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package operation
+
+import (
+	"Shared/geometry"
+	"Shared/model"
+	"fmt"
+)
+
+// Frames are containers on a slide: their children are stored relative to the
+// frame's x / y so moving the frame moves its contents, and clients clip the
+// children to the frame's width / height unless the frame has "clip": false.
+
+// AddToFrame moves objects of the slide into a frame, keeping their position on the slide
+func AddToFrame(slide *model.Slide, frameId string, childIds []interface{}) error {
+	frame, err := findFrame(slide, frameId)
+	if err != nil {
+		return err
+	}
+
+	members := make(map[string]bool, len(childIds))
+	for _, rawChildId := range childIds {
+		childId, _ := rawChildId.(string)
+		if childId == frameId {
+			return fmt.Errorf("frame %s can't contain itself", frameId)
+		}
+		members[childId] = true
+	}
+
+	offsetX := geometry.ToFloat(frame.Attributes["x"])
+	offsetY := geometry.ToFloat(frame.Attributes["y"])
+
+	var moved []model.Object
+	remaining := make([]model.Object, 0, len(slide.Objects))
+	for _, obj := range slide.Objects {
+		if members[obj.ID] {
+			geometry.Translate(&obj, -offsetX, -offsetY)
+			obj.LayerID = ""
+			moved = append(moved, obj)
+			continue
+		}
+		remaining = append(remaining, obj)
+	}
+	if len(moved) != len(members) {
+		return fmt.Errorf("objects to add to frame %s must be on slide %s", frameId, slide.ID)
+	}
+
+	slide.Objects = remaining
+	frame, _ = findFrame(slide, frameId) // the slice has been rebuilt
+	frame.Children = append(frame.Children, moved...)
+	return nil
+}
+
+// RemoveFromFrame moves children of a frame back to the slide, right above the frame
+func RemoveFromFrame(slide *model.Slide, frameId string, childIds []interface{}) error {
+	frame, err := findFrame(slide, frameId)
+	if err != nil {
+		return err
+	}
+
+	members := make(map[string]bool, len(childIds))
+	for _, rawChildId := range childIds {
+		childId, _ := rawChildId.(string)
+		members[childId] = true
+	}
+
+	offsetX := geometry.ToFloat(frame.Attributes["x"])
+	offsetY := geometry.ToFloat(frame.Attributes["y"])
+
+	var moved []model.Object
+	children := make([]model.Object, 0, len(frame.Children))
+	for _, child := range frame.Children {
+		if members[child.ID] {
+			geometry.Translate(&child, offsetX, offsetY)
+			child.LayerID = frame.LayerID
+			moved = append(moved, child)
+			continue
+		}
+		children = append(children, child)
+	}
+	if len(moved) != len(members) {
+		return fmt.Errorf("objects to remove must be children of frame %s", frameId)
+	}
+	frame.Children = children
+
+	i := 0
+	for slide.Objects[i].ID != frameId {
+		i++
+	}
+	objects := make([]model.Object, 0, len(slide.Objects)+len(moved))
+	objects = append(objects, slide.Objects[:i+1]...)
+	objects = append(objects, moved...)
+	objects = append(objects, slide.Objects[i+1:]...)
+	slide.Objects = objects
+	return nil
+}
+
+// findFrame returns the frame with the given id, frames only exist directly on a slide
+func findFrame(slide *model.Slide, frameId string) (*model.Object, error) {
+	for i := range slide.Objects {
+		if slide.Objects[i].ID == frameId {
+			if slide.Objects[i].Type != "frame" {
+				return nil, fmt.Errorf("object %s is not a frame", frameId)
+			}
+			return &slide.Objects[i], nil
+		}
+	}
+	return nil, fmt.Errorf("frame %s not found", frameId)
+}
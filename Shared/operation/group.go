@@ -0,0 +1,114 @@
+package operation
+
+import (
+	"Shared/geometry"
+	"Shared/model"
+	"fmt"
+)
+
+// Group moves the objects listed in op["childIds"] into a new group object.
+// The children must share the same container (slide or group), the group takes
+// the z-position of its top-most child and keeps the children's relative order.
+func Group(slide *model.Slide, op map[string]interface{}) error {
+	groupId, _ := op["objectId"].(string)
+	rawChildIds, ok := AsList(op["childIds"])
+	if !ok || len(rawChildIds) == 0 {
+		return fmt.Errorf("childIds missing for group %s", groupId)
+	}
+
+	if _, i := FindObject(&slide.Objects, groupId); i != -1 {
+		return fmt.Errorf("object %s already exists", groupId)
+	}
+
+	members := make(map[string]bool, len(rawChildIds))
+	var container *[]model.Object
+	for _, rawChildId := range rawChildIds {
+		childId, _ := rawChildId.(string)
+		objects, i := FindObject(&slide.Objects, childId)
+		if i == -1 {
+			return fmt.Errorf("object %s not found", childId)
+		}
+		if container != nil && objects != container {
+			return fmt.Errorf("object %s is not at the same level as the other group members", childId)
+		}
+		container = objects
+		members[childId] = true
+	}
+
+	attr, ok := op["attributes"].(map[string]interface{})
+	if !ok {
+		attr = map[string]interface{}{"x": 0.0, "y": 0.0}
+	}
+	group := model.Object{
+		ID:         groupId,
+		Type:       "group",
+		Attributes: attr,
+		Children:   make([]model.Object, 0, len(members)),
+	}
+
+	// children are relative to the group offset
+	offsetX := geometry.ToFloat(attr["x"])
+	offsetY := geometry.ToFloat(attr["y"])
+
+	remaining := make([]model.Object, 0, len(*container))
+	insertAt := 0
+	for _, obj := range *container {
+		if members[obj.ID] {
+			// the group goes to the layer of its top-most child
+			group.LayerID = obj.LayerID
+			obj.LayerID = ""
+			geometry.Translate(&obj, -offsetX, -offsetY)
+			group.Children = append(group.Children, obj)
+			insertAt = len(remaining)
+			continue
+		}
+		remaining = append(remaining, obj)
+	}
+
+	remaining = append(remaining, model.Object{})
+	copy(remaining[insertAt+1:], remaining[insertAt:])
+	remaining[insertAt] = group
+	*container = remaining
+
+	return nil
+}
+
+// Ungroup replaces a group by its children, baking the group offset and transform into them
+func Ungroup(slide *model.Slide, groupId string) error {
+	container, i := FindObject(&slide.Objects, groupId)
+	if i == -1 {
+		return fmt.Errorf("object %s not found", groupId)
+	}
+
+	group := (*container)[i]
+	if group.Type != "group" {
+		return fmt.Errorf("object %s is not a group", groupId)
+	}
+
+	// the group transform pivots on the center of the group
+	transform := geometry.ReadTransform(group.Attributes)
+	bounds, hasBounds := geometry.LocalBounds(&group)
+	cx, cy := bounds.Center()
+
+	offsetX := geometry.ToFloat(group.Attributes["x"])
+	offsetY := geometry.ToFloat(group.Attributes["y"])
+	children := make([]model.Object, len(group.Children))
+	for j, child := range group.Children {
+		geometry.Translate(&child, offsetX, offsetY)
+		if container == &slide.Objects {
+			child.LayerID = group.LayerID
+		}
+		if hasBounds {
+			geometry.ApplyParentTransform(&child, transform, cx, cy)
+		}
+		children[j] = child
+	}
+
+	objects := make([]model.Object, 0, len(*container)-1+len(children))
+	objects = append(objects, (*container)[:i]...)
+	objects = append(objects, children...)
+	objects = append(objects, (*container)[i+1:]...)
+	*container = objects
+
+	return nil
+}
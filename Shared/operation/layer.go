@@ -0,0 +1,102 @@
+package operation
+
+import (
+	"Shared/model"
+	"fmt"
+)
+
+// AddLayer inserts a visible, unlocked layer at index (-1 puts it on top)
+func AddLayer(slide *model.Slide, layerId string, name string, index int) error {
+	if layerIndex(slide, layerId) != -1 {
+		return fmt.Errorf("layer %s already exists", layerId)
+	}
+
+	if index < 0 || index > len(slide.Layers) {
+		index = len(slide.Layers)
+	}
+	slide.Layers = append(slide.Layers, model.Layer{})
+	copy(slide.Layers[index+1:], slide.Layers[index:])
+	slide.Layers[index] = model.Layer{ID: layerId, Name: name, Visible: true}
+	return nil
+}
+
+// UpdateLayer changes the name, visible and locked properties of a layer
+func UpdateLayer(slide *model.Slide, layerId string, props map[string]interface{}) error {
+	i := layerIndex(slide, layerId)
+	if i == -1 {
+		return fmt.Errorf("layer %s not found", layerId)
+	}
+
+	layer := &slide.Layers[i]
+	for key, value := range props {
+		switch key {
+		case "name":
+			layer.Name, _ = value.(string)
+		case "visible":
+			layer.Visible, _ = value.(bool)
+		case "locked":
+			layer.Locked, _ = value.(bool)
+		}
+	}
+	return nil
+}
+
+// RemoveLayer deletes a layer, its objects move to the base layer
+func RemoveLayer(slide *model.Slide, layerId string) error {
+	i := layerIndex(slide, layerId)
+	if i == -1 {
+		return fmt.Errorf("layer %s not found", layerId)
+	}
+
+	slide.Layers = append(slide.Layers[:i], slide.Layers[i+1:]...)
+	for j := range slide.Objects {
+		if slide.Objects[j].LayerID == layerId {
+			slide.Objects[j].LayerID = ""
+		}
+	}
+	return nil
+}
+
+// MoveLayer moves a layer to index in the bottom to top layer order
+func MoveLayer(slide *model.Slide, layerId string, index int) error {
+	from := layerIndex(slide, layerId)
+	if from == -1 {
+		return fmt.Errorf("layer %s not found", layerId)
+	}
+
+	layer := slide.Layers[from]
+	layers := append(slide.Layers[:from], slide.Layers[from+1:]...)
+	if index < 0 || index > len(layers) {
+		index = len(layers)
+	}
+	layers = append(layers, model.Layer{})
+	copy(layers[index+1:], layers[index:])
+	layers[index] = layer
+	slide.Layers = layers
+	return nil
+}
+
+// SetLayer moves an object of the slide to a layer, "" is the base layer.
+// Only objects directly on the slide have a layer, children follow their container.
+func SetLayer(slide *model.Slide, objectId string, layerId string) error {
+	if layerId != "" && layerIndex(slide, layerId) == -1 {
+		return fmt.Errorf("layer %s not found", layerId)
+	}
+
+	for i := range slide.Objects {
+		if slide.Objects[i].ID == objectId {
+			slide.Objects[i].LayerID = layerId
+			return nil
+		}
+	}
+	return fmt.Errorf("object %s is not on slide %s", objectId, slide.ID)
+}
+
+func layerIndex(slide *model.Slide, layerId string) int {
+	for i := range slide.Layers {
+		if slide.Layers[i].ID == layerId {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,28 @@
+package operation
+
+import (
+	"Shared/model"
+	"strings"
+)
+
+// stampKey is the key of an attribute in Object.Stamps, dotted attribute paths
+// ("transform.rotation") are stored with "/" as field names can't contain dots
+func stampKey(attribute string) string {
+	return strings.ReplaceAll(attribute, ".", "/")
+}
+
+// AcceptStamp resolves a last-writer-wins update of one attribute: the write is
+// accepted (and its stamp recorded) unless the attribute already holds a newer one.
+// Stamps are ordered by string comparison, see FormatStamp in the UpdatesService.
+func AcceptStamp(obj *model.Object, attribute string, stamp string) bool {
+	key := stampKey(attribute)
+	if current, ok := obj.Stamps[key]; ok && current > stamp {
+		return false
+	}
+
+	if obj.Stamps == nil {
+		obj.Stamps = make(map[string]string)
+	}
+	obj.Stamps[key] = stamp
+	return true
+}
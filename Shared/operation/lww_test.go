@@ -0,0 +1,81 @@
+package operation
+
+import (
+	"Shared/model"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// stamp mirrors FormatStamp of the UpdatesService
+func stamp(clock int64, userId string) string {
+	return fmt.Sprintf("%020d:%s", clock, userId)
+}
+
+func TestAcceptStamp(t *testing.T) {
+	tests := []struct {
+		name      string
+		stamps    map[string]string
+		attribute string
+		stamp     string
+		want      bool
+		wantStamp string
+	}{
+		{"first write", nil, "x", stamp(1, "u1"), true, stamp(1, "u1")},
+		{"newer clock", map[string]string{"x": stamp(1, "u1")}, "x", stamp(2, "u2"), true, stamp(2, "u2")},
+		{"older clock", map[string]string{"x": stamp(5, "u1")}, "x", stamp(4, "u2"), false, stamp(5, "u1")},
+		{"same clock, user id breaks the tie", map[string]string{"x": stamp(3, "u1")}, "x", stamp(3, "u2"), true, stamp(3, "u2")},
+		{"same clock, lower user id loses", map[string]string{"x": stamp(3, "u2")}, "x", stamp(3, "u1"), false, stamp(3, "u2")},
+		{"same stamp is accepted again", map[string]string{"x": stamp(3, "u1")}, "x", stamp(3, "u1"), true, stamp(3, "u1")},
+		{"clock padding orders 10 after 9", map[string]string{"x": stamp(9, "u1")}, "x", stamp(10, "u1"), true, stamp(10, "u1")},
+		{"other attributes don't matter", map[string]string{"y": stamp(9, "u1")}, "x", stamp(1, "u1"), true, stamp(1, "u1")},
+		{"dotted paths", map[string]string{"transform/rotation": stamp(5, "u1")}, "transform.rotation", stamp(4, "u1"), false, stamp(5, "u1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &model.Object{Stamps: tt.stamps}
+			if got := AcceptStamp(obj, tt.attribute, tt.stamp); got != tt.want {
+				t.Errorf("accepted %v, want %v", got, tt.want)
+			}
+			if got := obj.Stamps[stampKey(tt.attribute)]; got != tt.wantStamp {
+				t.Errorf("stamp %q, want %q", got, tt.wantStamp)
+			}
+		})
+	}
+}
+
+func TestLWWUpdatesConverge(t *testing.T) {
+	updates := []map[string]interface{}{
+		{"stamp": stamp(1, "u1"), "updatedAttributes": map[string]interface{}{"x": 1.0, "fill": "red"}},
+		{"stamp": stamp(2, "u2"), "updatedAttributes": map[string]interface{}{"x": 2.0}},
+		{"stamp": stamp(2, "u1"), "updatedAttributes": map[string]interface{}{"x": 3.0, "transform.rotation": 45.0}},
+		{"stamp": stamp(3, "u1"), "updatedAttributes": map[string]interface{}{"fill": "blue"}},
+	}
+	want := map[string]interface{}{
+		"x":         2.0,
+		"fill":      "blue",
+		"transform": map[string]interface{}{"rotation": 45.0},
+	}
+
+	orders := [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {1, 3, 0, 2}, {2, 0, 3, 1}}
+	for _, order := range orders {
+		doc := &model.Document{Slides: []model.Slide{{
+			ID:      "s1",
+			Objects: []model.Object{{ID: "o1", Type: "rect", Attributes: map[string]interface{}{}}},
+		}}}
+		for _, i := range order {
+			op := map[string]interface{}{"action": "update", "slideId": "s1", "objectId": "o1"}
+			for key, value := range updates[i] {
+				op[key] = value
+			}
+			if err := Apply(doc, op); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := doc.Slides[0].Objects[0].Attributes; !reflect.DeepEqual(got, want) {
+			t.Errorf("order %v: got %v, want %v", order, got, want)
+		}
+	}
+}
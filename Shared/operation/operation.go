@@ -0,0 +1,237 @@
+package operation
+
+import (
+	"Shared/model"
+	"Shared/text"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Apply applies a single object op (create, update, delete, reorder, text_edit, group, ungroup,
+// add_to_frame, remove_from_frame, set_layer, restore_objects), layer op (add_layer, update_layer,
+// remove_layer, move_layer) or slide op (move_slide, duplicate_slide) to the in-memory document.
+// It is used for writes that have to be computed from the current state and
+// stored as one atomic update (see DocumentRepository.MutateDocument).
+func Apply(doc *model.Document, op map[string]interface{}) error {
+	action, _ := op["action"].(string)
+	slideId, _ := op["slideId"].(string)
+	objectId, _ := op["objectId"].(string)
+
+	// slide level ops
+	switch action {
+	case "move_slide":
+		index, _ := op["index"].(float64)
+		return MoveSlide(doc, slideId, int(index))
+	case "duplicate_slide":
+		newSlideId, _ := op["newSlideId"].(string)
+		index := -1
+		if i, ok := op["index"].(float64); ok {
+			index = int(i)
+		}
+		return DuplicateSlide(doc, slideId, newSlideId, index)
+	}
+
+	slide := FindSlide(doc, slideId)
+	if slide == nil {
+		return fmt.Errorf("slide %s not found", slideId)
+	}
+
+	switch action {
+	case "create":
+		if _, i := FindObject(&slide.Objects, objectId); i != -1 {
+			return fmt.Errorf("object %s already exists", objectId)
+		}
+		objectType, _ := op["objectType"].(string)
+		attr, ok := op["attributes"].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("attributes missing for object %s", objectId)
+		}
+		layerId, _ := op["layerId"].(string)
+		slide.Objects = append(slide.Objects, model.Object{
+			ID:         objectId,
+			Type:       objectType,
+			Attributes: attr,
+			LayerID:    layerId,
+		})
+
+	case "update":
+		objects, i := FindObject(&slide.Objects, objectId)
+		if i == -1 {
+			return fmt.Errorf("object %s not found", objectId)
+		}
+		updatedFields, ok := op["updatedAttributes"].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("updatedAttributes missing for object %s", objectId)
+		}
+		obj := &(*objects)[i]
+		if obj.Attributes == nil {
+			obj.Attributes = make(map[string]interface{})
+		}
+		// lww updates only overwrite attributes whose last write is older
+		stamp, _ := op["stamp"].(string)
+		for key, value := range updatedFields {
+			if stamp != "" && !AcceptStamp(obj, key, stamp) {
+				continue
+			}
+			SetAttribute(obj.Attributes, key, value)
+		}
+
+	case "delete":
+		objects, i := FindObject(&slide.Objects, objectId)
+		if i == -1 {
+			return fmt.Errorf("object %s not found", objectId)
+		}
+		*objects = append((*objects)[:i], (*objects)[i+1:]...)
+
+	case "reorder":
+		objects, from := FindObject(&slide.Objects, objectId)
+		if from == -1 {
+			return fmt.Errorf("object %s not found", objectId)
+		}
+		reorderOp, _ := op["op"].(string)
+		index, _ := op["index"].(float64)
+		reordered, err := Reorder(*objects, from, reorderOp, int(index))
+		if err != nil {
+			return err
+		}
+		*objects = reordered
+
+	case "text_edit":
+		objects, i := FindObject(&slide.Objects, objectId)
+		if i == -1 {
+			return fmt.Errorf("object %s not found", objectId)
+		}
+		obj := &(*objects)[i]
+		if obj.Type != "text" {
+			return fmt.Errorf("object %s is not a text", objectId)
+		}
+		rawOps, _ := AsList(op["ops"])
+		ops := make([]map[string]interface{}, 0, len(rawOps))
+		for _, rawOp := range rawOps {
+			if textOp, ok := AsMap(rawOp); ok {
+				ops = append(ops, textOp)
+			}
+		}
+		if obj.Attributes == nil {
+			obj.Attributes = make(map[string]interface{})
+		}
+		sequence := text.LoadSequence(obj.Attributes)
+		sequence.Apply(ops)
+		sequence.Store(obj.Attributes)
+
+	case "group":
+		return Group(slide, op)
+
+	case "ungroup":
+		return Ungroup(slide, objectId)
+
+	case "add_to_frame":
+		childIds, _ := AsList(op["childIds"])
+		return AddToFrame(slide, objectId, childIds)
+
+	case "remove_from_frame":
+		childIds, _ := AsList(op["childIds"])
+		return RemoveFromFrame(slide, objectId, childIds)
+
+	case "set_layer":
+		layerId, _ := op["layerId"].(string)
+		return SetLayer(slide, objectId, layerId)
+
+	case "add_layer":
+		layerId, _ := op["layerId"].(string)
+		name, _ := op["name"].(string)
+		index := -1
+		if i, ok := op["index"].(float64); ok {
+			index = int(i)
+		}
+		return AddLayer(slide, layerId, name, index)
+
+	case "update_layer":
+		layerId, _ := op["layerId"].(string)
+		props, _ := AsMap(op["updatedProperties"])
+		return UpdateLayer(slide, layerId, props)
+
+	case "remove_layer":
+		layerId, _ := op["layerId"].(string)
+		return RemoveLayer(slide, layerId)
+
+	case "move_layer":
+		layerId, _ := op["layerId"].(string)
+		index, _ := op["index"].(float64)
+		return MoveLayer(slide, layerId, int(index))
+
+	case "restore_objects":
+		return RestoreObjects(slide, op)
+
+	default:
+		return fmt.Errorf("unsupported action %q", action)
+	}
+
+	return nil
+}
+
+// FindSlide returns a pointer to the slide with the given id, or nil
+func FindSlide(doc *model.Document, slideId string) *model.Slide {
+	for i := range doc.Slides {
+		if doc.Slides[i].ID == slideId {
+			return &doc.Slides[i]
+		}
+	}
+	return nil
+}
+
+// FindObject searches objects and, recursively, the children of groups. It returns
+// the slice holding the object (so it can be modified in place) and the object's
+// index in it, or nil and -1.
+func FindObject(objects *[]model.Object, objectId string) (*[]model.Object, int) {
+	for i := range *objects {
+		if (*objects)[i].ID == objectId {
+			return objects, i
+		}
+		if len((*objects)[i].Children) > 0 {
+			if container, j := FindObject(&(*objects)[i].Children, objectId); j != -1 {
+				return container, j
+			}
+		}
+	}
+	return nil, -1
+}
+
+// SetAttribute sets attr[key], dotted keys ("transform.rotation") address nested
+// maps the same way the $set paths of DocumentRepository.UpdateElement do.
+func SetAttribute(attr map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := AsMap(attr[part])
+		if !ok {
+			next = make(map[string]interface{})
+			attr[part] = next
+		}
+		attr = next
+	}
+	attr[parts[len(parts)-1]] = value
+}
+
+// AsMap reads a nested document coming either from a json message or from mongo
+func AsMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case primitive.M:
+		return m, true
+	}
+	return nil, false
+}
+
+// AsList reads an array coming either from a json message or from mongo
+func AsList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, true
+	case primitive.A:
+		return l, true
+	}
+	return nil, false
+}
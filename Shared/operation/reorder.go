@@ -0,0 +1,49 @@
+package operation
+
+import (
+	"Shared/model"
+	"fmt"
+)
+
+// Reorder moves objects[from] according to op and returns the reordered slice.
+// Index 0 is the back of the slide, the last object is drawn on top.
+func Reorder(objects []model.Object, from int, op string, index int) ([]model.Object, error) {
+	last := len(objects) - 1
+	to := from
+
+	switch op {
+	case "bring_forward":
+		to = from + 1
+	case "send_backward":
+		to = from - 1
+	case "to_front":
+		to = last
+	case "to_back":
+		to = 0
+	case "move_to":
+		to = index
+	default:
+		return nil, fmt.Errorf("unsupported reorder op %q", op)
+	}
+
+	// moving past either end keeps the object where it is
+	if to < 0 {
+		to = 0
+	}
+	if to > last {
+		to = last
+	}
+	if to == from {
+		return objects, nil
+	}
+
+	obj := objects[from]
+	if to > from {
+		copy(objects[from:to], objects[from+1:to+1])
+	} else {
+		copy(objects[to+1:from+1], objects[to:from])
+	}
+	objects[to] = obj
+
+	return objects, nil
+}
@@ -0,0 +1,98 @@
+package operation
+
+import (
+	"Shared/model"
+	"reflect"
+	"testing"
+)
+
+func objectsWithIds(ids ...string) []model.Object {
+	objects := make([]model.Object, len(ids))
+	for i, id := range ids {
+		objects[i] = model.Object{ID: id}
+	}
+	return objects
+}
+
+func idsOf(objects []model.Object) []string {
+	ids := make([]string, len(objects))
+	for i, obj := range objects {
+		ids[i] = obj.ID
+	}
+	return ids
+}
+
+func TestReorder(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  int
+		op    string
+		index int
+		want  []string
+	}{
+		{"bring forward", 1, "bring_forward", 0, []string{"a", "c", "b", "d"}},
+		{"bring forward at the top", 3, "bring_forward", 0, []string{"a", "b", "c", "d"}},
+		{"send backward", 2, "send_backward", 0, []string{"a", "c", "b", "d"}},
+		{"send backward at the back", 0, "send_backward", 0, []string{"a", "b", "c", "d"}},
+		{"to front", 0, "to_front", 0, []string{"b", "c", "d", "a"}},
+		{"to back", 3, "to_back", 0, []string{"d", "a", "b", "c"}},
+		{"move to a lower index", 3, "move_to", 1, []string{"a", "d", "b", "c"}},
+		{"move to a higher index", 0, "move_to", 2, []string{"b", "c", "a", "d"}},
+		{"move to the same index", 2, "move_to", 2, []string{"a", "b", "c", "d"}},
+		{"move past the top is clamped", 1, "move_to", 10, []string{"a", "c", "d", "b"}},
+		{"move below the back is clamped", 2, "move_to", -3, []string{"c", "a", "b", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Reorder(objectsWithIds("a", "b", "c", "d"), tt.from, tt.op, tt.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(idsOf(got), tt.want) {
+				t.Errorf("got %v, want %v", idsOf(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestReorderSingleObject(t *testing.T) {
+	for _, op := range []string{"bring_forward", "send_backward", "to_front", "to_back", "move_to"} {
+		got, err := Reorder(objectsWithIds("a"), 0, op, 5)
+		if err != nil {
+			t.Fatalf("%s: %v", op, err)
+		}
+		if !reflect.DeepEqual(idsOf(got), []string{"a"}) {
+			t.Errorf("%s: got %v", op, idsOf(got))
+		}
+	}
+}
+
+func TestReorderUnknownOp(t *testing.T) {
+	if _, err := Reorder(objectsWithIds("a", "b"), 0, "sideways", 0); err == nil {
+		t.Error("unknown reorder op accepted")
+	}
+}
+
+func TestApplyReorderInsideGroup(t *testing.T) {
+	doc := &model.Document{Slides: []model.Slide{{
+		ID: "s1",
+		Objects: []model.Object{
+			{ID: "x"},
+			{ID: "g", Type: "group", Children: objectsWithIds("a", "b", "c")},
+		},
+	}}}
+
+	err := Apply(doc, map[string]interface{}{"action": "reorder", "slideId": "s1", "objectId": "a", "op": "to_front"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slide := doc.Slides[0]
+	if got := idsOf(slide.Objects); !reflect.DeepEqual(got, []string{"x", "g"}) {
+		t.Errorf("top level changed: %v", got)
+	}
+	if got := idsOf(slide.Objects[1].Children); !reflect.DeepEqual(got, []string{"b", "c", "a"}) {
+		t.Errorf("children: got %v, want [b c a]", got)
+	}
+}
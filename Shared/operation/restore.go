@@ -0,0 +1,80 @@
+package operation
+
+import (
+	"Shared/model"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// RestoreObjects puts objects of a slide back the way they were, it is the op sent
+// by UpdatesService to undo and redo changes. The objects listed in op["objectIds"]
+// are removed from the slide, then every entry of op["objects"] ({index, object})
+// is inserted at its index, in ascending index order.
+func RestoreObjects(slide *model.Slide, op map[string]interface{}) error {
+	rawObjectIds, _ := AsList(op["objectIds"])
+	removed := make(map[string]bool, len(rawObjectIds))
+	for _, rawObjectId := range rawObjectIds {
+		objectId, _ := rawObjectId.(string)
+		removed[objectId] = true
+	}
+
+	rawObjects, _ := AsList(op["objects"])
+	type placement struct {
+		index  int
+		object model.Object
+	}
+	placements := make([]placement, 0, len(rawObjects))
+	for _, rawObject := range rawObjects {
+		entry, ok := AsMap(rawObject)
+		if !ok {
+			return fmt.Errorf("invalid object to restore on slide %s", slide.ID)
+		}
+		obj, err := decodeObject(entry["object"])
+		if err != nil {
+			return err
+		}
+		index, _ := entry["index"].(float64)
+		placements = append(placements, placement{index: int(index), object: obj})
+	}
+	sort.SliceStable(placements, func(i, j int) bool { return placements[i].index < placements[j].index })
+
+	objects := make([]model.Object, 0, len(slide.Objects)+len(placements))
+	for _, obj := range slide.Objects {
+		if !removed[obj.ID] {
+			objects = append(objects, obj)
+		}
+	}
+
+	for _, p := range placements {
+		if _, i := FindObject(&objects, p.object.ID); i != -1 {
+			return fmt.Errorf("object %s already exists", p.object.ID)
+		}
+		index := p.index
+		if index < 0 || index > len(objects) {
+			index = len(objects)
+		}
+		objects = append(objects, model.Object{})
+		copy(objects[index+1:], objects[index:])
+		objects[index] = p.object
+	}
+
+	slide.Objects = objects
+	return nil
+}
+
+// decodeObject reads an object sent in a message
+func decodeObject(v interface{}) (model.Object, error) {
+	var obj model.Object
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return obj, fmt.Errorf("invalid object: %w", err)
+	}
+	if err := json.Unmarshal(encoded, &obj); err != nil {
+		return obj, fmt.Errorf("invalid object: %w", err)
+	}
+	if obj.ID == "" {
+		return obj, fmt.Errorf("object id missing")
+	}
+	return obj, nil
+}
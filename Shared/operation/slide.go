@@ -0,0 +1,156 @@
+package operation
+
+import (
+	"Shared/model"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+)
+
+// MoveSlide moves a slide to index (clamped to the slide list)
+func MoveSlide(doc *model.Document, slideId string, index int) error {
+	from := slideIndex(doc, slideId)
+	if from == -1 {
+		return fmt.Errorf("slide %s not found", slideId)
+	}
+
+	slide := doc.Slides[from]
+	slides := append(doc.Slides[:from:from], doc.Slides[from+1:]...)
+	doc.Slides = insertSlide(slides, slide, index)
+	return nil
+}
+
+// DuplicateSlide inserts a deep copy of slideId at index (-1 means right after the
+// source). Every copied object gets the id DerivedObjectID(newSlideId, oldId).
+func DuplicateSlide(doc *model.Document, slideId string, newSlideId string, index int) error {
+	from := slideIndex(doc, slideId)
+	if from == -1 {
+		return fmt.Errorf("slide %s not found", slideId)
+	}
+	if slideIndex(doc, newSlideId) != -1 {
+		return fmt.Errorf("slide %s already exists", newSlideId)
+	}
+
+	source := doc.Slides[from]
+	copied := source
+	copied.ID = newSlideId
+	copied.Objects = copyObjects(source.Objects, newSlideId)
+	copied.Layers = append([]model.Layer(nil), source.Layers...)
+
+	if index == -1 {
+		index = from + 1
+	}
+	doc.Slides = insertSlide(doc.Slides, copied, index)
+	return nil
+}
+
+// DerivedObjectID returns the id of the copy of objectId made for the slide newSlideId.
+// UpdatesService clients compute the same value: first 24 hex chars of sha1(newSlideId:objectId)
+func DerivedObjectID(newSlideId string, objectId string) string {
+	sum := sha1.Sum([]byte(newSlideId + ":" + objectId))
+	return hex.EncodeToString(sum[:12])
+}
+
+// copyObjects deep copies objects with their derived ids, the other fields (layer,
+// lww stamps) are kept
+func copyObjects(objects []model.Object, newSlideId string) []model.Object {
+	copies := make([]model.Object, len(objects))
+	for i, obj := range objects {
+		copies[i] = obj
+		copies[i].ID = DerivedObjectID(newSlideId, obj.ID)
+		copies[i].Attributes = CopyAttributes(obj.Attributes)
+		copies[i].Children = nil
+		if len(obj.Children) > 0 {
+			copies[i].Children = copyObjects(obj.Children, newSlideId)
+		}
+		if obj.Stamps != nil {
+			copies[i].Stamps = make(map[string]string, len(obj.Stamps))
+			for key, stamp := range obj.Stamps {
+				copies[i].Stamps[key] = stamp
+			}
+		}
+
+		// connectors of the copy stay bound to the copies of their targets
+		for _, key := range []string{"startBinding", "endBinding"} {
+			if binding, ok := AsMap(copies[i].Attributes[key]); ok {
+				if targetId, ok := binding["objectId"].(string); ok {
+					binding["objectId"] = DerivedObjectID(newSlideId, targetId)
+				}
+			}
+		}
+	}
+	return copies
+}
+
+// cloneSlide deep copies a slide, unlike DuplicateSlide the objects keep their ids
+func cloneSlide(slide model.Slide) model.Slide {
+	cloned := slide
+	cloned.Objects = cloneObjects(slide.Objects)
+	cloned.Layers = append([]model.Layer(nil), slide.Layers...)
+	return cloned
+}
+
+func cloneObjects(objects []model.Object) []model.Object {
+	if objects == nil {
+		return nil
+	}
+	clones := make([]model.Object, len(objects))
+	for i, obj := range objects {
+		clones[i] = obj
+		clones[i].Attributes = CopyAttributes(obj.Attributes)
+		clones[i].Children = cloneObjects(obj.Children)
+		if obj.Stamps != nil {
+			clones[i].Stamps = make(map[string]string, len(obj.Stamps))
+			for key, stamp := range obj.Stamps {
+				clones[i].Stamps[key] = stamp
+			}
+		}
+	}
+	return clones
+}
+
+// CopyAttributes deep copies nested maps and lists of an attributes map
+func CopyAttributes(attr map[string]interface{}) map[string]interface{} {
+	if attr == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(attr))
+	for k, v := range attr {
+		copied[k] = copyValue(v)
+	}
+	return copied
+}
+
+func copyValue(v interface{}) interface{} {
+	if m, ok := AsMap(v); ok {
+		return CopyAttributes(m)
+	}
+	if l, ok := AsList(v); ok {
+		copied := make([]interface{}, len(l))
+		for i, item := range l {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	}
+	return v
+}
+
+func slideIndex(doc *model.Document, slideId string) int {
+	for i := range doc.Slides {
+		if doc.Slides[i].ID == slideId {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertSlide inserts slide at index, indexes past the end append
+func insertSlide(slides []model.Slide, slide model.Slide, index int) []model.Slide {
+	if index < 0 || index > len(slides) {
+		index = len(slides)
+	}
+	slides = append(slides, model.Slide{})
+	copy(slides[index+1:], slides[index:])
+	slides[index] = slide
+	return slides
+}
@@ -0,0 +1,50 @@
+package operation
+
+import (
+	"Shared/model"
+	"testing"
+)
+
+func TestDuplicateSlide(t *testing.T) {
+	doc := &model.Document{Slides: []model.Slide{{
+		ID:     "s1",
+		Layers: []model.Layer{{ID: "l1", Name: "Ink", Visible: true}},
+		Objects: []model.Object{
+			{ID: "a", Type: "rect", LayerID: "l1", Attributes: map[string]interface{}{"x": 1.0}, Stamps: map[string]string{"x": "1-u"}},
+			{ID: "g", Type: "group", Children: []model.Object{{ID: "b", Type: "rect", Attributes: map[string]interface{}{}}}},
+			{ID: "c", Type: "connector", Attributes: map[string]interface{}{"startBinding": map[string]interface{}{"objectId": "a"}}},
+		},
+	}}}
+
+	if err := DuplicateSlide(doc, "s1", "s2", -1); err != nil {
+		t.Fatal(err)
+	}
+	source, copied := doc.Slides[0], doc.Slides[1]
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"slide id", copied.ID, "s2"},
+		{"object id", copied.Objects[0].ID, DerivedObjectID("s2", "a")},
+		{"layer id", copied.Objects[0].LayerID, "l1"},
+		{"stamps", copied.Objects[0].Stamps["x"], "1-u"},
+		{"child id", copied.Objects[1].Children[0].ID, DerivedObjectID("s2", "b")},
+		{"binding", copied.Objects[2].Attributes["startBinding"].(map[string]interface{})["objectId"], DerivedObjectID("s2", "a")},
+		{"source binding", source.Objects[2].Attributes["startBinding"].(map[string]interface{})["objectId"], "a"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// the copy doesn't share layers, attributes or stamps with its source
+	copied.Layers[0].Name = "Changed"
+	copied.Objects[0].Attributes["x"] = 2.0
+	copied.Objects[0].Stamps["x"] = "2-u"
+	if source.Layers[0].Name != "Ink" || source.Objects[0].Attributes["x"] != 1.0 || source.Objects[0].Stamps["x"] != "1-u" {
+		t.Errorf("source changed with its copy: %+v", source)
+	}
+}
@@ -0,0 +1,214 @@
+package text
+
+import (
+	"strconv"
+	"strings"
+)
+
+// initSite is the site of the characters seeded from a plain value that was
+// written before the text had a CRDT, clients seed the same ids ("1@init", ...)
+const initSite = "init"
+
+// Element is one character of the sequence, deleted characters stay as tombstones
+// because concurrent inserts may still reference them
+type Element struct {
+	ID      string
+	Counter int64
+	Site    string
+	Value   string
+	Deleted bool
+}
+
+// Sequence is the RGA (replicated growable array) state of a text object's value.
+// It is stored in the object attributes: "crdt" holds the elements, "crdtPending"
+// the ops that reference characters not received yet and "value" the visible text.
+type Sequence struct {
+	Elements []Element
+	Pending  []map[string]interface{}
+	index    map[string]int
+}
+
+// LoadSequence reads the sequence from the attributes, seeding it from value if needed
+func LoadSequence(attr map[string]interface{}) *Sequence {
+	s := &Sequence{}
+
+	if stored, ok := asList(attr["crdt"]); ok {
+		for _, raw := range stored {
+			e, ok := asMap(raw)
+			if !ok {
+				continue
+			}
+			id, _ := e["id"].(string)
+			counter, site, ok := ParseCharID(id)
+			if !ok {
+				continue
+			}
+			value, _ := e["v"].(string)
+			deleted, _ := e["d"].(bool)
+			s.Elements = append(s.Elements, Element{ID: id, Counter: counter, Site: site, Value: value, Deleted: deleted})
+		}
+	} else if value, ok := attr["value"].(string); ok {
+		i := int64(0)
+		for _, r := range value {
+			i++
+			id := strconv.FormatInt(i, 10) + "@" + initSite
+			s.Elements = append(s.Elements, Element{ID: id, Counter: i, Site: initSite, Value: string(r)})
+		}
+	}
+
+	if pending, ok := asList(attr["crdtPending"]); ok {
+		for _, raw := range pending {
+			if op, ok := asMap(raw); ok {
+				s.Pending = append(s.Pending, op)
+			}
+		}
+	}
+
+	s.reindex()
+	return s
+}
+
+// Store writes the sequence back and compacts it into the plain value
+func (s *Sequence) Store(attr map[string]interface{}) {
+	elements := make([]interface{}, len(s.Elements))
+	for i, e := range s.Elements {
+		stored := map[string]interface{}{"id": e.ID, "v": e.Value}
+		if e.Deleted {
+			stored["d"] = true
+		}
+		elements[i] = stored
+	}
+	attr["crdt"] = elements
+
+	if len(s.Pending) > 0 {
+		pending := make([]interface{}, len(s.Pending))
+		for i, op := range s.Pending {
+			pending[i] = op
+		}
+		attr["crdtPending"] = pending
+	} else {
+		delete(attr, "crdtPending")
+	}
+
+	attr["value"] = s.Text()
+}
+
+// Text returns the visible characters
+func (s *Sequence) Text() string {
+	var sb strings.Builder
+	for _, e := range s.Elements {
+		if !e.Deleted {
+			sb.WriteString(e.Value)
+		}
+	}
+	return sb.String()
+}
+
+// Apply integrates insert / delete ops. Ops referencing characters that are not
+// known yet (delivered out of order) are kept pending and retried with later ops.
+func (s *Sequence) Apply(ops []map[string]interface{}) {
+	queue := append(s.Pending, ops...)
+	s.Pending = nil
+
+	for {
+		var waiting []map[string]interface{}
+		for _, op := range queue {
+			if !s.applyOp(op) {
+				waiting = append(waiting, op)
+			}
+		}
+		if len(waiting) == 0 || len(waiting) == len(queue) {
+			s.Pending = waiting
+			return
+		}
+		queue = waiting
+	}
+}
+
+// applyOp returns false when the op has to wait for a missing character
+func (s *Sequence) applyOp(op map[string]interface{}) bool {
+	id, _ := op["id"].(string)
+	counter, site, ok := ParseCharID(id)
+	if !ok {
+		return true // malformed, drop it
+	}
+
+	switch op["type"] {
+	case "insert":
+		after, _ := op["after"].(string)
+		value, _ := op["value"].(string)
+		for _, r := range value {
+			charId := strconv.FormatInt(counter, 10) + "@" + site
+			if !s.insert(Element{ID: charId, Counter: counter, Site: site, Value: string(r)}, after) {
+				return false
+			}
+			after = charId
+			counter++
+		}
+		return true
+
+	case "delete":
+		i, ok := s.index[id]
+		if !ok {
+			return false
+		}
+		s.Elements[i].Deleted = true
+		return true
+	}
+
+	return true
+}
+
+// insert places e after the element with id after following the RGA rule:
+// concurrent inserts at the same place are ordered by descending id
+func (s *Sequence) insert(e Element, after string) bool {
+	if _, ok := s.index[e.ID]; ok {
+		return true // already integrated (redelivered message)
+	}
+
+	pos := 0
+	if after != "" {
+		i, ok := s.index[after]
+		if !ok {
+			return false
+		}
+		pos = i + 1
+	}
+
+	for pos < len(s.Elements) && greater(s.Elements[pos], e) {
+		pos++
+	}
+
+	s.Elements = append(s.Elements, Element{})
+	copy(s.Elements[pos+1:], s.Elements[pos:])
+	s.Elements[pos] = e
+	s.reindex()
+	return true
+}
+
+func (s *Sequence) reindex() {
+	s.index = make(map[string]int, len(s.Elements))
+	for i, e := range s.Elements {
+		s.index[e.ID] = i
+	}
+}
+
+func greater(a, b Element) bool {
+	if a.Counter != b.Counter {
+		return a.Counter > b.Counter
+	}
+	return a.Site > b.Site
+}
+
+// ParseCharID splits "<counter>@<site>"
+func ParseCharID(id string) (int64, string, bool) {
+	counter, site, ok := strings.Cut(id, "@")
+	if !ok || site == "" {
+		return 0, "", false
+	}
+	n, err := strconv.ParseInt(counter, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, "", false
+	}
+	return n, site, true
+}
@@ -0,0 +1,131 @@
+package text
+
+import (
+	"fmt"
+	"testing"
+)
+
+func insertOp(id string, after string, value string) map[string]interface{} {
+	return map[string]interface{}{"type": "insert", "id": id, "after": after, "value": value}
+}
+
+func deleteOp(id string) map[string]interface{} {
+	return map[string]interface{}{"type": "delete", "id": id}
+}
+
+// permutations returns every order of ops
+func permutations(ops []map[string]interface{}) [][]map[string]interface{} {
+	if len(ops) <= 1 {
+		return [][]map[string]interface{}{ops}
+	}
+	var all [][]map[string]interface{}
+	for i := range ops {
+		rest := make([]map[string]interface{}, 0, len(ops)-1)
+		rest = append(rest, ops[:i]...)
+		rest = append(rest, ops[i+1:]...)
+		for _, p := range permutations(rest) {
+			all = append(all, append([]map[string]interface{}{ops[i]}, p...))
+		}
+	}
+	return all
+}
+
+func TestSequenceConverges(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string // seeded as 1@init, 2@init, ...
+		ops   []map[string]interface{}
+		want  string
+	}{
+		{
+			name:  "concurrent inserts at the same place",
+			value: "ab",
+			ops:   []map[string]interface{}{insertOp("3@A", "1@init", "X"), insertOp("3@B", "1@init", "Y")},
+			want:  "aYXb",
+		},
+		{
+			name:  "concurrent inserts with a follow-up and a delete",
+			value: "ab",
+			ops: []map[string]interface{}{
+				insertOp("3@A", "1@init", "X"),
+				insertOp("3@B", "1@init", "Y"),
+				insertOp("4@B", "3@B", "Z"),
+				deleteOp("2@init"),
+			},
+			want: "aYZX",
+		},
+		{
+			name:  "insert at the start",
+			value: "ab",
+			ops:   []map[string]interface{}{insertOp("3@A", "", "X"), insertOp("3@B", "", "Y")},
+			want:  "YXab",
+		},
+		{
+			name:  "multi character insert and delete of an inserted character",
+			value: "",
+			ops:   []map[string]interface{}{insertOp("1@A", "", "hello"), deleteOp("2@A"), insertOp("6@B", "5@A", "!")},
+			want:  "hllo!",
+		},
+		{
+			name:  "concurrent deletes of the same character",
+			value: "abc",
+			ops:   []map[string]interface{}{deleteOp("2@init"), deleteOp("2@init"), insertOp("4@A", "2@init", "X")},
+			want:  "aXc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, order := range permutations(tt.ops) {
+				// one op per message, like clients send them
+				s := LoadSequence(map[string]interface{}{"value": tt.value})
+				for _, op := range order {
+					s.Apply([]map[string]interface{}{op})
+				}
+				if got := s.Text(); got != tt.want || len(s.Pending) != 0 {
+					t.Fatalf("order %v: got %q with %d pending, want %q", ids(order), got, len(s.Pending), tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSequencePendingSurvivesStore(t *testing.T) {
+	attr := map[string]interface{}{"value": "a"}
+
+	// the follow-up arrives first and waits for the insert it references
+	s := LoadSequence(attr)
+	s.Apply([]map[string]interface{}{insertOp("3@B", "2@B", "c")})
+	s.Store(attr)
+	if attr["value"] != "a" || attr["crdtPending"] == nil {
+		t.Fatalf("stored %v", attr)
+	}
+
+	s = LoadSequence(attr)
+	s.Apply([]map[string]interface{}{insertOp("2@B", "1@init", "b")})
+	s.Store(attr)
+	if attr["value"] != "abc" {
+		t.Errorf("got %q, want %q", attr["value"], "abc")
+	}
+	if _, ok := attr["crdtPending"]; ok {
+		t.Errorf("pending ops left: %v", attr["crdtPending"])
+	}
+}
+
+func TestSequenceRedelivery(t *testing.T) {
+	s := LoadSequence(map[string]interface{}{"value": "a"})
+	op := insertOp("2@A", "1@init", "b")
+	s.Apply([]map[string]interface{}{op, op})
+	s.Apply([]map[string]interface{}{op})
+	if got := s.Text(); got != "ab" {
+		t.Errorf("got %q, want %q", got, "ab")
+	}
+}
+
+func ids(ops []map[string]interface{}) []string {
+	out := make([]string, len(ops))
+	for i, op := range ops {
+		out[i] = fmt.Sprintf("%s %s", op["type"], op["id"])
+	}
+	return out
+}
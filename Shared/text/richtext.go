@@ -0,0 +1,64 @@
+package text
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PlainText extracts the text of a richText attribute ({paragraphs: [{spans: [{text}]}]}),
+// one line per paragraph. It is stored as the text object's value so search and
+// export keep working on plain strings.
+func PlainText(richText interface{}) (string, bool) {
+	root, ok := asMap(richText)
+	if !ok {
+		return "", false
+	}
+	paragraphs, ok := asList(root["paragraphs"])
+	if !ok {
+		return "", false
+	}
+
+	var sb strings.Builder
+	for i, rawParagraph := range paragraphs {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		paragraph, ok := asMap(rawParagraph)
+		if !ok {
+			continue
+		}
+		spans, _ := asList(paragraph["spans"])
+		for _, rawSpan := range spans {
+			span, ok := asMap(rawSpan)
+			if !ok {
+				continue
+			}
+			if t, ok := span["text"].(string); ok {
+				sb.WriteString(t)
+			}
+		}
+	}
+
+	return sb.String(), true
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case primitive.M:
+		return m, true
+	}
+	return nil, false
+}
+
+func asList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, true
+	case primitive.A:
+		return l, true
+	}
+	return nil, false
+}
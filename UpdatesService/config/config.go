@@ -0,0 +1,15 @@
+package config
+
+type MongoConfigStruct struct {
+	MongoUri                      string
+	DatabaseName                  string
+	DocumentCollectionName        string
+	SharedDocRecordCollectionName string
+}
+
+var MongoConfig = MongoConfigStruct{
+	MongoUri:                      "mongodb://canvas-live-mongodb:27017",
+	DatabaseName:                  "default",
+	DocumentCollectionName:        "document",
+	SharedDocRecordCollectionName: "shared",
+}
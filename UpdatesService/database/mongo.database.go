@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func ConnectDB(uri string) *mongo.Client {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Parse the URI and setup client options
+	// Nested documents are decoded as maps so object attributes can be edited in memory
+	clientOptions := options.Client().ApplyURI(uri).SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true})
+
+	// connect
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB: ", err)
+	}
+
+	// ping the database to verify the connection
+	if err = client.Ping(ctx, nil); err != nil {
+		log.Fatal("Failed to ping MongoDB: ", err)
+	}
+
+	fmt.Println("Successfully connected to MongoDB!")
+	return client
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"UpdatesService/types"
+	"UpdatesService/websocket"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// flushTimeout is how long a flush waits for the pending ops of the document
+const flushTimeout = 5 * time.Second
+
+// DocumentFlushHandler answers once the ops pushed for the document are persisted,
+// with the version of its room then. The database has the document as it is in the
+// room, a reset computed from it passes that version as its base (see
+// DocumentResetHandler). Internal route.
+func DocumentFlushHandler(pool *websocket.Pool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := websocket.FlushRequest{DocumentID: c.Param("docId"), Done: make(chan uint64, 1)}
+		pool.Flushes <- request
+
+		select {
+		case version := <-request.Done:
+			c.JSON(http.StatusOK, types.DocumentFlushDto{Version: version})
+		case <-time.After(flushTimeout):
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "Changes of the document haven't been saved yet"})
+		case <-c.Request.Context().Done():
+		}
+	}
+}
+
+// DocumentResetHandler replaces the slides of a document with the ones of a restored
+// version. The reset goes through the pool like any op: it is applied to the open
+// document, broadcast to the whole room and pushed to kafka so it is persisted after
+// the ops which came before it. It is refused (409) when the room applied actions
+// since the base version, they would be lost. Internal route, served on the internal
+// listener (see main) which is not published.
+func DocumentResetHandler(pool *websocket.Pool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docId := c.Param("docId")
+
+		var data types.DocumentResetPostData
+		if err := c.ShouldBindJSON(&data); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid data format or missing fields"})
+			return
+		}
+
+		body, err := json.Marshal(types.DocumentResetMessage{
+			Action:     "document_reset",
+			VersionID:  data.VersionID,
+			RestoredBy: data.UserID,
+			Slides:     data.Slides,
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error encoding reset"})
+			return
+		}
+
+		// no sender, every client of the room receives the reset
+		request := websocket.ResetRequest{
+			Message: types.KafkaInterMessage{
+				Topic: "document-updates",
+				Message: types.Message{
+					DocumentID: docId,
+					Type:       1,
+					Body:       string(body),
+					OpID:       websocket.NewOpID(),
+				},
+			},
+			BaseVersion: data.BaseVersion,
+			Done:        make(chan error, 1),
+		}
+		pool.Resets <- request
+		if err := <-request.Done; err != nil {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		// a closed document is loaded from the database once the reset is persisted
+		pool.Documents.ForgetCheckpoint(c.Request.Context(), docId)
+
+		c.String(http.StatusOK, "Success")
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"UpdatesService/types"
+	"UpdatesService/websocket"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	documentServiceURL = "http://canvas-live-document-service:8082/document"
+)
+
+// fetchPlayback reads the state and ops of the range from DocumentService, which
+// checks that the user may read the document. The returned status is DocumentService's.
+func fetchPlayback(userId string, docId string, query url.Values) (*types.PlaybackData, int, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/id/%s/playback/ops?%s", documentServiceURL, url.PathEscape(docId), query.Encode()), nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to create playback request: %w", err)
+	}
+	req.Header.Set("X-User-ID", userId)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to reach document service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var data types.PlaybackData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to decode playback: %w", err)
+	}
+	return &data, http.StatusOK, nil
+}
+
+// PlaybackHandler opens a read-only playback of the document between the from and
+// to query timestamps (RFC 3339), at speed (1 by default)
+func PlaybackHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		docId := c.Param("docId")
+		jwtToken := c.Param("token")
+
+		userInfo, err := authenticateToken(jwtToken)
+		if err != nil || userInfo.UserID == "" {
+			fmt.Printf("[PlaybackHandler][Error] %v", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization failed"})
+			return
+		}
+
+		from, err := time.Parse(time.RFC3339Nano, c.Query("from"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing from"})
+			return
+		}
+		speed := 1.0
+		if raw := c.Query("speed"); raw != "" {
+			speed, err = strconv.ParseFloat(raw, 64)
+			if err != nil || !(speed > 0 && speed <= types.MaxPlaybackSpeed) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid speed"})
+				return
+			}
+		}
+
+		// the player times the ops itself, DocumentService only needs the range
+		query := url.Values{"from": {c.Query("from")}, "to": {c.Query("to")}}
+		data, status, err := fetchPlayback(userInfo.UserID, docId, query)
+		if err != nil {
+			fmt.Printf("[PlaybackHandler][Error] %v", err)
+			c.AbortWithStatusJSON(status, gin.H{"error": "Playback unavailable"})
+			return
+		}
+
+		conn, err := websocket.Upgrade(c.Writer, c.Request)
+		if err != nil {
+			log.Printf("WebSocket Upgrade Failed: %v", err)
+			return
+		}
+
+		player := &websocket.Player{Conn: conn, From: from, Speed: speed, Data: *data}
+		player.Play()
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"UpdatesService/redis"
+	"UpdatesService/repository"
 	"UpdatesService/websocket"
 	"fmt"
 	"io"
@@ -80,7 +81,7 @@ func authenticateToken(token string) (*UserInfo, error) {
 	}, nil
 }
 
-func WsHandler(pool *websocket.Pool, redis_client *redis.RedisClient) gin.HandlerFunc {
+func WsHandler(pool *websocket.Pool, redis_client *redis.RedisClient, documents *repository.DocumentRepository) gin.HandlerFunc {
 	// Return a Gin handler function
 	return func(c *gin.Context) {
 		docId := c.Param("docId")
@@ -105,24 +106,35 @@ func WsHandler(pool *websocket.Pool, redis_client *redis.RedisClient) gin.Handle
 			return
 		}
 
-		// 2. Perform WebSocket Upgrade (Using c.Writer and c.Request)
+		// 2. Load the document into memory (only the first client of the document reads it)
+		if _, err := pool.Documents.Acquire(c.Request.Context(), docId); err != nil {
+			fmt.Printf("[WsHandler][Error] %v", err)
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+
+		// 3. Perform WebSocket Upgrade (Using c.Writer and c.Request)
 		conn, err := websocket.Upgrade(c.Writer, c.Request)
 		if err != nil {
+			pool.Documents.Release(docId)
 			// Log error after upgrade attempt, as headers may already be sent
 			log.Printf("WebSocket Upgrade Failed: %v", err)
 			// Note: Since upgrade failed, you cannot use c.JSON here
 			return
 		}
 
-		// 3. Initialize and Register Client
+		// 4. Initialize and Register Client
 		client := &websocket.Client{
 			UserID:      userId,
 			Username:    username,
+			SessionID:   websocket.NewSessionID(),
 			DocumentID:  docId, // Ensure this is correctly retrieved or set
 			Conn:        conn,
 			Pool:        pool,
 			Send:        make(chan []byte),
 			RedisClient: redis_client,
+			Documents:   documents,
+			Strokes:     make(map[string]*websocket.Stroke),
 		}
 
 		fmt.Println("[WsHandler] client reader running!")
@@ -11,10 +11,13 @@ const (
 	Topic       = "document-updates"
 )
 
-func ProduceMessage(p *kafka.Producer, topic string, message []byte) error {
+// ProduceMessage delivers message to topic, messages sharing a key (the document id)
+// land on the same partition so the consumer receives them in order
+func ProduceMessage(p *kafka.Producer, topic string, key []byte, message []byte) error {
 
 	kafkaMessage := &kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            key,
 		Value:          message,
 	}
 
@@ -0,0 +1,70 @@
+package kafkaUtils
+
+import (
+	"UpdatesService/types"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// ResultsTopic receives what the consumer did with every op
+const ResultsTopic = "document-results"
+
+// ConnectResultsConsumer subscribes to the results topic. Every instance of the service
+// uses its own group since each one relays the results to the rooms it holds, results
+// produced while it was down concern clients which are gone so it starts from the latest.
+func ConnectResultsConsumer(brokers string) (*kafka.Consumer, error) {
+	hostname, _ := os.Hostname()
+
+	var consumer *kafka.Consumer
+	var err error
+
+	maxRetries := 30
+	retryInterval := 5 * time.Second
+
+	for i := 0; i < maxRetries; i++ {
+		fmt.Printf("Attempting to connect Consumer to Kafka (Attempt %d/%d)...\n", i+1, maxRetries)
+
+		consumer, err = kafka.NewConsumer(&kafka.ConfigMap{
+			"bootstrap.servers": brokers,
+			"group.id":          "updates-service-results-" + hostname,
+			"auto.offset.reset": "latest",
+		})
+
+		if err == nil {
+			err = consumer.SubscribeTopics([]string{ResultsTopic}, nil)
+			if err == nil {
+				fmt.Println("Successfully connected Consumer to Kafka!")
+				return consumer, nil
+			}
+			consumer.Close()
+		}
+
+		fmt.Printf("Failed to connect Consumer: %v. Retrying in %v...\n", err, retryInterval)
+		time.Sleep(retryInterval)
+	}
+
+	return nil, fmt.Errorf("failed to connect consumer after %d attempts: %w", maxRetries, err)
+}
+
+// ConsumeResults reads the results topic forever and hands every result to out
+func ConsumeResults(consumer *kafka.Consumer, out chan<- types.ResultMessage) {
+	for {
+		msg, err := consumer.ReadMessage(-1)
+		if err != nil {
+			fmt.Printf("[ConsumeResults] Consumer error: %v\n", err)
+			continue
+		}
+
+		var result types.ResultMessage
+		if err := json.Unmarshal(msg.Value, &result); err != nil {
+			fmt.Printf("[ConsumeResults] Error unmarshalling result: %v\n", err)
+			continue
+		}
+
+		out <- result
+	}
+}
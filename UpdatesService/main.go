@@ -1,10 +1,15 @@
 package main
 
 import (
+	"UpdatesService/config"
+	"UpdatesService/database"
 	"UpdatesService/handler"
 	"UpdatesService/kafkaUtils"
 	"UpdatesService/redis"
+	"UpdatesService/repository"
+	"UpdatesService/room"
 	"UpdatesService/websocket"
+	"context"
 	"fmt"
 	"net/http"
 	"time"
@@ -60,17 +65,54 @@ func main() {
 	// Redis Setup
 	redis_client := redis.NewRedisClient("canvas-live-redis:6379")
 
+	// MongoDB Setup, documents are loaded into memory when their first client joins
+	// (from their checkpoint when they have one)
+	client := database.ConnectDB(config.MongoConfig.MongoUri)
+	defer client.Disconnect(context.Background())
+
+	documentRepository := repository.NewDocumentRepository(
+		client,
+		config.MongoConfig.DatabaseName,
+		config.MongoConfig.DocumentCollectionName,
+		config.MongoConfig.SharedDocRecordCollectionName,
+	)
+
 	// Websocket pool
-	pool := websocket.NewPool(p)
+	// rooms are checkpointed to redis, a restart doesn't lose the ops still in kafka
+	documents := room.NewManager(documentRepository, redis_client)
+	go documents.RunCheckpoints(room.CheckpointInterval)
+
+	pool := websocket.NewPool(p, documents)
 	go pool.Start()
 
+	// Results of the ops, failed ones are reverted in the rooms
+	resultsConsumer, err := kafkaUtils.ConnectResultsConsumer(kafkaUtils.KafkaBroker)
+	if err != nil {
+		fmt.Printf("Failed to create results consumer: %s\n", err)
+		return
+	}
+	defer resultsConsumer.Close()
+	go kafkaUtils.ConsumeResults(resultsConsumer, pool.Results)
+
 	// Server setup
 	router := gin.Default()
 	router.GET("/", func(c *gin.Context) {
 		c.String(http.StatusOK, "Server running.")
 	})
 
-	router.GET("/updates/ws/docId/:docId/token/:token", handler.WsHandler(pool, redis_client))
+	router.GET("/updates/ws/docId/:docId/token/:token", handler.WsHandler(pool, redis_client, documentRepository))
+	router.GET("/updates/ws/playback/docId/:docId/token/:token", handler.PlaybackHandler())
+
+	// Internal api, called by DocumentService when a version is restored. It has its own
+	// listener which docker-compose doesn't publish, only the other services reach it.
+	internalRouter := gin.Default()
+	internalRouter.POST("/internal/document/:docId/flush", handler.DocumentFlushHandler(pool))
+	internalRouter.POST("/internal/document/:docId/reset", handler.DocumentResetHandler(pool))
+	go func() {
+		if err := internalRouter.Run(":8084"); err != nil {
+			fmt.Printf("Internal api stopped: %s\n", err)
+		}
+	}()
 
 	router.Run(":8083")
 }
@@ -0,0 +1,16 @@
+package model
+
+import shared "Shared/model"
+
+// The document model is shared by every service, see Shared/model
+type (
+	Document = shared.Document
+	Slide    = shared.Slide
+	Layer    = shared.Layer
+	Object   = shared.Object
+)
+
+const (
+	DocumentKindSlides     = shared.DocumentKindSlides
+	DocumentKindWhiteboard = shared.DocumentKindWhiteboard
+)
@@ -69,3 +69,160 @@ func (r *RedisClient) ReleaseLock(ctx context.Context, objectId string) (bool, e
 
 	return count > 0, err
 }
+
+// AcquireLock takes the exclusive lock on an object, or refreshes it when the
+// lock is already held by the same user. Unlike SetExclusiveLock it can be
+// called repeatedly by the lock holder (e.g. for every frame of a drag).
+// The returned bool is true when the lock was free and has just been taken.
+func (r *RedisClient) AcquireLock(ctx context.Context, objectId string, userId string, duration time.Duration) (bool, error) {
+	ok, err := r.Client.SetNX(ctx, objectId, userId, duration).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis SETNX failed: %w", err)
+	}
+
+	if ok {
+		return true, nil // Lock acquired successfully
+	}
+
+	// The key exists, check who owns it
+	owner, err := r.Client.Get(ctx, objectId).Result()
+	if err == redis.Nil {
+		// Lock expired between SETNX and GET, try once more
+		if err := r.SetExclusiveLock(ctx, objectId, userId, duration); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("redis GET failed: %w", err)
+	}
+
+	if owner != userId {
+		return false, fmt.Errorf("element %s is already locked by another user", objectId)
+	}
+
+	// Same user, extend the lock
+	if err := r.Client.Expire(ctx, objectId, duration).Err(); err != nil {
+		return false, fmt.Errorf("redis EXPIRE failed: %w", err)
+	}
+
+	return false, nil
+}
+
+// ============================ LWW stamps ============================
+
+func attributeStampsKey(objectId string) string {
+	return "stamps:" + objectId
+}
+
+// mergeStampsScript keeps, per attribute, the greatest stamp seen so far.
+// KEYS[1] stamps hash, ARGV[1] stamp of the update, ARGV[2..] attribute names.
+// It returns the attributes for which the update wins.
+var mergeStampsScript = redis.NewScript(`
+local accepted = {}
+for i = 2, #ARGV do
+	local current = redis.call('HGET', KEYS[1], ARGV[i])
+	if (not current) or current <= ARGV[1] then
+		redis.call('HSET', KEYS[1], ARGV[i], ARGV[1])
+		table.insert(accepted, ARGV[i])
+	end
+end
+return accepted
+`)
+
+// MergeAttributeStamps records stamp for the given attributes of objectId and returns
+// the attributes whose current stamp is not newer, i.e. the part of the update that wins
+func (r *RedisClient) MergeAttributeStamps(ctx context.Context, objectId string, stamp string, attributes []string) ([]string, error) {
+	args := make([]interface{}, 0, len(attributes)+1)
+	args = append(args, stamp)
+	for _, attribute := range attributes {
+		args = append(args, attribute)
+	}
+
+	accepted, err := mergeStampsScript.Run(ctx, r.Client, []string{attributeStampsKey(objectId)}, args...).StringSlice()
+	if err == redis.Nil {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis stamp merge failed: %w", err)
+	}
+	return accepted, nil
+}
+
+// newStampScript stamps the attributes with a stamp newer than any of their current
+// ones. KEYS[1] stamps hash, ARGV[1] user id, ARGV[2..] attribute names. It returns
+// the stamp, formatted like types.FormatStamp.
+var newStampScript = redis.NewScript(`
+local clock = 0
+for i = 2, #ARGV do
+	local current = redis.call('HGET', KEYS[1], ARGV[i])
+	if current then
+		local c = tonumber(string.sub(current, 1, 20))
+		if c and c > clock then
+			clock = c
+		end
+	end
+end
+local stamp = string.format('%020d:%s', clock + 1, ARGV[1])
+for i = 2, #ARGV do
+	redis.call('HSET', KEYS[1], ARGV[i], stamp)
+end
+return stamp
+`)
+
+// NewAttributeStamp records and returns a stamp of userId newer than the current stamps
+// of the given attributes of objectId. Updates made by the server (undo / redo) use it
+// to win over the writes they revert.
+func (r *RedisClient) NewAttributeStamp(ctx context.Context, objectId string, userId string, attributes []string) (string, error) {
+	args := make([]interface{}, 0, len(attributes)+1)
+	args = append(args, userId)
+	for _, attribute := range attributes {
+		args = append(args, attribute)
+	}
+
+	stamp, err := newStampScript.Run(ctx, r.Client, []string{attributeStampsKey(objectId)}, args...).Text()
+	if err != nil {
+		return "", fmt.Errorf("redis new stamp failed: %w", err)
+	}
+	return stamp, nil
+}
+
+// ForgetAttributeStamps drops the stamps of a deleted object
+func (r *RedisClient) ForgetAttributeStamps(ctx context.Context, objectId string) error {
+	if err := r.Client.Del(ctx, attributeStampsKey(objectId)).Err(); err != nil {
+		return fmt.Errorf("redis DEL failed: %w", err)
+	}
+	return nil
+}
+
+func roomCheckpointKey(docId string) string {
+	return "room:" + docId
+}
+
+// SaveRoomCheckpoint stores the encoded in-memory document of a room for ttl
+func (r *RedisClient) SaveRoomCheckpoint(ctx context.Context, docId string, checkpoint []byte, ttl time.Duration) error {
+	if err := r.Client.Set(ctx, roomCheckpointKey(docId), checkpoint, ttl).Err(); err != nil {
+		return fmt.Errorf("redis SET failed: %w", err)
+	}
+	return nil
+}
+
+// GetRoomCheckpoint returns the checkpoint of a room, nil when there is none
+func (r *RedisClient) GetRoomCheckpoint(ctx context.Context, docId string) ([]byte, error) {
+	checkpoint, err := r.Client.Get(ctx, roomCheckpointKey(docId)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis GET failed: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// DeleteRoomCheckpoint drops the checkpoint of a room
+func (r *RedisClient) DeleteRoomCheckpoint(ctx context.Context, docId string) error {
+	if err := r.Client.Del(ctx, roomCheckpointKey(docId)).Err(); err != nil {
+		return fmt.Errorf("redis DEL failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"UpdatesService/model"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DocumentRepository only reads documents, every write goes through kafka and the consumer
+type DocumentRepository struct {
+	collection                *mongo.Collection
+	sharedDocRecordCollection *mongo.Collection
+}
+
+func NewDocumentRepository(client *mongo.Client, database string, collection string, sharedDocRecordCollection string) *DocumentRepository {
+	db := client.Database(database)
+	return &DocumentRepository{
+		collection:                db.Collection(collection),
+		sharedDocRecordCollection: db.Collection(sharedDocRecordCollection),
+	}
+}
+
+func (r *DocumentRepository) GetDocumentByID(ctx context.Context, docId string) (*model.Document, error) {
+	docObjectId, err := primitive.ObjectIDFromHex(docId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Document ID format: %w", err)
+	}
+
+	var doc model.Document
+	if err := r.collection.FindOne(ctx, bson.M{"_id": docObjectId}).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("[Repository][GetDocumentByID] error retrieving document: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// Access types of a user on a document, as DocumentService grants them
+const (
+	AccessOwner  = "Owner"
+	AccessEditor = "Editor"
+	AccessViewer = "Viewer"
+)
+
+// FindAccessType returns AccessOwner for the owner of the document, the access type of
+// the collaboration record for a collaborator and "" for anyone else
+func (r *DocumentRepository) FindAccessType(ctx context.Context, userId string, documentId string) (string, error) {
+	doc, err := r.GetDocumentByID(ctx, documentId)
+	if err != nil {
+		return "", err
+	}
+	if doc.OwnerID == userId {
+		return AccessOwner, nil
+	}
+
+	var record model.CollaborationRecord
+	err = r.sharedDocRecordCollection.FindOne(ctx, bson.M{"userId": userId, "documentId": documentId}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("[Repository][FindAccessType] error retrieving collaboration record: %w", err)
+	}
+	return record.AccessType, nil
+}
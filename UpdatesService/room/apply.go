@@ -0,0 +1,147 @@
+package room
+
+import (
+	"Shared/geometry"
+	"Shared/operation"
+	"UpdatesService/model"
+	"fmt"
+)
+
+// Change is an action derived by the server from another one
+type Change struct {
+	Action  map[string]interface{}
+	Inverse map[string]interface{} // op reverting the change
+	Region  *geometry.Rect         // canvas region the change affects on whiteboards, nil for the whole document
+}
+
+// Result is what applying an action produced
+type Result struct {
+	Region    *geometry.Rect         // canvas region the action affected (whiteboards only)
+	Inverse   map[string]interface{} // op reverting the action, nil when it can't be computed
+	FollowUps []Change               // changes the server has to broadcast and persist
+}
+
+// Apply applies an action which has been pushed to kafka. The follow-up changes are
+// the new geometry of connectors bound to objects which changed. The inverse of the
+// action goes to the history of the session which sent it, kind (StepDo, StepUndo or
+// StepRedo) tells which one.
+func (s *State) Apply(action map[string]interface{}, sessionId string, kind string) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	var result Result
+	if action["action"] == "document_reset" {
+		if err := s.reset(action); err != nil {
+			fmt.Printf("[Room][Apply] %v\n", err)
+		}
+		return result
+	}
+
+	pending := s.capture(action)
+	s.touch(action)
+
+	// on whiteboards the region covers the objects before and after the change
+	var objectIds []string
+	touched := map[string]bool{}
+	if canvas := s.canvas(); canvas != nil {
+		objectIds = referencedObjects(canvas, action)
+		for _, objectId := range topLevelIds(canvas, objectIds) {
+			touched[objectId] = true
+		}
+		result.Region = unionBounds(canvas, touched, result.Region)
+	}
+
+	if err := operation.ApplyAction(s.Document, action); err != nil {
+		fmt.Printf("[Room][Apply] %v\n", err)
+	} else if pending != nil {
+		if st := s.complete(pending); st != nil {
+			result.Inverse = st.inverse
+			if sessionId != "" {
+				s.record(sessionId, kind, st)
+			}
+		}
+	}
+
+	if canvas := s.canvas(); canvas != nil {
+		for _, objectId := range topLevelIds(canvas, objectIds) {
+			touched[objectId] = true
+		}
+		result.Region = unionBounds(canvas, touched, result.Region)
+		s.reindex(canvas, touched)
+	}
+
+	slideIds := map[string]bool{}
+	if slideId, ok := action["slideId"].(string); ok {
+		slideIds[slideId] = true
+	}
+	if ops, ok := action["ops"].([]interface{}); ok && action["action"] == "batch" {
+		for _, o := range ops {
+			if slideId, ok := o.(map[string]interface{})["slideId"].(string); ok {
+				slideIds[slideId] = true
+			}
+		}
+	}
+
+	for slideId := range slideIds {
+		if slide := operation.FindSlide(s.Document, slideId); slide != nil {
+			result.FollowUps = append(result.FollowUps, s.routeConnectors(slide)...)
+		}
+	}
+	return result
+}
+
+// routeConnectors recomputes the bound connectors of the slide and returns one update
+// per connector which moved. Connectors inside groups move with their group.
+func (s *State) routeConnectors(slide *model.Slide) []Change {
+	var changes []Change
+	indexed := s.index != nil && slide == s.canvas()
+
+	for i := range slide.Objects {
+		connector := &slide.Objects[i]
+		if !geometry.IsConnector(connector) || connector.Attributes == nil {
+			continue
+		}
+
+		updated := geometry.RouteConnector(slide.Objects, connector)
+		if updated == nil {
+			continue
+		}
+
+		s.touched[connector.ID] = s.version
+		before, hadBounds := geometry.Bounds(connector)
+		prior := make(map[string]interface{}, len(updated))
+		for key, value := range updated {
+			prior[key] = cloneValue(getAttribute(connector.Attributes, key))
+			operation.SetAttribute(connector.Attributes, key, value)
+		}
+
+		change := Change{
+			Action: map[string]interface{}{
+				"action":            "update",
+				"slideId":           slide.ID,
+				"objectId":          connector.ID,
+				"objectType":        connector.Type,
+				"updatedAttributes": updated,
+			},
+			Inverse: map[string]interface{}{
+				"action":            "update",
+				"slideId":           slide.ID,
+				"objectId":          connector.ID,
+				"objectType":        connector.Type,
+				"updatedAttributes": prior,
+			},
+		}
+		if indexed {
+			touched := map[string]bool{connector.ID: true}
+			if hadBounds {
+				change.Region = &before
+			}
+			change.Region = unionBounds(slide, touched, change.Region)
+			s.reindex(slide, touched)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
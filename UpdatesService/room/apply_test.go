@@ -0,0 +1,160 @@
+package room
+
+import (
+	"UpdatesService/model"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func rect(id string, x float64) model.Object {
+	return model.Object{ID: id, Type: "rectangle", Attributes: map[string]interface{}{
+		"x": x, "y": 0.0, "width": 10.0, "height": 10.0,
+	}}
+}
+
+// boundArrow connects the right side of from to the left side of to
+func boundArrow(id string, from string, to string) model.Object {
+	return model.Object{ID: id, Type: "arrow", Attributes: map[string]interface{}{
+		"x": 10.0, "y": 5.0, "width": 90.0, "height": 0.0,
+		"startBinding": map[string]interface{}{"objectId": from, "anchor": "right"},
+		"endBinding":   map[string]interface{}{"objectId": to, "anchor": "left"},
+	}}
+}
+
+func newState(objects ...model.Object) *State {
+	s := &State{Document: &model.Document{Slides: []model.Slide{{ID: "s1", Objects: objects}}}}
+	s.buildIndex()
+	return s
+}
+
+// attributes formats the attributes with sorted keys, bindings by the id of their object
+func attributes(attr map[string]interface{}) string {
+	keys := make([]string, 0, len(attr))
+	for key := range attr {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := ""
+	for _, key := range keys {
+		value := attr[key]
+		if binding, ok := value.(map[string]interface{}); ok {
+			value = binding["objectId"]
+		}
+		out += fmt.Sprintf(" %s=%v", key, value)
+	}
+	return out
+}
+
+func describeObjects(objects []model.Object) []string {
+	out := []string{}
+	for _, o := range objects {
+		out = append(out, fmt.Sprintf("%s{%s }", o.ID, attributes(o.Attributes)))
+	}
+	return out
+}
+
+func describeAction(action map[string]interface{}) string {
+	if action == nil {
+		return ""
+	}
+	updated, _ := action["updatedAttributes"].(map[string]interface{})
+	return fmt.Sprintf("%s %s{%s }", action["action"], action["objectId"], attributes(updated))
+}
+
+func describeChanges(changes []Change) []string {
+	out := []string{}
+	for _, c := range changes {
+		out = append(out, describeAction(c.Action))
+	}
+	return out
+}
+
+func update(objectId string, attr map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"action": "update", "slideId": "s1", "objectId": objectId, "objectType": "rectangle", "updatedAttributes": attr}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name          string
+		objects       []model.Object
+		action        map[string]interface{}
+		wantObjects   []string
+		wantFollowUps []string
+	}{
+		{
+			name:        "create",
+			action:      map[string]interface{}{"action": "create", "slideId": "s1", "objectId": "a", "objectType": "rectangle", "attributes": map[string]interface{}{"x": 1.0}},
+			wantObjects: []string{"a{ x=1 }"},
+		},
+		{
+			name:        "update",
+			objects:     []model.Object{rect("a", 0)},
+			action:      update("a", map[string]interface{}{"x": 5.0}),
+			wantObjects: []string{"a{ height=10 width=10 x=5 y=0 }"},
+		},
+		{
+			name:        "delete",
+			objects:     []model.Object{rect("a", 0), rect("b", 20)},
+			action:      map[string]interface{}{"action": "delete", "slideId": "s1", "objectId": "a"},
+			wantObjects: []string{"b{ height=10 width=10 x=20 y=0 }"},
+		},
+		{
+			name:        "unknown object leaves the slide unchanged",
+			objects:     []model.Object{rect("a", 0)},
+			action:      update("missing", map[string]interface{}{"x": 5.0}),
+			wantObjects: []string{"a{ height=10 width=10 x=0 y=0 }"},
+		},
+		{
+			name:    "moving a bound object reroutes the connector",
+			objects: []model.Object{rect("a", 0), rect("b", 100), boundArrow("c", "a", "b")},
+			action:  update("b", map[string]interface{}{"x": 200.0}),
+			wantObjects: []string{
+				"a{ height=10 width=10 x=0 y=0 }",
+				"b{ height=10 width=10 x=200 y=0 }",
+				"c{ endBinding=b height=0 startBinding=a width=190 x=10 y=5 }",
+			},
+			wantFollowUps: []string{"update c{ height=0 width=190 x=10 y=5 }"},
+		},
+		{
+			name:    "moving an unbound object leaves connectors alone",
+			objects: []model.Object{rect("a", 0), rect("b", 100), rect("d", 300), boundArrow("c", "a", "b")},
+			action:  update("d", map[string]interface{}{"x": 400.0}),
+			wantObjects: []string{
+				"a{ height=10 width=10 x=0 y=0 }",
+				"b{ height=10 width=10 x=100 y=0 }",
+				"d{ height=10 width=10 x=400 y=0 }",
+				"c{ endBinding=b height=0 startBinding=a width=90 x=10 y=5 }",
+			},
+			wantFollowUps: []string{},
+		},
+		{
+			name:    "deleting a bound object unbinds the connector",
+			objects: []model.Object{rect("a", 0), rect("b", 100), boundArrow("c", "a", "b")},
+			action:  map[string]interface{}{"action": "delete", "slideId": "s1", "objectId": "b"},
+			wantObjects: []string{
+				"a{ height=10 width=10 x=0 y=0 }",
+				"c{ endBinding=<nil> height=0 startBinding=a width=90 x=10 y=5 }",
+			},
+			wantFollowUps: []string{"update c{ endBinding=<nil> }"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newState(tt.objects...)
+			result := s.Apply(tt.action, "", StepDo)
+
+			if got := describeObjects(s.Document.Slides[0].Objects); !reflect.DeepEqual(got, tt.wantObjects) {
+				t.Errorf("objects: got %v, want %v", got, tt.wantObjects)
+			}
+			if tt.wantFollowUps == nil {
+				tt.wantFollowUps = []string{}
+			}
+			if got := describeChanges(result.FollowUps); !reflect.DeepEqual(got, tt.wantFollowUps) {
+				t.Errorf("follow-ups: got %v, want %v", got, tt.wantFollowUps)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package room
+
+import (
+	"Shared/geometry"
+	"Shared/operation"
+	"UpdatesService/model"
+	"encoding/json"
+	"fmt"
+)
+
+// IsWhiteboard reports whether the document is an unbounded canvas instead of a slide deck
+func (s *State) IsWhiteboard() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Document.Kind == model.DocumentKindWhiteboard
+}
+
+// canvas returns the slide of a whiteboard, nil for slide decks. s.mu must be held.
+func (s *State) canvas() *model.Slide {
+	if s.Document.Kind != model.DocumentKindWhiteboard || len(s.Document.Slides) == 0 {
+		return nil
+	}
+	return &s.Document.Slides[0]
+}
+
+// buildIndex indexes every object of a whiteboard canvas
+func (s *State) buildIndex() {
+	canvas := s.canvas()
+	if canvas == nil {
+		return
+	}
+
+	s.index = NewSpatialIndex()
+	touched := make(map[string]bool, len(canvas.Objects))
+	for i := range canvas.Objects {
+		touched[canvas.Objects[i].ID] = true
+	}
+	s.reindex(canvas, touched)
+}
+
+// reindex updates the index entries of the given top-level objects, removed objects are dropped
+func (s *State) reindex(canvas *model.Slide, objectIds map[string]bool) {
+	if s.index == nil {
+		return
+	}
+
+	for objectId := range objectIds {
+		s.index.Remove(objectId)
+	}
+	for i := range canvas.Objects {
+		obj := &canvas.Objects[i]
+		if !objectIds[obj.ID] {
+			continue
+		}
+		if bounds, ok := geometry.Bounds(obj); ok {
+			s.index.Set(obj.ID, bounds)
+		}
+	}
+}
+
+// Region returns the canvas region a broadcast-only action (a cursor move, a preview)
+// concerns, nil when the document is not a whiteboard or the region is unknown
+func (s *State) Region(action map[string]interface{}) *geometry.Rect {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	canvas := s.canvas()
+	if canvas == nil {
+		return nil
+	}
+
+	if location, ok := action["newCursorLocation"].([]interface{}); ok && len(location) == 2 {
+		return &geometry.Rect{X: geometry.ToFloat(location[0]), Y: geometry.ToFloat(location[1])}
+	}
+
+	touched := map[string]bool{}
+	for _, objectId := range topLevelIds(canvas, referencedObjects(canvas, action)) {
+		touched[objectId] = true
+	}
+	return unionBounds(canvas, touched, nil)
+}
+
+// ViewportObjects returns the id of the canvas and the json encoded objects of the
+// whiteboard intersecting viewport, in z-order
+func (s *State) ViewportObjects(viewport geometry.Rect) (string, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	canvas := s.canvas()
+	if canvas == nil || s.index == nil {
+		return "", nil, fmt.Errorf("[Room][ViewportObjects] document is not a whiteboard")
+	}
+
+	found := s.index.Query(viewport)
+	objects := make([]model.Object, 0, len(found))
+	for _, obj := range canvas.Objects {
+		if found[obj.ID] {
+			objects = append(objects, obj)
+		}
+	}
+
+	encoded, err := json.Marshal(objects)
+	if err != nil {
+		return "", nil, fmt.Errorf("[Room][ViewportObjects] %w", err)
+	}
+	return canvas.ID, encoded, nil
+}
+
+// referencedObjects lists the ids of the objects an action reads or changes. The children
+// of a group being dissolved are included, they become top-level objects.
+func referencedObjects(slide *model.Slide, action map[string]interface{}) []string {
+	var objectIds []string
+	if objectId, ok := action["objectId"].(string); ok {
+		objectIds = append(objectIds, objectId)
+
+		if action["action"] == "ungroup" {
+			if objects, i := operation.FindObject(&slide.Objects, objectId); i != -1 {
+				for _, child := range (*objects)[i].Children {
+					objectIds = append(objectIds, child.ID)
+				}
+			}
+		}
+	}
+
+	if childIds, ok := action["childIds"].([]interface{}); ok {
+		for _, childId := range childIds {
+			if id, ok := childId.(string); ok {
+				objectIds = append(objectIds, id)
+			}
+		}
+	}
+
+	// restore_objects replaces objects of the slide by earlier copies
+	if rawObjectIds, ok := action["objectIds"].([]interface{}); ok {
+		for _, rawObjectId := range rawObjectIds {
+			if id, ok := rawObjectId.(string); ok {
+				objectIds = append(objectIds, id)
+			}
+		}
+	}
+	if objects, ok := action["objects"].([]interface{}); ok {
+		for _, o := range objects {
+			entry, _ := o.(map[string]interface{})
+			obj, _ := entry["object"].(map[string]interface{})
+			if id, ok := obj["id"].(string); ok {
+				objectIds = append(objectIds, id)
+			}
+		}
+	}
+
+	if ops, ok := action["ops"].([]interface{}); ok {
+		for _, o := range ops {
+			if op, ok := o.(map[string]interface{}); ok {
+				objectIds = append(objectIds, referencedObjects(slide, op)...)
+			}
+		}
+	}
+
+	return objectIds
+}
+
+// topLevelIds maps object ids to the ids of the objects of the slide containing them
+func topLevelIds(slide *model.Slide, objectIds []string) []string {
+	topLevel := make([]string, 0, len(objectIds))
+	for _, objectId := range objectIds {
+		for i := range slide.Objects {
+			obj := &slide.Objects[i]
+			if obj.ID == objectId {
+				topLevel = append(topLevel, obj.ID)
+				break
+			}
+			if _, j := operation.FindObject(&obj.Children, objectId); j != -1 {
+				topLevel = append(topLevel, obj.ID)
+				break
+			}
+		}
+	}
+	return topLevel
+}
+
+// unionBounds extends region by the current bounds of the given top-level objects
+func unionBounds(slide *model.Slide, objectIds map[string]bool, region *geometry.Rect) *geometry.Rect {
+	for i := range slide.Objects {
+		obj := &slide.Objects[i]
+		if !objectIds[obj.ID] {
+			continue
+		}
+		bounds, ok := geometry.Bounds(obj)
+		if !ok {
+			continue
+		}
+		if region == nil {
+			region = &bounds
+		} else {
+			union := region.Union(bounds)
+			region = &union
+		}
+	}
+	return region
+}
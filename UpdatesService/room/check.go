@@ -0,0 +1,153 @@
+package room
+
+import (
+	"Shared/operation"
+	"UpdatesService/model"
+	"fmt"
+)
+
+// Check refuses an action referencing a slide, object or layer the document doesn't
+// hold, or creating one whose id is taken. Ops are checked before they are broadcast,
+// the consumer would otherwise fail to persist them after the room has seen them.
+func (s *State) Check(action map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return check(s.Document, action, map[string]bool{})
+}
+
+// check checks action against doc, created holds the ids created earlier in the same batch
+func check(doc *model.Document, action map[string]interface{}, created map[string]bool) error {
+	actVal, _ := action["action"].(string)
+	slideId, _ := action["slideId"].(string)
+	objectId, _ := action["objectId"].(string)
+	layerId, _ := action["layerId"].(string)
+
+	switch actVal {
+	case "add_slide":
+		if operation.FindSlide(doc, slideId) != nil {
+			return fmt.Errorf("slide %s already exists", slideId)
+		}
+		return nil
+
+	case "duplicate_slide":
+		newSlideId, _ := action["newSlideId"].(string)
+		if operation.FindSlide(doc, newSlideId) != nil {
+			return fmt.Errorf("slide %s already exists", newSlideId)
+		}
+
+	case "batch":
+		ops, _ := action["ops"].([]interface{})
+		for _, o := range ops {
+			op, ok := o.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if err := check(doc, op, created); err != nil {
+				return err
+			}
+			if op["action"] == "create" {
+				id, _ := op["objectId"].(string)
+				created[id] = true
+			}
+		}
+		return nil
+	}
+
+	if !checkedActions[actVal] {
+		return nil
+	}
+
+	slide := operation.FindSlide(doc, slideId)
+	if slide == nil {
+		return fmt.Errorf("slide %s not found", slideId)
+	}
+
+	switch actVal {
+	case "create", "stroke_begin", "group":
+		if _, i := operation.FindObject(&slide.Objects, objectId); i != -1 || created[objectId] {
+			return fmt.Errorf("object %s already exists", objectId)
+		}
+
+	case "update", "transient_update", "commit_update", "delete", "reorder", "text_edit",
+		"ungroup", "set_layer", "select", "add_to_frame", "remove_from_frame":
+		if err := objectExists(slide, objectId, created); err != nil {
+			return err
+		}
+
+	case "update_layer", "remove_layer", "move_layer":
+		if !hasLayer(slide, layerId) {
+			return fmt.Errorf("layer %s not found", layerId)
+		}
+		return nil
+
+	case "add_layer":
+		if hasLayer(slide, layerId) {
+			return fmt.Errorf("layer %s already exists", layerId)
+		}
+		return nil
+	}
+
+	if childIds, ok := action["childIds"].([]interface{}); ok {
+		for _, childId := range childIds {
+			id, _ := childId.(string)
+			if err := objectExists(slide, id, created); err != nil {
+				return err
+			}
+		}
+	}
+
+	// objects are put on an existing layer, the base layer ("") always exists
+	if layerId != "" && !hasLayer(slide, layerId) {
+		return fmt.Errorf("layer %s not found", layerId)
+	}
+	return nil
+}
+
+// checkedActions are the actions whose slide has to exist
+var checkedActions = map[string]bool{
+	"remove_slide":      true,
+	"update_slide":      true,
+	"move_slide":        true,
+	"duplicate_slide":   true,
+	"create":            true,
+	"update":            true,
+	"transient_update":  true,
+	"commit_update":     true,
+	"delete":            true,
+	"stroke_begin":      true,
+	"stroke_append":     true,
+	"stroke_end":        true,
+	"reorder":           true,
+	"text_edit":         true,
+	"group":             true,
+	"ungroup":           true,
+	"add_to_frame":      true,
+	"remove_from_frame": true,
+	"set_layer":         true,
+	"add_layer":         true,
+	"update_layer":      true,
+	"remove_layer":      true,
+	"move_layer":        true,
+	"select":            true,
+	"restore_objects":   true,
+}
+
+func objectExists(slide *model.Slide, objectId string, created map[string]bool) error {
+	if created[objectId] {
+		return nil
+	}
+	if _, i := operation.FindObject(&slide.Objects, objectId); i == -1 {
+		return fmt.Errorf("object %s not found", objectId)
+	}
+	return nil
+}
+
+func hasLayer(slide *model.Slide, layerId string) bool {
+	for _, layer := range slide.Layers {
+		if layer.ID == layerId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package room
+
+import (
+	"UpdatesService/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// CheckpointInterval is how often the states which changed are checkpointed
+	CheckpointInterval = 30 * time.Second
+	// checkpointTTL matches releasedStateTTL, once a state is dropped the database
+	// has caught up with it and is the source of truth again
+	checkpointTTL = releasedStateTTL
+)
+
+// RunCheckpoints saves the states which changed since their last checkpoint every
+// interval. A restarted service loads the rooms from their checkpoint, it holds the
+// ops which were still on their way to the database.
+func (m *Manager) RunCheckpoints(interval time.Duration) {
+	if m.checkpoints == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.mu.Lock()
+		states := make(map[string]*State, len(m.states))
+		for docId, state := range m.states {
+			states[docId] = state
+		}
+		m.mu.Unlock()
+
+		for docId, state := range states {
+			if err := m.checkpoint(docId, state); err != nil {
+				fmt.Printf("[Room][RunCheckpoints] %v\n", err)
+			}
+		}
+	}
+}
+
+// checkpoint saves the state when it changed since its last checkpoint
+func (m *Manager) checkpoint(docId string, state *State) error {
+	state.mu.Lock()
+	if state.version == state.checkpointed {
+		state.mu.Unlock()
+		return nil
+	}
+	version := state.version
+	encoded, err := json.Marshal(state.Document)
+	state.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode document %s: %w", docId, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := m.checkpoints.SaveRoomCheckpoint(ctx, docId, encoded, checkpointTTL); err != nil {
+		return err
+	}
+
+	state.mu.Lock()
+	state.checkpointed = version
+	state.mu.Unlock()
+	return nil
+}
+
+// load reads the document from the database and from its checkpoint and keeps the
+// newer one. The checkpoint wins when the database has no op it didn't acknowledge:
+// it also holds the ops which were still on their way to the database.
+func (m *Manager) load(ctx context.Context, docId string) (*model.Document, error) {
+	stored, err := m.repository.GetDocumentByID(ctx, docId)
+	if err != nil {
+		return nil, err
+	}
+	if m.checkpoints == nil {
+		return stored, nil
+	}
+
+	encoded, err := m.checkpoints.GetRoomCheckpoint(ctx, docId)
+	if err != nil {
+		fmt.Printf("[Room][load] %v\n", err)
+		return stored, nil
+	}
+	if encoded == nil {
+		return stored, nil
+	}
+
+	var doc model.Document
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		fmt.Printf("[Room][load] invalid checkpoint for %s: %v\n", docId, err)
+		return stored, nil
+	}
+	if doc.Seq < stored.Seq {
+		fmt.Printf("[Room][load] checkpoint of %s is older than the database (seq %d < %d)\n", docId, doc.Seq, stored.Seq)
+		return stored, nil
+	}
+	return &doc, nil
+}
+
+// ForgetCheckpoint drops the checkpoint of a document which isn't open, it would
+// shadow a change written to the database without going through the room
+func (m *Manager) ForgetCheckpoint(ctx context.Context, docId string) {
+	if m.checkpoints == nil {
+		return
+	}
+
+	m.mu.Lock()
+	_, open := m.states[docId]
+	m.mu.Unlock()
+	if open {
+		return
+	}
+
+	if err := m.checkpoints.DeleteRoomCheckpoint(ctx, docId); err != nil {
+		fmt.Printf("[Room][ForgetCheckpoint] %v\n", err)
+	}
+}
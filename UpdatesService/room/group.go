@@ -0,0 +1,38 @@
+package room
+
+import "UpdatesService/model"
+
+// LockTarget returns the top-most group enclosing objectId without crossing a frame,
+// or objectId itself. Frames don't share their lock with their contents.
+func (s *State) LockTarget(objectId string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.Document.Slides {
+		path := objectPath(s.Document.Slides[i].Objects, objectId)
+		if path == nil {
+			continue
+		}
+
+		target := objectId
+		for j := len(path) - 2; j >= 0 && path[j].Type == "group"; j-- {
+			target = path[j].ID
+		}
+		return target
+	}
+	return objectId
+}
+
+// objectPath returns the objects from the top of the slide down to objectId, nil when it is missing
+func objectPath(objects []model.Object, objectId string) []*model.Object {
+	for i := range objects {
+		obj := &objects[i]
+		if obj.ID == objectId {
+			return []*model.Object{obj}
+		}
+		if path := objectPath(obj.Children, objectId); path != nil {
+			return append([]*model.Object{obj}, path...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,423 @@
+package room
+
+import (
+	"Shared/operation"
+	"UpdatesService/model"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Every session has an undo and a redo history. When an op of the session is
+// applied, the op reverting it is computed from the state right before it:
+//   - attribute updates are reverted by an update restoring the previous values,
+//   - structural ops (create, delete, reorder, group, frames, set_layer, batches)
+//     by a restore_objects op putting back the top-level objects they touched.
+//
+// Undoing applies the reverting op, whose own inverse goes to the redo history.
+// Before a step is replayed it is checked against the current state: attributes
+// a collaborator has changed since are left alone, and a structural step whose
+// objects were changed by someone else is dropped instead of overwriting them.
+
+// Kinds of the ops applied to a state, they tell which history their inverse goes to
+const (
+	StepDo   = ""     // op sent by a client
+	StepUndo = "undo" // inverse taken from the undo history
+	StepRedo = "redo" // inverse taken from the redo history
+)
+
+// MaxHistory caps the undo and redo histories of a session
+const MaxHistory = 100
+
+var attributeActions = map[string]bool{
+	"update":        true,
+	"commit_update": true,
+}
+
+var structuralActions = map[string]bool{
+	"create":            true,
+	"stroke_end":        true,
+	"delete":            true,
+	"reorder":           true,
+	"group":             true,
+	"ungroup":           true,
+	"add_to_frame":      true,
+	"remove_from_frame": true,
+	"set_layer":         true,
+	"batch":             true,
+	"restore_objects":   true,
+}
+
+// step is one entry of a history
+type step struct {
+	inverse  map[string]interface{}
+	expected map[string]interface{} // attribute steps: the values written by the op
+	after    map[string]string      // structural steps: json of the top-level objects after the op
+	removed  []string               // structural steps: ids of the objects the op deleted
+}
+
+type history struct {
+	undo []*step
+	redo []*step
+}
+
+// Step is an entry taken from a history, Op reverts it
+type Step struct {
+	Kind string // StepUndo or StepRedo
+	Op   map[string]interface{}
+	step *step
+}
+
+// pendingStep is what is captured before an op is applied
+type pendingStep struct {
+	slideId   string
+	objectId  string
+	prior     map[string]interface{} // attribute steps
+	objectIds []string               // structural steps: objects referenced by the op
+	before    []placement
+}
+
+// placement is an object of a slide and its index, as sent in restore_objects
+type placement struct {
+	Index  int          `json:"index"`
+	Object model.Object `json:"object"`
+}
+
+// Undo takes the last step of the session's undo history
+func (s *State) Undo(sessionId string) (*Step, error) {
+	return s.take(sessionId, StepUndo)
+}
+
+// Redo takes the last step of the session's redo history
+func (s *State) Redo(sessionId string) (*Step, error) {
+	return s.take(sessionId, StepRedo)
+}
+
+// Requeue gives back a step which could not be applied (e.g. a lock was not free)
+func (s *State) Requeue(sessionId string, st *Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.history(sessionId)
+	if st.Kind == StepUndo {
+		h.undo = append(h.undo, st.step)
+	} else {
+		h.redo = append(h.redo, st.step)
+	}
+}
+
+// ForgetSession drops the history of a disconnected session
+func (s *State) ForgetSession(sessionId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.histories, sessionId)
+}
+
+func (s *State) take(sessionId string, kind string) (*Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.history(sessionId)
+	stack := &h.undo
+	if kind == StepRedo {
+		stack = &h.redo
+	}
+	if len(*stack) == 0 {
+		return nil, fmt.Errorf("[Room][%s] nothing to %s", kind, kind)
+	}
+
+	st := (*stack)[len(*stack)-1]
+	*stack = (*stack)[:len(*stack)-1]
+
+	// a step which conflicts with the current state is dropped
+	op, err := s.replayable(st)
+	if err != nil {
+		return nil, fmt.Errorf("[Room][%s] %w", kind, err)
+	}
+	return &Step{Kind: kind, Op: op, step: st}, nil
+}
+
+// history returns the history of the session, s.mu must be held
+func (s *State) history(sessionId string) *history {
+	if s.histories == nil {
+		s.histories = make(map[string]*history)
+	}
+	h, ok := s.histories[sessionId]
+	if !ok {
+		h = &history{}
+		s.histories[sessionId] = h
+	}
+	return h
+}
+
+// record adds the inverse of an applied op to the history of the session
+func (s *State) record(sessionId string, kind string, st *step) {
+	h := s.history(sessionId)
+	switch kind {
+	case StepDo:
+		h.undo = pushStep(h.undo, st)
+		h.redo = nil
+	case StepUndo:
+		h.redo = pushStep(h.redo, st)
+	case StepRedo:
+		h.undo = pushStep(h.undo, st)
+	}
+}
+
+func pushStep(stack []*step, st *step) []*step {
+	stack = append(stack, st)
+	if len(stack) > MaxHistory {
+		stack = stack[len(stack)-MaxHistory:]
+	}
+	return stack
+}
+
+// capture records what the inverse of action needs, nil when the action can't be undone
+func (s *State) capture(action map[string]interface{}) *pendingStep {
+	actionStr, _ := action["action"].(string)
+	if !attributeActions[actionStr] && !structuralActions[actionStr] {
+		return nil
+	}
+
+	slideId, ok := actionSlide(action)
+	if !ok {
+		return nil
+	}
+	slide := operation.FindSlide(s.Document, slideId)
+	if slide == nil {
+		return nil
+	}
+
+	p := &pendingStep{slideId: slideId}
+	if attributeActions[actionStr] {
+		p.objectId, _ = action["objectId"].(string)
+		objects, i := operation.FindObject(&slide.Objects, p.objectId)
+		updated, ok := action["updatedAttributes"].(map[string]interface{})
+		if i == -1 || !ok {
+			return nil
+		}
+		p.prior = make(map[string]interface{}, len(updated))
+		for key := range updated {
+			p.prior[key] = cloneValue(getAttribute((*objects)[i].Attributes, key))
+		}
+		return p
+	}
+
+	p.objectIds = referencedObjects(slide, action)
+	for _, objectId := range uniqueIds(topLevelIds(slide, p.objectIds)) {
+		for i := range slide.Objects {
+			if slide.Objects[i].ID == objectId {
+				p.before = append(p.before, placement{Index: i, Object: copyObject(slide.Objects[i])})
+			}
+		}
+	}
+	return p
+}
+
+// complete builds the step reverting the op once it has been applied
+func (s *State) complete(p *pendingStep) *step {
+	slide := operation.FindSlide(s.Document, p.slideId)
+	if slide == nil {
+		return nil
+	}
+
+	if p.prior != nil {
+		objects, i := operation.FindObject(&slide.Objects, p.objectId)
+		if i == -1 {
+			return nil
+		}
+		obj := &(*objects)[i]
+
+		st := &step{expected: map[string]interface{}{}}
+		restored := map[string]interface{}{}
+		for key, prior := range p.prior {
+			current := getAttribute(obj.Attributes, key)
+			// lww updates may have lost some attributes, those weren't changed
+			if jsonEqual(current, prior) {
+				continue
+			}
+			st.expected[key] = cloneValue(current)
+			restored[key] = prior
+		}
+		if len(restored) == 0 {
+			return nil
+		}
+		// no stamp, in lww mode the client stamps it when it is sent (see Client.HandleHistory)
+		st.inverse = map[string]interface{}{
+			"action":            "update",
+			"slideId":           p.slideId,
+			"objectId":          p.objectId,
+			"objectType":        obj.Type,
+			"updatedAttributes": restored,
+		}
+		return st
+	}
+
+	st := &step{after: map[string]string{}}
+	objectIds := []interface{}{}
+	for _, objectId := range uniqueIds(topLevelIds(slide, p.objectIds)) {
+		for i := range slide.Objects {
+			if slide.Objects[i].ID == objectId {
+				st.after[objectId] = encodeObject(slide.Objects[i])
+				objectIds = append(objectIds, objectId)
+			}
+		}
+	}
+
+	objects := make([]interface{}, 0, len(p.before))
+	for _, b := range p.before {
+		if _, i := operation.FindObject(&slide.Objects, b.Object.ID); i == -1 {
+			st.removed = append(st.removed, b.Object.ID)
+		}
+		objects = append(objects, map[string]interface{}{"index": b.Index, "object": b.Object})
+	}
+	if len(objectIds) == 0 && len(objects) == 0 {
+		return nil
+	}
+
+	st.inverse = map[string]interface{}{
+		"action":    "restore_objects",
+		"slideId":   p.slideId,
+		"objectIds": objectIds,
+		"objects":   objects,
+	}
+	return st
+}
+
+// replayable checks a step against the current state and returns the op to apply, s.mu must be held
+func (s *State) replayable(st *step) (map[string]interface{}, error) {
+	slideId, _ := st.inverse["slideId"].(string)
+	slide := operation.FindSlide(s.Document, slideId)
+	if slide == nil {
+		return nil, fmt.Errorf("slide %s not found", slideId)
+	}
+
+	if st.expected != nil {
+		objectId, _ := st.inverse["objectId"].(string)
+		objects, i := operation.FindObject(&slide.Objects, objectId)
+		if i == -1 {
+			return nil, fmt.Errorf("object %s has been deleted", objectId)
+		}
+
+		// only the attributes nobody changed since are reverted
+		restored := map[string]interface{}{}
+		updated := st.inverse["updatedAttributes"].(map[string]interface{})
+		for key, expected := range st.expected {
+			if jsonEqual(getAttribute((*objects)[i].Attributes, key), expected) {
+				restored[key] = updated[key]
+			}
+		}
+		if len(restored) == 0 {
+			return nil, fmt.Errorf("object %s has been changed by a collaborator", objectId)
+		}
+
+		op := make(map[string]interface{}, len(st.inverse))
+		for key, value := range st.inverse {
+			op[key] = value
+		}
+		op["updatedAttributes"] = restored
+		return op, nil
+	}
+
+	for objectId, after := range st.after {
+		found := false
+		for i := range slide.Objects {
+			if slide.Objects[i].ID == objectId {
+				found = encodeObject(slide.Objects[i]) == after
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("object %s has been changed by a collaborator", objectId)
+		}
+	}
+	for _, objectId := range st.removed {
+		if _, i := operation.FindObject(&slide.Objects, objectId); i != -1 {
+			return nil, fmt.Errorf("object %s has been recreated by a collaborator", objectId)
+		}
+	}
+	return st.inverse, nil
+}
+
+// actionSlide returns the slide of an object op, batches must stay on one slide
+func actionSlide(action map[string]interface{}) (string, bool) {
+	if slideId, ok := action["slideId"].(string); ok {
+		return slideId, true
+	}
+
+	ops, ok := action["ops"].([]interface{})
+	if !ok || len(ops) == 0 {
+		return "", false
+	}
+	slideId := ""
+	for _, o := range ops {
+		op, _ := o.(map[string]interface{})
+		opSlideId, ok := op["slideId"].(string)
+		if !ok || (slideId != "" && opSlideId != slideId) {
+			return "", false
+		}
+		slideId = opSlideId
+	}
+	return slideId, true
+}
+
+// getAttribute reads attr[key], dotted keys address nested maps like operation.SetAttribute
+func getAttribute(attr map[string]interface{}, key string) interface{} {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := operation.AsMap(attr[part])
+		if !ok {
+			return nil
+		}
+		attr = next
+	}
+	return attr[parts[len(parts)-1]]
+}
+
+func uniqueIds(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func copyObject(obj model.Object) model.Object {
+	var copied model.Object
+	if err := json.Unmarshal([]byte(encodeObject(obj)), &copied); err != nil {
+		fmt.Printf("[Room][copyObject] %v\n", err)
+	}
+	return copied
+}
+
+func encodeObject(obj model.Object) string {
+	encoded, err := json.Marshal(obj)
+	if err != nil {
+		fmt.Printf("[Room][encodeObject] %v\n", err)
+	}
+	return string(encoded)
+}
+
+// cloneValue deep copies an attribute value into its json form
+func cloneValue(v interface{}) interface{} {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var cloned interface{}
+	if err := json.Unmarshal(encoded, &cloned); err != nil {
+		return nil
+	}
+	return cloned
+}
+
+func jsonEqual(a interface{}, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
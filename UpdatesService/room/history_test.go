@@ -0,0 +1,170 @@
+package room
+
+import (
+	"UpdatesService/model"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// historyStep is an op of a session, or an undo or redo of it when kind is StepUndo or StepRedo
+type historyStep struct {
+	session string
+	kind    string
+	op      map[string]interface{}
+	fails   bool // the undo or redo is refused
+}
+
+func do(session string, op map[string]interface{}) historyStep {
+	return historyStep{session: session, kind: StepDo, op: op}
+}
+
+func undo(session string) historyStep {
+	return historyStep{session: session, kind: StepUndo}
+}
+
+func redo(session string) historyStep {
+	return historyStep{session: session, kind: StepRedo}
+}
+
+func refused(st historyStep) historyStep {
+	st.fails = true
+	return st
+}
+
+// viaJSON returns op as the room gets it, clients and kafka messages carry json
+func viaJSON(t *testing.T, op map[string]interface{}) map[string]interface{} {
+	encoded, err := json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestHistory(t *testing.T) {
+	create := map[string]interface{}{"action": "create", "slideId": "s1", "objectId": "n", "objectType": "rectangle", "attributes": map[string]interface{}{"x": 1.0}}
+	deleteA := map[string]interface{}{"action": "delete", "slideId": "s1", "objectId": "a"}
+
+	tests := []struct {
+		name    string
+		objects []model.Object
+		steps   []historyStep
+		want    []string
+	}{
+		{
+			name:    "undo an update",
+			objects: []model.Object{rect("a", 0)},
+			steps:   []historyStep{do("s", update("a", map[string]interface{}{"x": 5.0})), undo("s")},
+			want:    []string{"a{ height=10 width=10 x=0 y=0 }"},
+		},
+		{
+			name:    "redo an undone update",
+			objects: []model.Object{rect("a", 0)},
+			steps:   []historyStep{do("s", update("a", map[string]interface{}{"x": 5.0})), undo("s"), redo("s")},
+			want:    []string{"a{ height=10 width=10 x=5 y=0 }"},
+		},
+		{
+			name:    "undo a create",
+			objects: []model.Object{rect("a", 0)},
+			steps:   []historyStep{do("s", create), undo("s")},
+			want:    []string{"a{ height=10 width=10 x=0 y=0 }"},
+		},
+		{
+			name:    "undo a delete puts the object back at its index",
+			objects: []model.Object{rect("a", 0), rect("b", 20)},
+			steps:   []historyStep{do("s", deleteA), undo("s")},
+			want:    []string{"a{ height=10 width=10 x=0 y=0 }", "b{ height=10 width=10 x=20 y=0 }"},
+		},
+		{
+			name:    "undo and redo a delete",
+			objects: []model.Object{rect("a", 0), rect("b", 20)},
+			steps:   []historyStep{do("s", deleteA), undo("s"), redo("s")},
+			want:    []string{"b{ height=10 width=10 x=20 y=0 }"},
+		},
+		{
+			name:    "nothing to undo",
+			objects: []model.Object{rect("a", 0)},
+			steps:   []historyStep{refused(undo("s"))},
+			want:    []string{"a{ height=10 width=10 x=0 y=0 }"},
+		},
+		{
+			name:    "histories are per session",
+			objects: []model.Object{rect("a", 0)},
+			steps:   []historyStep{do("s", update("a", map[string]interface{}{"x": 5.0})), refused(undo("other"))},
+			want:    []string{"a{ height=10 width=10 x=5 y=0 }"},
+		},
+		{
+			name:    "a new op clears the redo history",
+			objects: []model.Object{rect("a", 0)},
+			steps: []historyStep{
+				do("s", update("a", map[string]interface{}{"x": 5.0})),
+				undo("s"),
+				do("s", update("a", map[string]interface{}{"x": 8.0})),
+				refused(redo("s")),
+			},
+			want: []string{"a{ height=10 width=10 x=8 y=0 }"},
+		},
+		{
+			name:    "undo leaves the attributes a collaborator changed",
+			objects: []model.Object{rect("a", 0)},
+			steps: []historyStep{
+				do("s", update("a", map[string]interface{}{"x": 5.0, "y": 5.0})),
+				do("other", update("a", map[string]interface{}{"y": 9.0})),
+				undo("s"),
+			},
+			want: []string{"a{ height=10 width=10 x=0 y=9 }"},
+		},
+		{
+			name:    "undo is refused when a collaborator changed every attribute",
+			objects: []model.Object{rect("a", 0)},
+			steps: []historyStep{
+				do("s", update("a", map[string]interface{}{"x": 5.0})),
+				do("other", update("a", map[string]interface{}{"x": 7.0})),
+				refused(undo("s")),
+			},
+			want: []string{"a{ height=10 width=10 x=7 y=0 }"},
+		},
+		{
+			name:    "undo of a delete is refused when the object was recreated",
+			objects: []model.Object{rect("a", 0)},
+			steps: []historyStep{
+				do("s", deleteA),
+				do("other", map[string]interface{}{"action": "create", "slideId": "s1", "objectId": "a", "objectType": "rectangle", "attributes": map[string]interface{}{"x": 3.0}}),
+				refused(undo("s")),
+			},
+			want: []string{"a{ x=3 }"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newState(tt.objects...)
+			for i, st := range tt.steps {
+				if st.kind == StepDo {
+					s.Apply(viaJSON(t, st.op), st.session, StepDo)
+					continue
+				}
+
+				take := s.Undo
+				if st.kind == StepRedo {
+					take = s.Redo
+				}
+				step, err := take(st.session)
+				if (err != nil) != st.fails {
+					t.Fatalf("step %d: got error %v, want refused=%v", i, err, st.fails)
+				}
+				if err == nil {
+					s.Apply(viaJSON(t, step.Op), st.session, st.kind)
+				}
+			}
+
+			if got := describeObjects(s.Document.Slides[0].Objects); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package room
+
+import (
+	"Shared/geometry"
+	"math"
+)
+
+// indexCellSize is the side (canvas px) of the cells of the spatial index
+const indexCellSize = 512
+
+// maxIndexCells bounds the cells a single rect is registered in, bigger objects
+// are kept in a list that every query returns
+const maxIndexCells = 4096
+
+type cell struct {
+	X, Y int
+}
+
+// SpatialIndex is a uniform grid over the bounding boxes of the objects of a
+// whiteboard canvas, it finds the objects intersecting a viewport without
+// looking at every object of the board.
+type SpatialIndex struct {
+	cells map[cell]map[string]bool
+	large map[string]bool
+	rects map[string]geometry.Rect
+}
+
+func NewSpatialIndex() *SpatialIndex {
+	return &SpatialIndex{
+		cells: make(map[cell]map[string]bool),
+		large: make(map[string]bool),
+		rects: make(map[string]geometry.Rect),
+	}
+}
+
+// Set adds the object or moves it to its new bounds
+func (idx *SpatialIndex) Set(objectId string, r geometry.Rect) {
+	idx.Remove(objectId)
+	idx.rects[objectId] = r
+
+	if cellSpan(r) > maxIndexCells {
+		idx.large[objectId] = true
+		return
+	}
+
+	minX, minY, maxX, maxY := cellRange(r)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			c := cell{x, y}
+			if idx.cells[c] == nil {
+				idx.cells[c] = make(map[string]bool)
+			}
+			idx.cells[c][objectId] = true
+		}
+	}
+}
+
+func (idx *SpatialIndex) Remove(objectId string) {
+	r, ok := idx.rects[objectId]
+	if !ok {
+		return
+	}
+	delete(idx.rects, objectId)
+
+	if idx.large[objectId] {
+		delete(idx.large, objectId)
+		return
+	}
+
+	minX, minY, maxX, maxY := cellRange(r)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			c := cell{x, y}
+			delete(idx.cells[c], objectId)
+			if len(idx.cells[c]) == 0 {
+				delete(idx.cells, c)
+			}
+		}
+	}
+}
+
+// Query returns the ids of the objects whose bounds intersect r
+func (idx *SpatialIndex) Query(r geometry.Rect) map[string]bool {
+	found := make(map[string]bool)
+	for objectId := range idx.large {
+		if idx.rects[objectId].Intersects(r) {
+			found[objectId] = true
+		}
+	}
+
+	if cellSpan(r) > float64(len(idx.cells)) {
+		// the viewport covers more cells than are in use, scan the objects instead
+		for objectId, bounds := range idx.rects {
+			if bounds.Intersects(r) {
+				found[objectId] = true
+			}
+		}
+		return found
+	}
+
+	minX, minY, maxX, maxY := cellRange(r)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			for objectId := range idx.cells[cell{x, y}] {
+				if idx.rects[objectId].Intersects(r) {
+					found[objectId] = true
+				}
+			}
+		}
+	}
+	return found
+}
+
+// cellSpan is the number of cells r covers, computed without integer overflows
+func cellSpan(r geometry.Rect) float64 {
+	return (math.Floor(r.Width/indexCellSize) + 2) * (math.Floor(r.Height/indexCellSize) + 2)
+}
+
+func cellRange(r geometry.Rect) (int, int, int, int) {
+	return int(math.Floor(r.X / indexCellSize)), int(math.Floor(r.Y / indexCellSize)),
+		int(math.Floor((r.X + r.Width) / indexCellSize)), int(math.Floor((r.Y + r.Height) / indexCellSize))
+}
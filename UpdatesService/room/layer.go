@@ -0,0 +1,49 @@
+package room
+
+import (
+	"Shared/operation"
+	"UpdatesService/model"
+)
+
+// LayerLocked reports whether the layer of the slide is locked, the base layer ("") never is
+func (s *State) LayerLocked(slideId string, layerId string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slide := operation.FindSlide(s.Document, slideId)
+	if slide == nil || layerId == "" {
+		return false
+	}
+	return layerLocked(slide, layerId)
+}
+
+// ObjectLayerLocked reports whether the object, or the group / frame it belongs to, is on a locked layer
+func (s *State) ObjectLayerLocked(slideId string, objectId string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slide := operation.FindSlide(s.Document, slideId)
+	if slide == nil {
+		return false
+	}
+
+	for i := range slide.Objects {
+		obj := &slide.Objects[i]
+		if obj.ID == objectId {
+			return layerLocked(slide, obj.LayerID)
+		}
+		if _, j := operation.FindObject(&obj.Children, objectId); j != -1 {
+			return layerLocked(slide, obj.LayerID)
+		}
+	}
+	return false
+}
+
+func layerLocked(slide *model.Slide, layerId string) bool {
+	for _, layer := range slide.Layers {
+		if layer.ID == layerId {
+			return layer.Locked
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package room
+
+import "Shared/operation"
+
+// reset replaces the slides of the document by the ones of a restored version.
+// Histories are dropped, their steps refer to objects which may not exist anymore, and
+// ops which failed before the reset aren't reverted (see Revert).
+func (s *State) reset(action map[string]interface{}) error {
+	slides, err := operation.DecodeSlides(action["slides"])
+	if err != nil {
+		return err
+	}
+
+	s.Document.Slides = slides
+	s.histories = nil
+	s.touched = nil
+	s.resetAt = s.version
+	s.index = nil
+	s.buildIndex()
+	return nil
+}
@@ -0,0 +1,58 @@
+package room
+
+import (
+	"Shared/operation"
+	"UpdatesService/model"
+)
+
+// touch records that the objects referenced by the action change at the current
+// version, s.mu must be held. It runs before the action is applied, the children of
+// a group are only known until it is ungrouped.
+func (s *State) touch(action map[string]interface{}) {
+	if s.touched == nil {
+		s.touched = make(map[string]uint64)
+	}
+	for _, objectId := range referencedObjects(s.slideOf(action), action) {
+		s.touched[objectId] = s.version
+	}
+}
+
+// Revert applies the inverse of a failed op which was applied at version, unless an
+// action applied since changed one of the objects of the inverse: reverting would undo
+// that change as well. It returns whether the inverse was applied.
+func (s *State) Revert(inverse map[string]interface{}, version uint64) bool {
+	s.mu.Lock()
+	changed := s.changedSince(inverse, version)
+	s.mu.Unlock()
+	if changed {
+		return false
+	}
+
+	// not recorded in any history, the op never reached the document
+	s.Apply(inverse, "", StepDo)
+	return true
+}
+
+// changedSince tells whether an action applied after version changed an object
+// referenced by the action, s.mu must be held
+func (s *State) changedSince(action map[string]interface{}, version uint64) bool {
+	if s.resetAt > version {
+		return true
+	}
+	for _, objectId := range referencedObjects(s.slideOf(action), action) {
+		if s.touched[objectId] > version {
+			return true
+		}
+	}
+	return false
+}
+
+// slideOf returns the slide of the action, an empty slide when it has none
+func (s *State) slideOf(action map[string]interface{}) *model.Slide {
+	if slideId, ok := actionSlide(action); ok {
+		if slide := operation.FindSlide(s.Document, slideId); slide != nil {
+			return slide
+		}
+	}
+	return &model.Slide{}
+}
@@ -0,0 +1,66 @@
+package room
+
+import (
+	"UpdatesService/model"
+	"reflect"
+	"testing"
+)
+
+func TestRevert(t *testing.T) {
+	reset := map[string]interface{}{"action": "document_reset", "slides": []interface{}{
+		map[string]interface{}{"id": "s1", "objects": []interface{}{
+			map[string]interface{}{"id": "a", "type": "rectangle", "attributes": map[string]interface{}{"x": 7.0}},
+		}},
+	}}
+
+	tests := []struct {
+		name        string
+		later       []map[string]interface{} // actions applied after the failed op
+		want        bool                     // the inverse is applied
+		wantObjects []string
+	}{
+		{
+			name:        "reverted when nothing changed since",
+			want:        true,
+			wantObjects: []string{"a{ height=10 width=10 x=0 y=0 }", "b{ height=10 width=10 x=20 y=0 }"},
+		},
+		{
+			name:        "reverted when a later op changed another object",
+			later:       []map[string]interface{}{update("b", map[string]interface{}{"x": 30.0})},
+			want:        true,
+			wantObjects: []string{"a{ height=10 width=10 x=0 y=0 }", "b{ height=10 width=10 x=30 y=0 }"},
+		},
+		{
+			name:        "kept when a later op changed the object",
+			later:       []map[string]interface{}{update("a", map[string]interface{}{"y": 3.0})},
+			wantObjects: []string{"a{ height=10 width=10 x=5 y=3 }", "b{ height=10 width=10 x=20 y=0 }"},
+		},
+		{
+			name:        "kept when the document was reset since",
+			later:       []map[string]interface{}{reset},
+			wantObjects: []string{"a{ x=7 }"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newState(rect("a", 0), rect("b", 20))
+			result := s.Apply(viaJSON(t, update("a", map[string]interface{}{"x": 5.0})), "", StepDo)
+			version := s.Version()
+			for _, action := range tt.later {
+				s.Apply(viaJSON(t, action), "", StepDo)
+			}
+
+			if got := s.Revert(viaJSON(t, result.Inverse), version); got != tt.want {
+				t.Errorf("reverted: got %v, want %v", got, tt.want)
+			}
+			var objects []model.Object
+			if len(s.Document.Slides) > 0 {
+				objects = s.Document.Slides[0].Objects
+			}
+			if got := describeObjects(objects); !reflect.DeepEqual(got, tt.wantObjects) {
+				t.Errorf("objects: got %v, want %v", got, tt.wantObjects)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package room
+
+import (
+	"UpdatesService/model"
+	"UpdatesService/redis"
+	"UpdatesService/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// releasedStateTTL is how long the state of a document stays in memory after its
+// last client left. Ops of the last session may still be on their way to the
+// database, a client reconnecting in the meantime must not load a stale copy.
+const releasedStateTTL = 10 * time.Minute
+
+// State is the in-memory copy of an open document. Every op pushed to kafka is
+// applied to it in the same order, so the server can derive follow-up ops (such
+// as connector geometry) and check ops (see Check) without reading the database.
+type State struct {
+	mu           sync.Mutex
+	Document     *model.Document
+	index        *SpatialIndex       // whiteboards only
+	histories    map[string]*history // undo / redo histories by session
+	clients      int
+	releasedAt   time.Time
+	version      uint64            // number of actions applied
+	touched      map[string]uint64 // version of the last action on each object, see Revert
+	resetAt      uint64            // version of the last document_reset
+	checkpointed uint64            // version of the last checkpoint
+}
+
+// Acknowledge records that the database has the ops up to seq. Document.Seq is the
+// last acknowledged seq, a checkpoint older than the database is ignored by load.
+func (s *State) Acknowledge(seq int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if seq > s.Document.Seq {
+		s.Document.Seq = seq
+	}
+}
+
+// ConcurrencyMode returns the concurrency mode stored on the document, "" when none was set
+func (s *State) ConcurrencyMode() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Document.ConcurrencyMode
+}
+
+// EncodeSlides returns the json encoded slides for which include is true, in order
+func (s *State) EncodeSlides(include func(slideId string) bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slides := []model.Slide{}
+	for _, slide := range s.Document.Slides {
+		if include(slide.ID) {
+			slides = append(slides, slide)
+		}
+	}
+
+	encoded, err := json.Marshal(slides)
+	if err != nil {
+		return nil, fmt.Errorf("[Room][EncodeSlides] %w", err)
+	}
+	return encoded, nil
+}
+
+// Version returns the number of actions applied since the document was loaded. The
+// pool applies every action, a version it reads doesn't change until it applies another.
+func (s *State) Version() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.version
+}
+
+// Manager holds the states of the documents which have connected clients
+type Manager struct {
+	mu          sync.Mutex
+	states      map[string]*State
+	loading     map[string]*loadCall // documents being loaded, joins wait for the first load
+	repository  *repository.DocumentRepository
+	checkpoints *redis.RedisClient // nil disables checkpoints
+}
+
+// loadCall is a load in progress, done is closed once state or err is set
+type loadCall struct {
+	done  chan struct{}
+	state *State
+	err   error
+}
+
+func NewManager(r *repository.DocumentRepository, checkpoints *redis.RedisClient) *Manager {
+	return &Manager{
+		states:      make(map[string]*State),
+		loading:     make(map[string]*loadCall),
+		repository:  r,
+		checkpoints: checkpoints,
+	}
+}
+
+// Acquire returns the state of the document, loading it on the first join from its
+// checkpoint or the database. The load runs without m.mu held, concurrent joins of
+// the same document wait for it instead of loading it again.
+func (m *Manager) Acquire(ctx context.Context, docId string) (*State, error) {
+	m.mu.Lock()
+	swept := m.sweep()
+
+	if state, ok := m.states[docId]; ok {
+		state.clients++
+		m.mu.Unlock()
+		m.forgetCheckpoints(swept)
+		return state, nil
+	}
+
+	if call, ok := m.loading[docId]; ok {
+		m.mu.Unlock()
+		m.forgetCheckpoints(swept)
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("[Room][Acquire] %w", ctx.Err())
+		}
+		if call.err != nil {
+			return nil, call.err
+		}
+
+		m.mu.Lock()
+		call.state.clients++
+		m.mu.Unlock()
+		return call.state, nil
+	}
+
+	call := &loadCall{done: make(chan struct{})}
+	m.loading[docId] = call
+	m.mu.Unlock()
+	m.forgetCheckpoints(swept)
+
+	doc, err := m.load(ctx, docId)
+	if err != nil {
+		call.err = fmt.Errorf("[Room][Acquire] %w", err)
+	} else {
+		call.state = &State{Document: doc}
+		call.state.buildIndex()
+	}
+
+	m.mu.Lock()
+	delete(m.loading, docId)
+	if call.state != nil {
+		call.state.clients++
+		m.states[docId] = call.state
+	}
+	m.mu.Unlock()
+	close(call.done)
+
+	return call.state, call.err
+}
+
+// Release is called when a client of the document disconnects
+func (m *Manager) Release(docId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.states[docId]
+	if !ok {
+		return
+	}
+	state.clients--
+	if state.clients <= 0 {
+		state.releasedAt = time.Now()
+	}
+}
+
+// Get returns the state of an open document
+func (m *Manager) Get(docId string) (*State, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.states[docId]
+	return state, ok
+}
+
+// sweep drops the states released for longer than releasedStateTTL and returns their
+// documents, m.mu must be held
+func (m *Manager) sweep() []string {
+	var swept []string
+	for docId, state := range m.states {
+		if state.clients <= 0 && time.Since(state.releasedAt) > releasedStateTTL {
+			delete(m.states, docId)
+			swept = append(swept, docId)
+		}
+	}
+	return swept
+}
+
+// forgetCheckpoints deletes the checkpoints of swept documents, m.mu must not be held
+func (m *Manager) forgetCheckpoints(docIds []string) {
+	if m.checkpoints == nil {
+		return
+	}
+
+	for _, docId := range docIds {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		if err := m.checkpoints.DeleteRoomCheckpoint(ctx, docId); err != nil {
+			fmt.Printf("[Room][sweep] %v\n", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,53 @@
+package types
+
+import "fmt"
+
+// Concurrency modes of a document.
+// In "lock" mode (the default) an object has to be locked before it is modified.
+// In "lww" (last writer wins) mode attribute updates are not locked, every update
+// carries the Lamport clock of its sender and conflicting writes to the same
+// attribute are resolved by comparing stamps. Structural operations (batch,
+// reorder, group, ungroup) keep using locks in both modes.
+const (
+	ConcurrencyModeLock = "lock"
+	ConcurrencyModeLWW  = "lww"
+)
+
+// SetConcurrencyModeMessage switches the concurrency mode of the document
+type SetConcurrencyModeMessage struct {
+	Action string `json:"action"` // {'set_concurrency_mode'}
+	Mode   string `json:"mode"`   // {'lock', 'lww'}
+}
+
+// MaxClock keeps clocks exactly representable as JSON numbers
+const MaxClock = 1<<53 - 1
+
+// FormatStamp builds the stamp of an update from the Lamport clock of the sender,
+// ties between equal clocks are broken by user id. Clocks are zero padded so that
+// stamps are ordered by plain string comparison (in redis, the consumer and clients).
+func FormatStamp(clock int64, userId string) string {
+	return fmt.Sprintf("%020d:%s", clock, userId)
+}
+
+func ValidateClock(v interface{}) bool {
+	clock, ok := v.(float64)
+	return ok && clock >= 0 && clock <= MaxClock && clock == float64(int64(clock))
+}
+
+func ValidateSetConcurrencyModeMessage(msg map[string]interface{}) bool {
+	mode, ok := msg["mode"].(string)
+	if !ok {
+		return false
+	}
+
+	return mode == ConcurrencyModeLock || mode == ConcurrencyModeLWW
+}
+
+// ValidateLWWUpdateMessage checks an update sent in lww mode, it must carry the clock
+func ValidateLWWUpdateMessage(msg map[string]interface{}) bool {
+	if !ValidateTransientUpdateMessage(msg) {
+		return false
+	}
+
+	return ValidateClock(msg["clock"])
+}
@@ -0,0 +1,59 @@
+package types
+
+// ConnectorBinding binds one end of a line or arrow ("startBinding" / "endBinding")
+// to another object of the slide. The server moves the end whenever the bound
+// object changes and removes the binding when the object is deleted.
+type ConnectorBinding struct {
+	ObjectID string  `json:"objectId"`
+	Anchor   string  `json:"anchor"`           // side of the bound object's bounding box
+	Offset   float64 `json:"offset,omitempty"` // position along the side, 0 to 1, 0.5 when omitted
+}
+
+var connectorAnchors = map[string]bool{
+	"top":    true,
+	"right":  true,
+	"bottom": true,
+	"left":   true,
+	"center": true,
+}
+
+// ValidateConnectorBinding validates a binding, nil unbinds the end
+func ValidateConnectorBinding(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	binding, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	if objectId, ok := binding["objectId"].(string); !ok || objectId == "" {
+		return false
+	}
+
+	if anchor, ok := binding["anchor"]; ok {
+		if a, ok := anchor.(string); !ok || !connectorAnchors[a] {
+			return false
+		}
+	}
+
+	if offset, ok := binding["offset"]; ok {
+		if o, ok := offset.(float64); !ok || o < 0 || o > 1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateConnectorBindings checks the binding keys of connector attributes or of an update
+func ValidateConnectorBindings(attr map[string]interface{}) bool {
+	for _, key := range []string{"startBinding", "endBinding"} {
+		if binding, ok := attr[key]; ok && !ValidateConnectorBinding(binding) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,95 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextEditMessage carries character level edits of a text object's value.
+// The value is a sequence CRDT (RGA): every character has a unique id
+// "<counter>@<site>" where counter is a Lamport clock of the editing site.
+// Edits are relayed without taking the object lock so concurrent typing merges.
+type TextEditMessage struct {
+	Action   string   `json:"action"` // {'text_edit'}
+	SlideID  string   `json:"slideId"`
+	ObjectID string   `json:"objectId"`
+	Ops      []TextOp `json:"ops"`
+}
+
+type TextOp struct {
+	Type  string `json:"type"`            // {'insert', 'delete'}
+	ID    string `json:"id"`              // id of the (first) inserted or of the deleted character
+	After string `json:"after,omitempty"` // insert: id of the character to insert after, "" for the start
+	Value string `json:"value,omitempty"` // insert: characters, the n-th one gets counter+n
+}
+
+const (
+	MaxTextEditOps      = 1000
+	MaxTextInsertLength = 10000
+)
+
+// ParseCharID splits "<counter>@<site>"
+func ParseCharID(id string) (int64, string, bool) {
+	counter, site, ok := strings.Cut(id, "@")
+	if !ok || site == "" {
+		return 0, "", false
+	}
+	n, err := strconv.ParseInt(counter, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, "", false
+	}
+	return n, site, true
+}
+
+func ValidateTextEditMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["objectId"].(string); !ok {
+		return false
+	}
+
+	ops, ok := msg["ops"].([]interface{})
+	if !ok || len(ops) == 0 || len(ops) > MaxTextEditOps {
+		return false
+	}
+
+	for _, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		id, ok := op["id"].(string)
+		if !ok {
+			return false
+		}
+		if _, _, ok := ParseCharID(id); !ok {
+			return false
+		}
+
+		switch op["type"] {
+		case "insert":
+			after, ok := op["after"].(string)
+			if !ok {
+				return false
+			}
+			if after != "" {
+				if _, _, ok := ParseCharID(after); !ok {
+					return false
+				}
+			}
+			value, ok := op["value"].(string)
+			if !ok || value == "" || utf8.RuneCountInString(value) > MaxTextInsertLength {
+				return false
+			}
+		case "delete":
+		default:
+			return false
+		}
+	}
+
+	return true
+}
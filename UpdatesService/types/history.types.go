@@ -0,0 +1,85 @@
+package types
+
+// HistoryMessage undoes or redoes the last change of the sender's session. The
+// server answers with the op it applied (an update or a restore_objects), which
+// is also broadcast to the room like any other op.
+type HistoryMessage struct {
+	Action string `json:"action"` // {'undo', 'redo'}
+}
+
+// RestoreObjectsMessage is only produced by the server when undoing or redoing a
+// structural change: the top-level objects listed in ObjectIDs are removed from the
+// slide, then every object of Objects is inserted at its index, in ascending order.
+type RestoreObjectsMessage struct {
+	Action    string           `json:"action"` // {'restore_objects'}
+	SlideID   string           `json:"slideId"`
+	ObjectIDs []string         `json:"objectIds"`
+	Objects   []RestoredObject `json:"objects"`
+}
+
+type RestoredObject struct {
+	Index  int                    `json:"index"`
+	Object map[string]interface{} `json:"object"` // id, type, attributes and optionally children and layerId
+}
+
+func ValidateRestoreObjectsMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	objectIds, ok := msg["objectIds"].([]interface{})
+	if !ok {
+		return false
+	}
+	for _, objectId := range objectIds {
+		if _, ok := objectId.(string); !ok {
+			return false
+		}
+	}
+
+	objects, ok := msg["objects"].([]interface{})
+	if !ok || len(objects)+len(objectIds) == 0 {
+		return false
+	}
+	for _, o := range objects {
+		entry, ok := o.(map[string]interface{})
+		if !ok || !isSlideIndex(entry["index"]) {
+			return false
+		}
+		obj, ok := entry["object"].(map[string]interface{})
+		if !ok || !validateRestoredObject(obj) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateRestoredObject(obj map[string]interface{}) bool {
+	if id, ok := obj["id"].(string); !ok || id == "" {
+		return false
+	}
+
+	if _, ok := obj["type"].(string); !ok {
+		return false
+	}
+
+	if _, ok := obj["attributes"].(map[string]interface{}); !ok {
+		return false
+	}
+
+	if children, ok := obj["children"]; ok {
+		list, ok := children.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, c := range list {
+			child, ok := c.(map[string]interface{})
+			if !ok || !validateRestoredObject(child) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,204 @@
+package types
+
+// Layers are named, ordered sets of the objects of a slide. Objects without a
+// layerId are on the implicit base layer. While a layer is locked its objects
+// can't be changed, selected or moved to another layer.
+
+type AddLayerMessage struct {
+	Action  string `json:"action"` // {'add_layer'}
+	SlideID string `json:"slideId"`
+	LayerID string `json:"layerId"`
+	Name    string `json:"name"`
+	Index   *int   `json:"index,omitempty"` // bottom to top position, on top when omitted
+}
+
+type UpdateLayerMessage struct {
+	Action            string                 `json:"action"` // {'update_layer'}
+	SlideID           string                 `json:"slideId"`
+	LayerID           string                 `json:"layerId"`
+	UpdatedProperties map[string]interface{} `json:"updatedProperties"` // name, visible, locked
+}
+
+type RemoveLayerMessage struct {
+	Action  string `json:"action"` // {'remove_layer'}, the objects move to the base layer
+	SlideID string `json:"slideId"`
+	LayerID string `json:"layerId"`
+}
+
+type MoveLayerMessage struct {
+	Action  string `json:"action"` // {'move_layer'}
+	SlideID string `json:"slideId"`
+	LayerID string `json:"layerId"`
+	Index   int    `json:"index"`
+}
+
+// SetLayerMessage moves an object of the slide to another layer
+type SetLayerMessage struct {
+	Action   string `json:"action"` // {'set_layer'}
+	SlideID  string `json:"slideId"`
+	ObjectID string `json:"objectId"`
+	LayerID  string `json:"layerId"` // "" for the base layer
+}
+
+// Frames are containers whose children are stored relative to the frame (x, y),
+// moving a frame moves its contents and clients clip them to its size.
+type FrameAttributes struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Width      float64 `json:"width"`
+	Height     float64 `json:"height"`
+	Name       string  `json:"name,omitempty"`
+	Background string  `json:"background,omitempty"`
+	Clip       *bool   `json:"clip,omitempty"` // true when omitted
+}
+
+// FrameMembersMessage moves objects of the slide into a frame or children of a frame back to the slide
+type FrameMembersMessage struct {
+	Action   string   `json:"action"` // {'add_to_frame', 'remove_from_frame'}
+	SlideID  string   `json:"slideId"`
+	ObjectID string   `json:"objectId"` // id of the frame
+	ChildIDs []string `json:"childIds"`
+}
+
+const MaxLayerNameLength = 256
+
+func ValidateAddLayerMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if layerId, ok := msg["layerId"].(string); !ok || layerId == "" {
+		return false
+	}
+
+	if name, ok := msg["name"].(string); !ok || len(name) > MaxLayerNameLength {
+		return false
+	}
+
+	if index, ok := msg["index"]; ok && !isSlideIndex(index) {
+		return false
+	}
+
+	return true
+}
+
+func ValidateUpdateLayerMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["layerId"].(string); !ok {
+		return false
+	}
+
+	props, ok := msg["updatedProperties"].(map[string]interface{})
+	if !ok || len(props) == 0 {
+		return false
+	}
+
+	for key, value := range props {
+		switch key {
+		case "name":
+			if name, ok := value.(string); !ok || len(name) > MaxLayerNameLength {
+				return false
+			}
+		case "visible", "locked":
+			if _, ok := value.(bool); !ok {
+				return false
+			}
+		default:
+			return false // unknown property
+		}
+	}
+
+	return true
+}
+
+func ValidateRemoveLayerMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["layerId"].(string); !ok {
+		return false
+	}
+
+	return true
+}
+
+func ValidateMoveLayerMessage(msg map[string]interface{}) bool {
+	if !ValidateRemoveLayerMessage(msg) {
+		return false
+	}
+
+	return isSlideIndex(msg["index"])
+}
+
+func ValidateSetLayerMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["objectId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["layerId"].(string); !ok {
+		return false
+	}
+
+	return true
+}
+
+func ValidateFrameAttributes(attr map[string]interface{}) bool {
+	for _, key := range []string{"x", "y", "width", "height"} {
+		if _, ok := attr[key].(float64); !ok {
+			return false
+		}
+	}
+
+	// frames stay axis aligned so their clip rectangle is their bounding box
+	if _, ok := attr["transform"]; ok {
+		return false
+	}
+
+	for _, key := range []string{"name", "background"} {
+		if value, ok := attr[key]; ok {
+			if _, ok := value.(string); !ok {
+				return false
+			}
+		}
+	}
+
+	if clip, ok := attr["clip"]; ok {
+		if _, ok := clip.(bool); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ValidateFrameMembersMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	frameId, ok := msg["objectId"].(string)
+	if !ok {
+		return false
+	}
+
+	childIds, ok := msg["childIds"].([]interface{})
+	if !ok || len(childIds) == 0 {
+		return false
+	}
+
+	for _, childId := range childIds {
+		if id, ok := childId.(string); !ok || id == "" || id == frameId {
+			return false
+		}
+	}
+
+	return true
+}
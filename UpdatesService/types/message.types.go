@@ -6,6 +6,11 @@ type Message struct {
 	Username   string `json:"username"`
 	Type       int    `json:"type"`
 	Body       string `json:"body"`
+	OpID       string `json:"opId,omitempty"` // sent back by the consumer with the result of the op
+
+	// Inverse is the op reverting Body, only set on the copy pushed to kafka
+	// (the consumer stores it in the oplog)
+	Inverse map[string]interface{} `json:"inverse,omitempty"`
 }
 
 // Update Message
@@ -15,6 +20,19 @@ type UpdateMessage struct {
 	SlideID           string                 `json:"slideId"`
 	ObjectType        string                 `json:"objectType"`
 	UpdatedAttributes map[string]interface{} `json:"updatedAttributes"` // only attributes which have changed
+	Clock             int64                  `json:"clock,omitempty"`   // lww mode: Lamport clock of the sender
+	Stamp             string                 `json:"stamp,omitempty"`   // lww mode: set by the server, see FormatStamp
+}
+
+// Transient / Commit update message
+// transient_update is only relayed to peers (live drag/resize preview) and never persisted,
+// commit_update carries the final state of the interaction and is persisted like update.
+type TransientUpdateMessage struct {
+	Action            string                 `json:"action"` // {'transient_update', 'commit_update'}
+	ObjectID          string                 `json:"objectId"`
+	SlideID           string                 `json:"slideId"`
+	ObjectType        string                 `json:"objectType"`
+	UpdatedAttributes map[string]interface{} `json:"updatedAttributes"`
 }
 
 // Delete Message
@@ -32,6 +50,66 @@ type CreateMessage struct {
 	ObjectID   string                 `json:"objectId"`
 	Type       string                 `json:"objectType"`
 	Attributes map[string]interface{} `json:"attributes"`
+	LayerID    string                 `json:"layerId,omitempty"` // base layer when omitted
+}
+
+// Stroke messages, a pen object is streamed while it is being drawn
+// stroke_begin opens the stroke, stroke_append carries point batches
+// and stroke_end closes it so the full pen object can be persisted once.
+type StrokeBeginMessage struct {
+	Action     string                 `json:"action"` // {'stroke_begin'}
+	SlideID    string                 `json:"slideId"`
+	ObjectID   string                 `json:"objectId"`
+	Attributes map[string]interface{} `json:"attributes"` // pen attributes, points may be omitted
+	LayerID    string                 `json:"layerId,omitempty"`
+}
+
+type StrokeAppendMessage struct {
+	Action   string    `json:"action"` // {'stroke_append', 'stroke_end'}
+	SlideID  string    `json:"slideId"`
+	ObjectID string    `json:"objectId"`
+	Points   []float64 `json:"points"` // flat [x0, y0, x1, y1, ...], optional for stroke_end
+}
+
+// StrokeCancelMessage is sent by the server when the client drawing a stroke
+// disconnected before ending it, peers drop the stroke
+type StrokeCancelMessage struct {
+	Action   string `json:"action"` // {'stroke_cancel'}
+	SlideID  string `json:"slideId"`
+	ObjectID string `json:"objectId"`
+}
+
+// Batch message, all ops are applied together or not at all
+type BatchMessage struct {
+	Action string                   `json:"action"` // {'batch'}
+	Ops    []map[string]interface{} `json:"ops"`    // create / update / delete messages
+}
+
+// Reorder message, changes the z-order of an object inside its slide
+type ReorderMessage struct {
+	Action   string `json:"action"` // {'reorder'}
+	SlideID  string `json:"slideId"`
+	ObjectID string `json:"objectId"`
+	Op       string `json:"op"`    // {'bring_forward', 'send_backward', 'to_front', 'to_back', 'move_to'}
+	Index    int    `json:"index"` // target index for 'move_to', 0 is the back
+}
+
+// Group message, the children become members of a new group object.
+// Group attributes hold the group transform (x, y translation), children
+// coordinates are relative to it.
+type GroupMessage struct {
+	Action     string                 `json:"action"` // {'group'}
+	SlideID    string                 `json:"slideId"`
+	ObjectID   string                 `json:"objectId"` // id of the new group
+	ChildIDs   []string               `json:"childIds"`
+	Attributes map[string]interface{} `json:"attributes"` // optional, defaults to {x: 0, y: 0}
+}
+
+// Ungroup message, the group transform is baked into the children
+type UngroupMessage struct {
+	Action   string `json:"action"` // {'ungroup'}
+	SlideID  string `json:"slideId"`
+	ObjectID string `json:"objectId"`
 }
 
 // CursorMove message
@@ -50,8 +128,33 @@ type SelectMessage struct {
 
 // Add slide
 type AddSlide struct {
-	Action  string `json:"action"`
+	Action     string `json:"action"`
+	SlideID    string `json:"slideId"`
+	Index      *int   `json:"index,omitempty"`      // optional position, appended at the end when missing
+	Background string `json:"background,omitempty"` // optional, defaults to #fff
+}
+
+// Update slide properties, only the properties that changed
+type UpdateSlide struct {
+	Action            string                 `json:"action"` // {'update_slide'}
+	SlideID           string                 `json:"slideId"`
+	UpdatedProperties map[string]interface{} `json:"updatedProperties"`
+}
+
+// Move slide
+type MoveSlide struct {
+	Action  string `json:"action"` // {'move_slide'}
 	SlideID string `json:"slideId"`
+	Index   int    `json:"index"`
+}
+
+// Duplicate slide, the copied objects get fresh ids derived from NewSlideID so every
+// client computes them locally: first 24 hex chars of sha1(newSlideId + ":" + objectId)
+type DuplicateSlide struct {
+	Action     string `json:"action"` // {'duplicate_slide'}
+	SlideID    string `json:"slideId"`
+	NewSlideID string `json:"newSlideId"`
+	Index      *int   `json:"index,omitempty"` // defaults to right after the source slide
 }
 
 // Remove slide
@@ -63,10 +166,14 @@ type RemoveSlide struct {
 // ========================================================
 
 type KafkaInterMessage struct {
-	Topic   string
-	Message Message
+	Topic     string
+	Message   Message
+	SessionID string        // session whose history records the op, empty for ops derived by the server
+	Step      string        // room.StepDo, room.StepUndo or room.StepRedo
+	Applied   chan struct{} // closed once applied to the in-memory document, nil when nobody waits
 }
 
 type ServerResponseMessage struct {
-	Success bool `json:"success"` // true for success false for failure
+	Success bool   `json:"success"`        // true for success false for failure
+	OpID    string `json:"opId,omitempty"` // id of the acknowledged op, see op_failed
 }
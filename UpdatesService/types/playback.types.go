@@ -0,0 +1,65 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PlaybackData is what DocumentService returns for a replayed time range
+type PlaybackData struct {
+	Seq       int64           `json:"seq"`
+	Document  json.RawMessage `json:"document"` // state at the start of the range
+	Ops       []PlaybackOp    `json:"ops"`
+	Truncated bool            `json:"truncated"`
+}
+
+type PlaybackOp struct {
+	Seq       int64                  `json:"seq"`
+	UserID    string                 `json:"userId"`
+	Username  string                 `json:"username,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Payload   map[string]interface{} `json:"payload"`
+	Offset    int64                  `json:"offset"` // milliseconds between the start of the range and the op
+}
+
+// Playback websocket messages, the server sends the state once, then every op at
+// its time and finally playback_end. Clients can only send playback controls.
+type PlaybackStateMessage struct {
+	Action   string          `json:"action"` // {'playback_state'}
+	At       time.Time       `json:"at"`
+	Seq      int64           `json:"seq"`
+	Document json.RawMessage `json:"document"`
+}
+
+type PlaybackOpMessage struct {
+	Action    string                 `json:"action"` // {'playback_op'}
+	Seq       int64                  `json:"seq"`
+	UserID    string                 `json:"userId"`
+	Username  string                 `json:"username,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Op        map[string]interface{} `json:"op"`
+}
+
+type PlaybackEndMessage struct {
+	Action    string `json:"action"` // {'playback_end'}
+	Truncated bool   `json:"truncated"`
+}
+
+// PlaybackControlMessage pauses, resumes or changes the speed of a playback
+type PlaybackControlMessage struct {
+	Action string  `json:"action"` // {'playback_pause', 'playback_resume', 'playback_speed'}
+	Speed  float64 `json:"speed,omitempty"`
+}
+
+// MaxPlaybackSpeed matches the limit of DocumentService
+const MaxPlaybackSpeed = 1000
+
+func ValidatePlaybackControlMessage(msg PlaybackControlMessage) bool {
+	switch msg.Action {
+	case "playback_pause", "playback_resume":
+		return true
+	case "playback_speed":
+		return msg.Speed > 0 && msg.Speed <= MaxPlaybackSpeed
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package types
+
+// DocumentResetPostData is sent by DocumentService to restore a version of a document
+type DocumentResetPostData struct {
+	VersionID string        `json:"versionId" binding:"required"`
+	UserID    string        `json:"userId"`
+	Slides    []interface{} `json:"slides" binding:"required"`
+	// BaseVersion is the version of the room the slides were computed from (see
+	// DocumentFlushDto), the reset is refused once the room applied other actions
+	BaseVersion uint64 `json:"baseVersion"`
+}
+
+// DocumentFlushDto is returned once the ops pushed for a document are persisted.
+// Version is the version of its room then, 0 when the document isn't open.
+type DocumentFlushDto struct {
+	Version uint64 `json:"version"`
+}
+
+// DocumentResetMessage replaces every slide of the document. It is only produced by
+// the server when a version is restored and is broadcast to every client of the room.
+type DocumentResetMessage struct {
+	Action     string        `json:"action"` // {'document_reset'}
+	VersionID  string        `json:"versionId"`
+	RestoredBy string        `json:"restoredBy"`
+	Slides     []interface{} `json:"slides"`
+}
@@ -0,0 +1,32 @@
+package types
+
+const (
+	ResultApplied = "applied"
+	ResultFailed  = "failed"
+)
+
+// ResultMessage is published by the consumer for every op it received, Status tells
+// whether it was persisted. Failed ops carry the inverse computed when they were applied.
+type ResultMessage struct {
+	OpID       string                 `json:"opId"`
+	DocumentID string                 `json:"documentId"`
+	UserID     string                 `json:"userId"`
+	Action     string                 `json:"action"`
+	Status     string                 `json:"status"`
+	Seq        int64                  `json:"seq,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Inverse    map[string]interface{} `json:"inverse,omitempty"`
+}
+
+// OpFailedMessage is sent to the client which sent an op that couldn't be persisted.
+// Inverse is set when the server reverted the op, the client reverts it by applying
+// Inverse as the other clients of the room do. Without it a later op changed the
+// objects of the failed one and is kept.
+type OpFailedMessage struct {
+	Action   string                 `json:"action"` // {'op_failed'}
+	OpID     string                 `json:"opId"`
+	UserID   string                 `json:"userId"`
+	OpAction string                 `json:"opAction"`
+	Error    string                 `json:"error"`
+	Inverse  map[string]interface{} `json:"inverse,omitempty"`
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	MaxRichTextParagraphs = 1000
+	MaxRichTextLength     = 100000 // characters over all spans
+	MaxRichTextFontSize   = 400
+	MaxRichTextIndent     = 8
+)
+
+// ParseRichText decodes a richText attribute, unknown fields are rejected
+func ParseRichText(value interface{}) (*RichText, bool) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	var richText RichText
+	if err := decoder.Decode(&richText); err != nil {
+		return nil, false
+	}
+
+	return &richText, true
+}
+
+func ValidateRichText(value interface{}) bool {
+	richText, ok := ParseRichText(value)
+	if !ok || len(richText.Paragraphs) > MaxRichTextParagraphs {
+		return false
+	}
+
+	length := 0
+	for _, p := range richText.Paragraphs {
+		switch p.Align {
+		case "", "left", "center", "right", "justify":
+		default:
+			return false
+		}
+
+		switch p.List {
+		case "", "bullet", "ordered":
+		default:
+			return false
+		}
+
+		if p.Indent < 0 || p.Indent > MaxRichTextIndent {
+			return false
+		}
+
+		for _, span := range p.Spans {
+			// paragraphs are the only line breaks
+			if strings.ContainsRune(span.Text, '\n') {
+				return false
+			}
+			if span.Size < 0 || span.Size > MaxRichTextFontSize {
+				return false
+			}
+			length += len([]rune(span.Text))
+		}
+	}
+
+	return length <= MaxRichTextLength
+}
@@ -0,0 +1,19 @@
+package types
+
+const (
+	SaveSaving = "saving"
+	SaveSaved  = "saved"
+	SaveError  = "error"
+)
+
+// SaveStatusMessage tells a client whether its changes are stored. Seq is the highest
+// oplog seq the consumer acknowledged for the session, DocumentSeq the one for the
+// whole document. Pending counts the ops of the session still on their way.
+type SaveStatusMessage struct {
+	Action      string `json:"action"` // {'save_status'}
+	Status      string `json:"status"` // {'saving', 'saved', 'error'}
+	Seq         int64  `json:"seq"`
+	DocumentSeq int64  `json:"documentSeq"`
+	Pending     int    `json:"pending"`
+	Error       string `json:"error,omitempty"`
+}
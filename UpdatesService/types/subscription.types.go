@@ -0,0 +1,51 @@
+package types
+
+// Clients declare the slides they are viewing. Object ops of other slides are not
+// sent to them, they get a slide_changed hint instead so that thumbnails can be
+// refreshed. Changes to the slide list itself are sent to everyone.
+
+// ViewSlidesMessage replaces the slides the sender is subscribed to, a null list
+// subscribes to every slide again (the default)
+type ViewSlidesMessage struct {
+	Action   string   `json:"action"` // {'view_slides'}
+	SlideIDs []string `json:"slideIds"`
+}
+
+// SlidesStateMessage answers view_slides with the current state of the slides the
+// client starts viewing, the changes made to them meanwhile were not sent to it
+type SlidesStateMessage struct {
+	Action string      `json:"action"` // {'slides_state'}
+	Slides interface{} `json:"slides"`
+}
+
+// SlideChangedMessage tells a client that persisted changes were made to slides it is not viewing
+type SlideChangedMessage struct {
+	Action   string   `json:"action"` // {'slide_changed'}
+	SlideIDs []string `json:"slideIds"`
+}
+
+// MaxViewedSlides caps the subscription list of a single client
+const MaxViewedSlides = 1000
+
+func ValidateViewSlidesMessage(msg map[string]interface{}) bool {
+	slideIds, ok := msg["slideIds"]
+	if !ok {
+		return false
+	}
+	if slideIds == nil {
+		return true
+	}
+
+	list, ok := slideIds.([]interface{})
+	if !ok || len(list) > MaxViewedSlides {
+		return false
+	}
+
+	for _, slideId := range list {
+		if _, ok := slideId.(string); !ok {
+			return false
+		}
+	}
+
+	return true
+}
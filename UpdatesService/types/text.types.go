@@ -10,7 +10,32 @@ type TextAttributes struct {
 	BoxWidth  float64 `json:"width"`
 	BoxHeight float64 `json:"height"`
 	// Padding            int     `json:"padding"`
-	BorderStrokeWidth  int    `json:"strokeWidth"`
-	BorderStrokeColor  string `json:"strokeColor"`
-	BorderFillColorHex string `json:"fillColor"`
+	BorderStrokeWidth  int       `json:"strokeWidth"`
+	BorderStrokeColor  string    `json:"strokeColor"`
+	BorderFillColorHex string    `json:"fillColor"`
+	RichText           *RichText `json:"richText,omitempty"` // when set, value holds its plain text
+}
+
+// RichText is the structured content of a text object
+type RichText struct {
+	Paragraphs []Paragraph `json:"paragraphs"`
+}
+
+type Paragraph struct {
+	Align  string     `json:"align,omitempty"`  // {'left', 'center', 'right', 'justify'}
+	List   string     `json:"list,omitempty"`   // {'bullet', 'ordered'}, empty for plain paragraphs
+	Indent int        `json:"indent,omitempty"` // list nesting level
+	Spans  []TextSpan `json:"spans"`
+}
+
+// TextSpan is a run of text sharing the same style, unset styles inherit the
+// text object defaults (textColor, fontWidth, font)
+type TextSpan struct {
+	Text      string  `json:"text"`
+	Bold      bool    `json:"bold,omitempty"`
+	Italic    bool    `json:"italic,omitempty"`
+	Underline bool    `json:"underline,omitempty"`
+	Color     string  `json:"color,omitempty"`
+	Size      float64 `json:"size,omitempty"`
+	Font      string  `json:"font,omitempty"`
 }
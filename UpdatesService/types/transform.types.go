@@ -0,0 +1,76 @@
+package types
+
+import (
+	"math"
+	"strings"
+)
+
+// TransformAttributes is the optional "transform" block every object type accepts.
+// The transform is applied around the center of the object's bounding box:
+// scale and flip first, then the rotation.
+type TransformAttributes struct {
+	Rotation float64 `json:"rotation"` // degrees, clockwise
+	Opacity  float64 `json:"opacity"`  // 0 (invisible) to 1
+	ScaleX   float64 `json:"scaleX"`
+	ScaleY   float64 `json:"scaleY"`
+	FlipX    bool    `json:"flipX"`
+	FlipY    bool    `json:"flipY"`
+}
+
+// MaxTransformScale caps scaleX / scaleY
+const MaxTransformScale = 100
+
+// ValidateTransformValue validates a single transform key
+func ValidateTransformValue(key string, value interface{}) bool {
+	switch key {
+	case "rotation":
+		n, ok := value.(float64)
+		return ok && !math.IsNaN(n) && !math.IsInf(n, 0)
+	case "opacity":
+		n, ok := value.(float64)
+		return ok && n >= 0 && n <= 1
+	case "scaleX", "scaleY":
+		n, ok := value.(float64)
+		return ok && n > 0 && n <= MaxTransformScale
+	case "flipX", "flipY":
+		_, ok := value.(bool)
+		return ok
+	}
+	return false
+}
+
+func ValidateTransform(value interface{}) bool {
+	transform, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for key, v := range transform {
+		if !ValidateTransformValue(key, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateUpdatedTransform checks the transform keys of updatedAttributes, either the
+// whole block ("transform") or a single value ("transform.rotation")
+func ValidateUpdatedTransform(updated map[string]interface{}) bool {
+	for key, value := range updated {
+		if key == "transform" {
+			if !ValidateTransform(value) {
+				return false
+			}
+			continue
+		}
+
+		if sub, ok := strings.CutPrefix(key, "transform."); ok {
+			if !ValidateTransformValue(sub, value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -9,7 +9,12 @@ func ValidateTextAttributes(attr map[string]interface{}) bool {
 		return false
 	}
 
-	if _, ok := attr["value"]; !ok {
+	// rich text replaces the plain value
+	if richText, ok := attr["richText"]; ok {
+		if !ValidateRichText(richText) {
+			return false
+		}
+	} else if _, ok := attr["value"]; !ok {
 		return false
 	}
 
@@ -126,6 +131,100 @@ func ValidatePenAttributes(attr map[string]interface{}) bool {
 	return true
 }
 
+// MaxStrokePoints caps the number of coordinates a single streamed stroke may hold
+const MaxStrokePoints = 20000
+
+// ValidateStrokePoints checks a batch of streamed points (flat list of x/y pairs)
+func ValidateStrokePoints(points []interface{}) bool {
+	if len(points)%2 != 0 {
+		return false
+	}
+
+	for _, v := range points {
+		if _, ok := v.(float64); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ValidateStrokeBeginMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["objectId"].(string); !ok {
+		return false
+	}
+
+	if layerId, ok := msg["layerId"]; ok {
+		if _, ok := layerId.(string); !ok {
+			return false
+		}
+	}
+
+	attr, ok := msg["attributes"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	// the stroke may start without any points
+	points, ok := attr["points"]
+	if !ok {
+		points = []interface{}{}
+	}
+	pointList, ok := points.([]interface{})
+	if !ok || !ValidateStrokePoints(pointList) {
+		return false
+	}
+
+	penAttr := make(map[string]interface{}, len(attr)+1)
+	for k, v := range attr {
+		penAttr[k] = v
+	}
+	penAttr["points"] = pointList
+
+	return ValidatePenAttributes(penAttr)
+}
+
+func ValidateStrokeAppendMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["objectId"].(string); !ok {
+		return false
+	}
+
+	points, ok := msg["points"].([]interface{})
+	if !ok {
+		return false
+	}
+
+	return ValidateStrokePoints(points)
+}
+
+func ValidateStrokeEndMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["objectId"].(string); !ok {
+		return false
+	}
+
+	// trailing points are optional
+	if points, ok := msg["points"]; ok {
+		pointList, ok := points.([]interface{})
+		if !ok || !ValidateStrokePoints(pointList) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func ValidateLineAttributes(attr map[string]interface{}) bool {
 	// Line/Arrow behaves like a bounding box in your frontend logic (x,y,width,height)
 	if _, ok := attr["x"]; !ok {
@@ -146,14 +245,51 @@ func ValidateLineAttributes(attr map[string]interface{}) bool {
 	if _, ok := attr["strokeColor"]; !ok {
 		return false
 	}
+	// the ends may be bound to other objects
+	if !ValidateConnectorBindings(attr) {
+		return false
+	}
 	return true
 }
 
+// ValidateObjectAttributes validates the attributes of a new object of the given type
+func ValidateObjectAttributes(objectType string, attr map[string]interface{}) bool {
+	// every object type may carry a transform block
+	if transform, ok := attr["transform"]; ok && !ValidateTransform(transform) {
+		return false
+	}
+
+	switch objectType {
+	case "rectangle":
+		return ValidateRectangleAttributes(attr)
+	case "circle":
+		return ValidateCircleAttributes(attr)
+	case "text":
+		return ValidateTextAttributes(attr)
+	case "pen":
+		return ValidatePenAttributes(attr)
+	case "line", "arrow":
+		return ValidateLineAttributes(attr)
+	case "frame":
+		return ValidateFrameAttributes(attr)
+	case "image":
+		return true
+	}
+	return false
+}
+
 func ValidateCreateMessage(msg map[string]interface{}) bool {
 	if _, ok := msg["objectType"]; !ok {
 		return false
 	}
 
+	// optional, the base layer when omitted
+	if layerId, ok := msg["layerId"]; ok {
+		if _, ok := layerId.(string); !ok {
+			return false
+		}
+	}
+
 	if _, ok := msg["attributes"]; !ok {
 		return false
 	}
@@ -198,6 +334,113 @@ func ValidateUpdateMessage(msg map[string]interface{}) bool {
 		return false
 	}
 
+	if updated, ok := msg["updatedAttributes"].(map[string]interface{}); ok && !ValidateUpdatedAttributes(updated) {
+		return false
+	}
+
+	return true
+}
+
+func ValidateTransientUpdateMessage(msg map[string]interface{}) bool {
+	if !ValidateUpdateMessage(msg) {
+		return false
+	}
+
+	if _, ok := msg["updatedAttributes"].(map[string]interface{}); !ok {
+		return false
+	}
+
+	return true
+}
+
+func ValidateReorderMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["objectId"].(string); !ok {
+		return false
+	}
+
+	op, ok := msg["op"].(string)
+	if !ok {
+		return false
+	}
+
+	switch op {
+	case "bring_forward", "send_backward", "to_front", "to_back":
+		return true
+	case "move_to":
+		index, ok := msg["index"].(float64)
+		return ok && index >= 0 && index == float64(int(index))
+	}
+
+	return false
+}
+
+func ValidateGroupMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	groupId, ok := msg["objectId"].(string)
+	if !ok {
+		return false
+	}
+
+	childIds, ok := msg["childIds"].([]interface{})
+	if !ok || len(childIds) < 2 {
+		return false
+	}
+
+	seen := make(map[string]bool, len(childIds))
+	for _, c := range childIds {
+		childId, ok := c.(string)
+		if !ok || childId == groupId || seen[childId] {
+			return false
+		}
+		seen[childId] = true
+	}
+
+	if attr, ok := msg["attributes"]; ok {
+		attrMap, ok := attr.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if transform, ok := attrMap["transform"]; ok && !ValidateTransform(transform) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ValidateUngroupMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := msg["objectId"].(string); !ok {
+		return false
+	}
+
+	return true
+}
+
+// ValidateUpdatedAttributes checks the structured attributes (transform, richText, bindings) of an update
+func ValidateUpdatedAttributes(updated map[string]interface{}) bool {
+	if !ValidateUpdatedTransform(updated) {
+		return false
+	}
+
+	if !ValidateConnectorBindings(updated) {
+		return false
+	}
+
+	if richText, ok := updated["richText"]; ok && !ValidateRichText(richText) {
+		return false
+	}
+
 	return true
 }
 
@@ -231,9 +474,98 @@ func ValidateAddSlideMessage(msg map[string]interface{}) bool {
 		return false
 	}
 
+	if index, ok := msg["index"]; ok && !isSlideIndex(index) {
+		return false
+	}
+
+	if background, ok := msg["background"]; ok {
+		if _, ok := background.(string); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// slideStringProperties / slideSizeProperties are the slide properties an update_slide may change
+var slideStringProperties = map[string]int{
+	"background":      64,   // color
+	"backgroundImage": 2048, // asset id or url, "" clears it
+	"title":           512,
+	"notes":           65536, // speaker notes
+}
+
+var slideSizeProperties = map[string]bool{
+	"width":  true,
+	"height": true,
+}
+
+// MaxSlideSize caps custom slide dimensions (px)
+const MaxSlideSize = 20000
+
+func ValidateUpdateSlideMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	props, ok := msg["updatedProperties"].(map[string]interface{})
+	if !ok || len(props) == 0 {
+		return false
+	}
+
+	for key, value := range props {
+		if maxLen, ok := slideStringProperties[key]; ok {
+			str, ok := value.(string)
+			if !ok || len(str) > maxLen {
+				return false
+			}
+			continue
+		}
+
+		if slideSizeProperties[key] {
+			size, ok := value.(float64)
+			if !ok || size <= 0 || size > MaxSlideSize {
+				return false
+			}
+			continue
+		}
+
+		return false // unknown property
+	}
+
+	return true
+}
+
+func ValidateMoveSlideMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	return isSlideIndex(msg["index"])
+}
+
+func ValidateDuplicateSlideMessage(msg map[string]interface{}) bool {
+	if _, ok := msg["slideId"].(string); !ok {
+		return false
+	}
+
+	if newSlideId, ok := msg["newSlideId"].(string); !ok || newSlideId == "" {
+		return false
+	}
+
+	if index, ok := msg["index"]; ok && !isSlideIndex(index) {
+		return false
+	}
+
 	return true
 }
 
+// isSlideIndex checks for a non negative integer json number
+func isSlideIndex(v interface{}) bool {
+	index, ok := v.(float64)
+	return ok && index >= 0 && index == float64(int(index))
+}
+
 func ValidateRemoveSlideMessage(msg map[string]interface{}) bool {
 	if _, ok := msg["slideId"]; !ok {
 		return false
@@ -241,3 +573,60 @@ func ValidateRemoveSlideMessage(msg map[string]interface{}) bool {
 
 	return true
 }
+
+// MaxBatchOps caps the number of operations a single batch may carry
+const MaxBatchOps = 500
+
+// ValidateBatchOp validates one create/update/delete operation contained in a batch
+func ValidateBatchOp(op map[string]interface{}) bool {
+	if _, ok := op["objectId"].(string); !ok {
+		return false
+	}
+
+	if _, ok := op["slideId"].(string); !ok {
+		return false
+	}
+
+	action, _ := op["action"].(string)
+	switch action {
+	case "create":
+		if !ValidateCreateMessage(op) {
+			return false
+		}
+		objectType, ok := op["objectType"].(string)
+		if !ok {
+			return false
+		}
+		attr, ok := op["attributes"].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		return ValidateObjectAttributes(objectType, attr)
+	case "update":
+		if !ValidateUpdateMessage(op) {
+			return false
+		}
+		_, ok := op["updatedAttributes"].(map[string]interface{})
+		return ok
+	case "delete":
+		return ValidateDeleteMessage(op)
+	}
+
+	return false
+}
+
+func ValidateBatchMessage(msg map[string]interface{}) bool {
+	ops, ok := msg["ops"].([]interface{})
+	if !ok || len(ops) == 0 || len(ops) > MaxBatchOps {
+		return false
+	}
+
+	for _, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok || !ValidateBatchOp(op) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,52 @@
+package types
+
+// Whiteboards are documents with a single unbounded slide. Clients tell the server
+// which part of the canvas they show, they are sent the objects of that region and
+// only receive the changes which intersect it.
+
+type ViewportRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ViewportMessage sets the viewport of the sender (set_viewport) or asks for the
+// objects of a region without changing it (viewport_query)
+type ViewportMessage struct {
+	Action string       `json:"action"` // {'set_viewport', 'viewport_query'}
+	Rect   ViewportRect `json:"rect"`
+}
+
+// ViewportObjectsMessage is the answer to both viewport messages, sent to the sender only
+type ViewportObjectsMessage struct {
+	Action  string       `json:"action"` // {'viewport_objects'}
+	SlideID string       `json:"slideId"`
+	Rect    ViewportRect `json:"rect"`
+	Objects interface{}  `json:"objects"`
+}
+
+// MaxViewportSize keeps a single query from returning a whole huge board by accident
+const MaxViewportSize = 1e6
+
+func ValidateViewportMessage(msg map[string]interface{}) bool {
+	rect, ok := msg["rect"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, key := range []string{"x", "y"} {
+		if _, ok := rect[key].(float64); !ok {
+			return false
+		}
+	}
+
+	for _, key := range []string{"width", "height"} {
+		size, ok := rect[key].(float64)
+		if !ok || size < 0 || size > MaxViewportSize {
+			return false
+		}
+	}
+
+	return true
+}
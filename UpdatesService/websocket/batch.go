@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"UpdatesService/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HandleBatch lock-checks every object of an already validated batch before anything
+// is broadcast. If one lock is not free the locks taken for this batch are released
+// and nothing is applied, otherwise the batch is broadcast and persisted as one message.
+// In lww mode no lock is taken, see handleLWWBatch.
+func (c *Client) HandleBatch(msg map[string]interface{}, outMsg types.Message) error {
+	if c.ConcurrencyMode() == types.ConcurrencyModeLWW {
+		return c.handleLWWBatch(msg, outMsg)
+	}
+
+	ops := msg["ops"].([]interface{})
+
+	objectIds := make([]string, 0, len(ops))
+	for _, o := range ops {
+		objectIds = append(objectIds, o.(map[string]interface{})["objectId"].(string))
+	}
+	if err := c.acquireLocks(objectIds); err != nil {
+		return err
+	}
+
+	// one frame for the room and one kafka message for the consumer
+	c.BroadcastAndPushToKafka(outMsg)
+	return nil
+}
+
+// handleLWWBatch stamps the updates of the batch like HandleLWWUpdate, an update which
+// lost every attribute to newer ones is dropped. Deleted objects forget their stamps.
+func (c *Client) handleLWWBatch(msg map[string]interface{}, outMsg types.Message) error {
+	ops := msg["ops"].([]interface{})
+	for _, o := range ops {
+		op := o.(map[string]interface{})
+		if op["action"] == "update" && !types.ValidateLWWUpdateMessage(op) {
+			return fmt.Errorf("[Client][HandleBatch][Error] invalid lww update in batch")
+		}
+	}
+
+	kept := make([]interface{}, 0, len(ops))
+	var deleted []string
+	for _, o := range ops {
+		op := o.(map[string]interface{})
+		switch op["action"] {
+		case "update":
+			stamped, err := c.stampUpdate(op)
+			if err != nil {
+				return err
+			}
+			if !stamped {
+				continue
+			}
+		case "delete":
+			deleted = append(deleted, op["objectId"].(string))
+		}
+		kept = append(kept, op)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+
+	msg["ops"] = kept
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("[Client][HandleBatch][Error] failure to marshal batch: %w", err)
+	}
+	outMsg.Body = string(body)
+	c.BroadcastAndPushToKafka(outMsg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	for _, objectId := range deleted {
+		if err := c.RedisClient.ForgetAttributeStamps(ctx, objectId); err != nil {
+			fmt.Printf("[Client][HandleBatch] %v\n", err)
+		}
+	}
+	return nil
+}
+
+// acquireLocks takes the locks of all the objects or none of them
+func (c *Client) acquireLocks(objectIds []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	acquired := make([]string, 0, len(objectIds))
+	seen := make(map[string]bool, len(objectIds))
+	for _, objectId := range objectIds {
+		// members of a group share the lock of the group
+		lockId := c.lockTarget(objectId)
+		if seen[lockId] {
+			continue
+		}
+		seen[lockId] = true
+
+		isNew, err := c.RedisClient.AcquireLock(ctx, lockId, c.UserID, 10*time.Minute)
+		if err != nil {
+			c.releaseLocks(acquired)
+			return fmt.Errorf("[Error] Lock is not free")
+		}
+		if isNew {
+			acquired = append(acquired, lockId)
+		}
+	}
+	return nil
+}
+
+// releaseLocks rolls back locks taken during a failed multi-object operation
+func (c *Client) releaseLocks(lockIds []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	for _, lockId := range lockIds {
+		if _, err := c.RedisClient.ReleaseLock(ctx, lockId); err != nil {
+			fmt.Printf("[Client][releaseLocks] %v\n", err)
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package websocket
 
 import (
+	"Shared/geometry"
 	"UpdatesService/redis"
+	"UpdatesService/repository"
 	"UpdatesService/types"
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,15 +17,23 @@ import (
 type Client struct {
 	UserID      string
 	Username    string
+	SessionID   string // identifies the connection, undo / redo histories are kept per session
 	DocumentID  string
 	Conn        *websocket.Conn
 	Pool        *Pool
 	Send        chan []byte
 	RedisClient *redis.RedisClient
+	Documents   *repository.DocumentRepository // access checks of ops only owners and editors may send
+	Strokes     map[string]*Stroke             // pen strokes being streamed by this client, keyed by objectId
+	viewMu      sync.Mutex                     // guards viewport and slides
+	viewport    *geometry.Rect                 // visible region of a whiteboard, nil until set_viewport
+	slides      map[string]bool                // slides the client views, nil until view_slides
+	opID        string                         // id of the op being handled, echoed in the ack
 }
 
 func (c *Client) Read() {
 	defer func() {
+		c.CancelStrokes()
 		c.Pool.Unregister <- c
 		c.Conn.Close()
 	}()
@@ -78,14 +89,14 @@ func (c *Client) Writer() {
 
 			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				fmt.Println("[Client Writer] Failed to send message")
-				return 
+				return
 			}
 
-		case <-ticker.C: 
+		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				fmt.Println("[Client Writer] PING fails")
-				return 
+				return
 			}
 		}
 	}
@@ -111,12 +122,29 @@ func (c *Client) HandleMessage(p []byte) error {
 		return fmt.Errorf("[Error] action key is not a string")
 	}
 
+	c.opID = opIDOf(msg)
 	outMsg := types.Message{
 		DocumentID: c.DocumentID,
 		Username:   c.Username,
 		UserID:     c.UserID,
 		Type:       1,
 		Body:       string(p),
+		OpID:       c.opID,
+	}
+
+	// objects on locked layers can't be changed
+	if err := c.CheckLayerLock(actionStr, msg); err != nil {
+		return err
+	}
+
+	// a whiteboard keeps its single slide
+	if err := c.CheckWhiteboardAction(actionStr); err != nil {
+		return err
+	}
+
+	// ops on slides or objects which are gone never reach the room
+	if err := c.CheckReferences(msg); err != nil {
+		return err
 	}
 
 	switch actionStr {
@@ -133,7 +161,7 @@ func (c *Client) HandleMessage(p []byte) error {
 				return fmt.Errorf("[Client][HandleMessage][Error] attributes missing")
 
 			}
-			objectType, ok := msg["objectType"]
+			objectType, ok := msg["objectType"].(string)
 			if !ok {
 				return fmt.Errorf("[Client][HandleMessage][Error] objectType missing")
 			}
@@ -142,26 +170,11 @@ func (c *Client) HandleMessage(p []byte) error {
 				return fmt.Errorf("[Client][HandleMessage][Error] objectId missing")
 			}
 
-			isValid := false
-
-			switch objectType {
-			case "rectangle":
-				isValid = types.ValidateRectangleAttributes(attr)
-			case "circle":
-				isValid = types.ValidateCircleAttributes(attr)
-			case "text":
-				isValid = types.ValidateTextAttributes(attr)
-			case "pen":
-				isValid = types.ValidatePenAttributes(attr)
-			case "line", "arrow":
-				isValid = types.ValidateLineAttributes(attr)
-			case "image":
-				isValid = true 
-			default:
-				fmt.Printf("[HandleMessage] Unknown object type: %s\n", objectType)
-			}
+			isValid := types.ValidateObjectAttributes(objectType, attr)
 
-			if isValid {
+			if isValid && c.ConcurrencyMode() == types.ConcurrencyModeLWW {
+				c.BroadcastAndPushToKafka(outMsg)
+			} else if isValid {
 				if err := c.CheckLockAndBroadcastAndPushToKafka(outMsg, objectId); err != nil {
 					return err
 				}
@@ -172,6 +185,13 @@ func (c *Client) HandleMessage(p []byte) error {
 		}
 
 	case "update":
+		if c.ConcurrencyMode() == types.ConcurrencyModeLWW {
+			if !types.ValidateLWWUpdateMessage(msg) {
+				return fmt.Errorf("[Client][HandleMessage][Error] invalid lww update")
+			}
+			return c.HandleLWWUpdate(msg, outMsg)
+		}
+
 		if types.ValidateUpdateMessage(msg) {
 			objectId, ok := msg["objectId"].(string)
 			if !ok {
@@ -182,6 +202,77 @@ func (c *Client) HandleMessage(p []byte) error {
 				return err
 			}
 		}
+	case "transient_update":
+		if types.ValidateTransientUpdateMessage(msg) {
+			objectId, ok := msg["objectId"].(string)
+			if !ok {
+				return fmt.Errorf("[Client][HandleMessage][Error] objectId missing")
+			}
+
+			// preview only, peers render it but it never reaches kafka
+			if c.ConcurrencyMode() == types.ConcurrencyModeLWW {
+				c.Broadcast(outMsg)
+			} else if err := c.HoldLockAndBroadcast(outMsg, objectId); err != nil {
+				return err
+			}
+		}
+	case "commit_update":
+		if c.ConcurrencyMode() == types.ConcurrencyModeLWW {
+			if !types.ValidateLWWUpdateMessage(msg) {
+				return fmt.Errorf("[Client][HandleMessage][Error] invalid lww update")
+			}
+			return c.HandleLWWUpdate(msg, outMsg)
+		}
+
+		if types.ValidateTransientUpdateMessage(msg) {
+			objectId, ok := msg["objectId"].(string)
+			if !ok {
+				return fmt.Errorf("[Client][HandleMessage][Error] objectId missing")
+			}
+
+			if err := c.HoldLockAndBroadcastAndPushToKafka(outMsg, objectId); err != nil {
+				return err
+			}
+		}
+	case "stroke_begin":
+		if types.ValidateStrokeBeginMessage(msg) {
+			if err := c.StrokeBegin(msg, outMsg); err != nil {
+				return err
+			}
+		}
+	case "stroke_append":
+		if types.ValidateStrokeAppendMessage(msg) {
+			if err := c.StrokeAppend(msg, outMsg); err != nil {
+				return err
+			}
+		}
+	case "stroke_end":
+		if types.ValidateStrokeEndMessage(msg) {
+			if err := c.StrokeEnd(msg, outMsg); err != nil {
+				return err
+			}
+		}
+	case "batch":
+		if types.ValidateBatchMessage(msg) {
+			if err := c.HandleBatch(msg, outMsg); err != nil {
+				return err
+			}
+		} else {
+			return fmt.Errorf("[Client][HandleMessage][Error] invalid batch")
+		}
+	case "reorder":
+		if types.ValidateReorderMessage(msg) {
+			objectId := msg["objectId"].(string)
+
+			if err := c.HoldLockAndBroadcastAndPushToKafka(outMsg, objectId); err != nil {
+				return err
+			}
+		}
+	case "text_edit":
+		// no exclusive lock, concurrent character edits are merged by the text CRDT
+		if types.ValidateTextEditMessage(msg) {
+			c.BroadcastAndPushToKafka(outMsg)
+		}
 	case "delete":
 		if types.ValidateDeleteMessage(msg) {
 			objectId, ok := msg["objectId"].(string)
@@ -189,10 +280,60 @@ func (c *Client) HandleMessage(p []byte) error {
 				return fmt.Errorf("[Client][HandleMessage][Error] objectId missing")
 			}
 
-			if err := c.CheckLockAndBroadcastAndPushToKafka(outMsg, objectId); err != nil {
+			if c.ConcurrencyMode() == types.ConcurrencyModeLWW {
+				c.BroadcastAndPushToKafka(outMsg)
+			} else if err := c.CheckLockAndBroadcastAndPushToKafka(outMsg, objectId); err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if err := c.RedisClient.ForgetAttributeStamps(ctx, objectId); err != nil {
+				fmt.Printf("[Client][HandleMessage] %v\n", err)
+			}
+		}
+	case "group":
+		if types.ValidateGroupMessage(msg) {
+			if err := c.HandleGroup(msg, outMsg); err != nil {
 				return err
 			}
 		}
+	case "ungroup":
+		if types.ValidateUngroupMessage(msg) {
+			if err := c.HandleUngroup(msg, outMsg); err != nil {
+				return err
+			}
+		}
+	case "add_to_frame", "remove_from_frame":
+		if types.ValidateFrameMembersMessage(msg) {
+			if err := c.HandleFrameMembers(msg, outMsg); err != nil {
+				return err
+			}
+		}
+	case "set_layer":
+		if types.ValidateSetLayerMessage(msg) {
+			objectId := msg["objectId"].(string)
+
+			if err := c.HoldLockAndBroadcastAndPushToKafka(outMsg, objectId); err != nil {
+				return err
+			}
+		}
+	case "add_layer":
+		if types.ValidateAddLayerMessage(msg) {
+			c.BroadcastAndPushToKafka(outMsg)
+		}
+	case "update_layer":
+		if types.ValidateUpdateLayerMessage(msg) {
+			c.BroadcastAndPushToKafka(outMsg)
+		}
+	case "remove_layer":
+		if types.ValidateRemoveLayerMessage(msg) {
+			c.BroadcastAndPushToKafka(outMsg)
+		}
+	case "move_layer":
+		if types.ValidateMoveLayerMessage(msg) {
+			c.BroadcastAndPushToKafka(outMsg)
+		}
 	case "select":
 		if types.ValidateSelectMessage(msg) {
 			objectId, ok := msg["objectId"].(string)
@@ -200,7 +341,10 @@ func (c *Client) HandleMessage(p []byte) error {
 				return fmt.Errorf("[Client][HandleMessage][Error] objectId missing")
 			}
 
-			if err := c.CheckLockAndBroadcast(outMsg, objectId); err != nil {
+			// without locks a selection is only shown to the others
+			if c.ConcurrencyMode() == types.ConcurrencyModeLWW {
+				c.Broadcast(outMsg)
+			} else if err := c.CheckLockAndBroadcast(outMsg, objectId); err != nil {
 				return err
 			}
 		}
@@ -211,9 +355,15 @@ func (c *Client) HandleMessage(p []byte) error {
 				return fmt.Errorf("[Client][HandleMessage][Error] objectId missing")
 			}
 
+			if c.ConcurrencyMode() == types.ConcurrencyModeLWW {
+				c.Broadcast(outMsg)
+				return nil
+			}
+
 			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 			defer cancel()
-			anyKeyDeleted, err := c.RedisClient.ReleaseLock(ctx, objectId)
+			lockId := c.lockTarget(objectId)
+			anyKeyDeleted, err := c.RedisClient.ReleaseLock(ctx, lockId)
 			if err != nil {
 				return err
 			}
@@ -224,6 +374,28 @@ func (c *Client) HandleMessage(p []byte) error {
 			}
 
 		}
+	case "set_concurrency_mode":
+		if types.ValidateSetConcurrencyModeMessage(msg) {
+			if err := c.SetConcurrencyMode(msg, outMsg); err != nil {
+				return err
+			}
+		}
+	case "undo", "redo":
+		if err := c.HandleHistory(actionStr, outMsg); err != nil {
+			return err
+		}
+	case "view_slides":
+		if types.ValidateViewSlidesMessage(msg) {
+			if err := c.SetViewedSlides(p); err != nil {
+				return err
+			}
+		}
+	case "set_viewport", "viewport_query":
+		if types.ValidateViewportMessage(msg) {
+			if err := c.HandleViewport(actionStr, p); err != nil {
+				return err
+			}
+		}
 	case "add_slide":
 		if types.ValidateAddSlideMessage(msg) {
 			c.BroadcastAndPushToKafka(outMsg)
@@ -232,6 +404,18 @@ func (c *Client) HandleMessage(p []byte) error {
 		if types.ValidateRemoveSlideMessage(msg) {
 			c.BroadcastAndPushToKafka(outMsg)
 		}
+	case "update_slide":
+		if types.ValidateUpdateSlideMessage(msg) {
+			c.BroadcastAndPushToKafka(outMsg)
+		}
+	case "move_slide":
+		if types.ValidateMoveSlideMessage(msg) {
+			c.BroadcastAndPushToKafka(outMsg)
+		}
+	case "duplicate_slide":
+		if types.ValidateDuplicateSlideMessage(msg) {
+			c.BroadcastAndPushToKafka(outMsg)
+		}
 	default:
 		// c.Send <- []byte("[Error] Invalid m essage format")
 		return fmt.Errorf("[Client][HandleMessage][Error] Invalid message format received")
@@ -246,7 +430,10 @@ func (c *Client) CheckLockAndBroadcast(outMsg types.Message, objectId string) er
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
 
-	if err := c.RedisClient.SetExclusiveLock(ctx, objectId, outMsg.UserID, 10*time.Minute); err != nil {
+	// members of a group share the lock of the group
+	lockId := c.lockTarget(objectId)
+
+	if err := c.RedisClient.SetExclusiveLock(ctx, lockId, outMsg.UserID, 10*time.Minute); err != nil {
 		// The lock is not free
 		return err
 	}
@@ -263,30 +450,73 @@ func (c *Client) CheckLockAndBroadcastAndPushToKafka(outMsg types.Message, objec
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
 
-	if err := c.RedisClient.SetExclusiveLock(ctx, objectId, outMsg.UserID, 10*time.Minute); err != nil {
+	// members of a group share the lock of the group
+	lockId := c.lockTarget(objectId)
+
+	if err := c.RedisClient.SetExclusiveLock(ctx, lockId, outMsg.UserID, 10*time.Minute); err != nil {
 		// The lock is not free
 		return fmt.Errorf("[Error] Lock is not free")
 	}
+	fmt.Printf("Message Received: %+v\n", outMsg)
+
+	// broadcast message to everyone in the room and push to kafka
+	kafkaMessage := types.KafkaInterMessage{Topic: "document-updates", Message: outMsg, SessionID: c.SessionID}
+	c.publish(c.Pool.Publish, kafkaMessage)
+
+	return nil
+}
+
+// HoldLockAndBroadcast is used for high frequency messages of a single interaction,
+// the lock is taken on the first message and only refreshed by the following ones.
+func (c *Client) HoldLockAndBroadcast(outMsg types.Message, objectId string) error {
+	if err := c.holdLock(outMsg.UserID, objectId); err != nil {
+		return err
+	}
 
-	// broadcast message to everyone in the room
 	c.Pool.RoomBroadcast <- outMsg
+	return nil
+}
+
+func (c *Client) HoldLockAndBroadcastAndPushToKafka(outMsg types.Message, objectId string) error {
+	if err := c.holdLock(outMsg.UserID, objectId); err != nil {
+		return err
+	}
 	fmt.Printf("Message Received: %+v\n", outMsg)
 
-	// push to kafka
-	kafkaMessage := types.KafkaInterMessage{Topic: "document-updates", Message: outMsg}
-	c.Pool.PushToKafka <- kafkaMessage
+	// broadcast message to everyone in the room and push to kafka
+	kafkaMessage := types.KafkaInterMessage{Topic: "document-updates", Message: outMsg, SessionID: c.SessionID}
+	c.publish(c.Pool.Publish, kafkaMessage)
 
 	return nil
 }
 
+// holdLock takes the lock of objectId (of its group for members) or confirms userId already holds it
+func (c *Client) holdLock(userId string, objectId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	lockId := c.lockTarget(objectId)
+
+	if _, err := c.RedisClient.AcquireLock(ctx, lockId, userId, 10*time.Minute); err != nil {
+		return fmt.Errorf("[Error] Lock is not free")
+	}
+	return nil
+}
+
 func (c *Client) BroadcastAndPushToKafka(outMsg types.Message) {
-	// broadcast message to everyone in the room
-	c.Pool.RoomBroadcast <- outMsg
 	fmt.Printf("Message Received: %+v\n", outMsg)
 
-	// push to kafka
-	kafkaMessage := types.KafkaInterMessage{Topic: "document-updates", Message: outMsg}
-	c.Pool.PushToKafka <- kafkaMessage
+	// broadcast message to everyone in the room and push to kafka
+	kafkaMessage := types.KafkaInterMessage{Topic: "document-updates", Message: outMsg, SessionID: c.SessionID}
+	c.publish(c.Pool.Publish, kafkaMessage)
+}
+
+// publish hands the op to the pool and waits until it is applied to the in-memory
+// document, so the next op of the client is checked against a state holding it
+func (c *Client) publish(ch chan types.KafkaInterMessage, message types.KafkaInterMessage) {
+	message.Applied = make(chan struct{})
+	ch <- message
+	<-message.Applied
 }
 
 func (c *Client) Broadcast(outMsg types.Message) {
@@ -298,7 +528,7 @@ func (c *Client) Broadcast(outMsg types.Message) {
 }
 
 func (c *Client) FailureResponseMessage() error {
-	msg := types.ServerResponseMessage{Success: false}
+	msg := types.ServerResponseMessage{Success: false, OpID: c.opID}
 	jsonBytes, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("[Error] failure to marshal server response message")
@@ -308,7 +538,7 @@ func (c *Client) FailureResponseMessage() error {
 }
 
 func (c *Client) SuccessResponseMessage() error {
-	msg := types.ServerResponseMessage{Success: true}
+	msg := types.ServerResponseMessage{Success: true, OpID: c.opID}
 	jsonBytes, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("[Error] failure to marshal server response message")
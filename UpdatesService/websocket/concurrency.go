@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"UpdatesService/repository"
+	"UpdatesService/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ConcurrencyMode returns the concurrency mode of the client's document, read from
+// the in-memory document. Documents fall back to locking when no mode was set.
+func (c *Client) ConcurrencyMode() string {
+	state, ok := c.Pool.Documents.Get(c.DocumentID)
+	if !ok {
+		return types.ConcurrencyModeLock
+	}
+	if mode := state.ConcurrencyMode(); mode != "" {
+		return mode
+	}
+	return types.ConcurrencyModeLock
+}
+
+// SetConcurrencyMode switches the mode, the room applies it before the next op of the
+// client is handled and the consumer persists it on the document. Only the owner and
+// editors of the document may switch it.
+func (c *Client) SetConcurrencyMode(msg map[string]interface{}, outMsg types.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	access, err := c.Documents.FindAccessType(ctx, c.UserID, c.DocumentID)
+	if err != nil {
+		return err
+	}
+	if access != repository.AccessOwner && access != repository.AccessEditor {
+		return fmt.Errorf("[Client][SetConcurrencyMode][Error] user %s may not change the concurrency mode", c.UserID)
+	}
+
+	c.BroadcastAndPushToKafka(outMsg)
+	return nil
+}
+
+// HandleLWWUpdate applies an update in lww mode. The update is stamped with the
+// sender's clock, only the attributes for which it is the newest write are kept,
+// and the trimmed update is broadcast with its stamp so every replica (peers and
+// the consumer) can resolve concurrent writes the same way.
+func (c *Client) HandleLWWUpdate(msg map[string]interface{}, outMsg types.Message) error {
+	kept, err := c.stampUpdate(msg)
+	if err != nil {
+		return err
+	}
+	// every attribute has already been overwritten by a newer update
+	if !kept {
+		return nil
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("[Client][HandleLWWUpdate][Error] failure to marshal update: %w", err)
+	}
+	outMsg.Body = string(body)
+
+	c.BroadcastAndPushToKafka(outMsg)
+	return nil
+}
+
+// stampUpdate stamps a validated lww update with the sender's clock and trims it to the
+// attributes it wins. It returns false when a newer update overwrote every attribute.
+func (c *Client) stampUpdate(msg map[string]interface{}) (bool, error) {
+	objectId := msg["objectId"].(string)
+	updated := msg["updatedAttributes"].(map[string]interface{})
+	stamp := types.FormatStamp(int64(msg["clock"].(float64)), c.UserID)
+
+	attributes := make([]string, 0, len(updated))
+	for attribute := range updated {
+		attributes = append(attributes, attribute)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	accepted, err := c.RedisClient.MergeAttributeStamps(ctx, objectId, stamp, attributes)
+	if err != nil {
+		return false, err
+	}
+	if len(accepted) == 0 {
+		return false, nil
+	}
+
+	winning := make(map[string]interface{}, len(accepted))
+	for _, attribute := range accepted {
+		winning[attribute] = updated[attribute]
+	}
+	msg["updatedAttributes"] = winning
+	msg["stamp"] = stamp
+	return true, nil
+}
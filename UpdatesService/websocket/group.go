@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"UpdatesService/types"
+	"context"
+	"fmt"
+	"time"
+)
+
+// HandleGroup locks every child (and the new group id) before the group is created.
+// Once the children are members their locks are covered by the group lock.
+func (c *Client) HandleGroup(msg map[string]interface{}, outMsg types.Message) error {
+	groupId := msg["objectId"].(string)
+	rawChildIds := msg["childIds"].([]interface{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	childIds := make([]string, 0, len(rawChildIds))
+	lockIds := make([]string, 0, len(rawChildIds)+1)
+	for _, rawChildId := range rawChildIds {
+		childId := rawChildId.(string)
+
+		lockId := c.lockTarget(childId)
+		// only objects at the same level can be grouped, members of another group are not
+		if lockId != childId {
+			return fmt.Errorf("[Client][HandleGroup][Error] %s already belongs to group %s", childId, lockId)
+		}
+
+		childIds = append(childIds, childId)
+		lockIds = append(lockIds, childId)
+	}
+	lockIds = append(lockIds, groupId)
+
+	acquired := make([]string, 0, len(lockIds))
+	for _, lockId := range lockIds {
+		isNew, err := c.RedisClient.AcquireLock(ctx, lockId, c.UserID, 10*time.Minute)
+		if err != nil {
+			c.releaseLocks(acquired)
+			return fmt.Errorf("[Error] Lock is not free")
+		}
+		if isNew {
+			acquired = append(acquired, lockId)
+		}
+	}
+
+	// once applied the group lock guards the children, see Client.lockTarget
+	c.BroadcastAndPushToKafka(outMsg)
+	c.releaseLocks(childIds)
+	return nil
+}
+
+// HandleUngroup dissolves a group, its children keep being guarded by the group's parent (if any)
+func (c *Client) HandleUngroup(msg map[string]interface{}, outMsg types.Message) error {
+	groupId := msg["objectId"].(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	lockId := c.lockTarget(groupId)
+
+	if _, err := c.RedisClient.AcquireLock(ctx, lockId, c.UserID, 10*time.Minute); err != nil {
+		return fmt.Errorf("[Error] Lock is not free")
+	}
+
+	c.BroadcastAndPushToKafka(outMsg)
+	if lockId == groupId {
+		c.releaseLocks([]string{groupId})
+	}
+	return nil
+}
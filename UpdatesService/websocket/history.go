@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"UpdatesService/room"
+	"UpdatesService/types"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// NewSessionID returns a random id for a new connection
+func NewSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("[NewSessionID] %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// HandleHistory undoes or redoes the last change of the session. The reverting op
+// is validated and lock-checked like an op sent by the client, when it can't be
+// applied the step stays in the history.
+func (c *Client) HandleHistory(actionStr string, outMsg types.Message) error {
+	state, ok := c.Pool.Documents.Get(c.DocumentID)
+	if !ok {
+		return fmt.Errorf("[Client][HandleHistory] document %s is not open", c.DocumentID)
+	}
+
+	var step *room.Step
+	var err error
+	if actionStr == "undo" {
+		step, err = state.Undo(c.SessionID)
+	} else {
+		step, err = state.Redo(c.SessionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	body, msg, err := c.prepareHistoryOp(step.Op)
+	if err != nil {
+		state.Requeue(c.SessionID, step)
+		return err
+	}
+
+	outMsg.Body = string(body)
+	c.publish(c.Pool.Publish, types.KafkaInterMessage{
+		Topic:     "document-updates",
+		Message:   outMsg,
+		SessionID: c.SessionID,
+		Step:      step.Kind,
+	})
+
+	// the room gets the op like any other, the sender needs it as well
+	serialized, err := SerializeMessage(outMsg)
+	if err != nil {
+		return err
+	}
+	c.Send <- serialized
+
+	fmt.Printf("[Client][HandleHistory] %s applied %v\n", actionStr, msg["action"])
+	return nil
+}
+
+// prepareHistoryOp validates the op reverting a step and takes the locks of its objects,
+// in lww mode an update is stamped instead
+func (c *Client) prepareHistoryOp(op map[string]interface{}) ([]byte, map[string]interface{}, error) {
+	body, err := json.Marshal(op)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[Client][HandleHistory] %w", err)
+	}
+
+	// validated in its json form, as if a client had sent it
+	var msg map[string]interface{}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, nil, fmt.Errorf("[Client][HandleHistory] %w", err)
+	}
+
+	actionStr, _ := msg["action"].(string)
+	var objectIds []string
+	switch actionStr {
+	case "update":
+		// null restores an attribute the object didn't have, only the values are validated
+		validated := make(map[string]interface{}, len(msg))
+		for key, value := range msg {
+			validated[key] = value
+		}
+		updated, _ := msg["updatedAttributes"].(map[string]interface{})
+		values := make(map[string]interface{}, len(updated))
+		for key, value := range updated {
+			if value != nil {
+				values[key] = value
+			}
+		}
+		validated["updatedAttributes"] = values
+
+		objectId, ok := msg["objectId"].(string)
+		if !ok || !types.ValidateUpdateMessage(validated) {
+			return nil, nil, fmt.Errorf("[Client][HandleHistory] invalid update")
+		}
+		objectIds = append(objectIds, objectId)
+
+	case "restore_objects":
+		if !types.ValidateRestoreObjectsMessage(msg) {
+			return nil, nil, fmt.Errorf("[Client][HandleHistory] invalid restore_objects")
+		}
+		for _, objectId := range msg["objectIds"].([]interface{}) {
+			objectIds = append(objectIds, objectId.(string))
+		}
+		for _, o := range msg["objects"].([]interface{}) {
+			obj := o.(map[string]interface{})["object"].(map[string]interface{})
+			objectIds = append(objectIds, obj["id"].(string))
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("[Client][HandleHistory] unsupported op %q", actionStr)
+	}
+
+	if err := c.CheckLayerLock(actionStr, msg); err != nil {
+		return nil, nil, err
+	}
+
+	// in lww mode objects are not locked, updates are stamped to win over the writes they revert
+	if c.ConcurrencyMode() == types.ConcurrencyModeLock {
+		if err := c.acquireLocks(objectIds); err != nil {
+			return nil, nil, err
+		}
+	} else if actionStr == "update" {
+		if body, err = c.stampHistoryUpdate(msg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return body, msg, nil
+}
+
+// stampHistoryUpdate gives an undone or redone update a stamp newer than the ones of
+// its attributes and returns it encoded
+func (c *Client) stampHistoryUpdate(msg map[string]interface{}) ([]byte, error) {
+	updated, _ := msg["updatedAttributes"].(map[string]interface{})
+	attributes := make([]string, 0, len(updated))
+	for attribute := range updated {
+		attributes = append(attributes, attribute)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	stamp, err := c.RedisClient.NewAttributeStamp(ctx, msg["objectId"].(string), c.UserID, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("[Client][HandleHistory] %w", err)
+	}
+	msg["stamp"] = stamp
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("[Client][HandleHistory] %w", err)
+	}
+	return body, nil
+}
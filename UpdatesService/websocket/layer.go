@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"UpdatesService/types"
+	"context"
+	"fmt"
+	"time"
+)
+
+// layerCheckedActions change objects, they are refused on objects of locked layers
+var layerCheckedActions = map[string]bool{
+	"create":            true,
+	"update":            true,
+	"transient_update":  true,
+	"commit_update":     true,
+	"delete":            true,
+	"reorder":           true,
+	"text_edit":         true,
+	"stroke_begin":      true,
+	"select":            true,
+	"group":             true,
+	"ungroup":           true,
+	"add_to_frame":      true,
+	"remove_from_frame": true,
+	"set_layer":         true,
+	"batch":             true,
+	"restore_objects":   true,
+}
+
+// CheckLayerLock refuses an action touching an object of a locked layer, or adding one to it
+func (c *Client) CheckLayerLock(actionStr string, msg map[string]interface{}) error {
+	if !layerCheckedActions[actionStr] {
+		return nil
+	}
+
+	state, ok := c.Pool.Documents.Get(c.DocumentID)
+	if !ok {
+		return nil
+	}
+
+	if actionStr == "batch" {
+		ops, _ := msg["ops"].([]interface{})
+		for _, o := range ops {
+			if op, ok := o.(map[string]interface{}); ok {
+				if err := c.CheckLayerLock("create", op); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	slideId, _ := msg["slideId"].(string)
+
+	if layerId, ok := msg["layerId"].(string); ok && state.LayerLocked(slideId, layerId) {
+		return fmt.Errorf("[Client][CheckLayerLock][Error] layer %s is locked", layerId)
+	}
+
+	objectIds := []interface{}{msg["objectId"]}
+	if childIds, ok := msg["childIds"].([]interface{}); ok {
+		objectIds = append(objectIds, childIds...)
+	}
+	if restoredIds, ok := msg["objectIds"].([]interface{}); ok {
+		objectIds = append(objectIds, restoredIds...)
+	}
+	if objects, ok := msg["objects"].([]interface{}); ok {
+		for _, o := range objects {
+			entry, _ := o.(map[string]interface{})
+			obj, _ := entry["object"].(map[string]interface{})
+			if layerId, ok := obj["layerId"].(string); ok && state.LayerLocked(slideId, layerId) {
+				return fmt.Errorf("[Client][CheckLayerLock][Error] layer %s is locked", layerId)
+			}
+		}
+	}
+	for _, rawObjectId := range objectIds {
+		objectId, ok := rawObjectId.(string)
+		if ok && state.ObjectLayerLocked(slideId, objectId) {
+			return fmt.Errorf("[Client][CheckLayerLock][Error] %s is on a locked layer", objectId)
+		}
+	}
+
+	return nil
+}
+
+// HandleFrameMembers locks the frame and the objects moved into or out of it
+func (c *Client) HandleFrameMembers(msg map[string]interface{}, outMsg types.Message) error {
+	frameId := msg["objectId"].(string)
+	childIds := msg["childIds"].([]interface{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	lockIds := make([]string, 0, len(childIds)+1)
+	lockIds = append(lockIds, frameId)
+	for _, rawChildId := range childIds {
+		childId := rawChildId.(string)
+
+		lockId := c.lockTarget(childId)
+		// members of a group move with their group
+		if lockId != childId {
+			return fmt.Errorf("[Client][HandleFrameMembers][Error] %s belongs to group %s", childId, lockId)
+		}
+		lockIds = append(lockIds, childId)
+	}
+
+	acquired := make([]string, 0, len(lockIds))
+	for _, lockId := range lockIds {
+		isNew, err := c.RedisClient.AcquireLock(ctx, lockId, c.UserID, 10*time.Minute)
+		if err != nil {
+			c.releaseLocks(acquired)
+			return fmt.Errorf("[Error] Lock is not free")
+		}
+		if isNew {
+			acquired = append(acquired, lockId)
+		}
+	}
+
+	c.BroadcastAndPushToKafka(outMsg)
+	return nil
+}
@@ -0,0 +1,12 @@
+package websocket
+
+// lockTarget returns the id whose lock guards objectId: members of a group share the
+// lock of their top-most group. Membership comes from the in-memory document, so it
+// follows deletes, restores and merges like the rest of the room.
+func (c *Client) lockTarget(objectId string) string {
+	state, ok := c.Pool.Documents.Get(c.DocumentID)
+	if !ok {
+		return objectId
+	}
+	return state.LockTarget(objectId)
+}
@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"UpdatesService/types"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// MaxPlaybackIdle caps the real time a player waits between two ops, so quiet
+// hours of a range don't stall the playback
+const MaxPlaybackIdle = 5 * time.Second
+
+// Player streams a recorded range of a document to one read-only connection. It
+// doesn't join the pool: playback clients neither send ops nor receive live ones.
+type Player struct {
+	Conn  *websocket.Conn
+	From  time.Time
+	Speed float64
+	Data  types.PlaybackData
+}
+
+// Play sends the state at the start of the range, then every op once its offset is
+// reached at the current speed, and closes the connection at the end
+func (p *Player) Play() {
+	defer p.Conn.Close()
+
+	controls := make(chan types.PlaybackControlMessage)
+	stop := make(chan struct{})
+	defer close(stop)
+	closed := make(chan struct{})
+	go p.readControls(controls, stop, closed)
+
+	err := p.Conn.WriteJSON(types.PlaybackStateMessage{Action: "playback_state", At: p.From, Seq: p.Data.Seq, Document: p.Data.Document})
+	if err != nil {
+		fmt.Println("[Player][Play] Error sending state:", err)
+		return
+	}
+
+	var position time.Duration // playback position relative to From
+	paused := false
+	for _, op := range p.Data.Ops {
+		target := time.Duration(op.Offset) * time.Millisecond
+		for paused || position < target {
+			var timeout <-chan time.Time
+			var timer *time.Timer
+			started := time.Now()
+			if !paused {
+				wait := time.Duration(float64(target-position) / p.Speed)
+				if wait > MaxPlaybackIdle {
+					wait = MaxPlaybackIdle
+					position = target - time.Duration(float64(wait)*p.Speed)
+				}
+				timer = time.NewTimer(wait)
+				timeout = timer.C
+			}
+
+			select {
+			case <-timeout:
+				position = target
+			case ctl := <-controls:
+				if timer != nil {
+					timer.Stop()
+					position += time.Duration(float64(time.Since(started)) * p.Speed)
+				}
+				switch ctl.Action {
+				case "playback_pause":
+					paused = true
+				case "playback_resume":
+					paused = false
+				case "playback_speed":
+					p.Speed = ctl.Speed
+				}
+			case <-closed:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+		}
+
+		err := p.Conn.WriteJSON(types.PlaybackOpMessage{
+			Action:    "playback_op",
+			Seq:       op.Seq,
+			UserID:    op.UserID,
+			Username:  op.Username,
+			Timestamp: op.Timestamp,
+			Op:        op.Payload,
+		})
+		if err != nil {
+			fmt.Println("[Player][Play] Error sending op:", err)
+			return
+		}
+	}
+
+	if err := p.Conn.WriteJSON(types.PlaybackEndMessage{Action: "playback_end", Truncated: p.Data.Truncated}); err != nil {
+		fmt.Println("[Player][Play] Error sending end:", err)
+	}
+}
+
+// readControls forwards valid control messages until the connection closes
+func (p *Player) readControls(controls chan<- types.PlaybackControlMessage, stop <-chan struct{}, closed chan<- struct{}) {
+	defer close(closed)
+	for {
+		_, data, err := p.Conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg types.PlaybackControlMessage
+		if err := json.Unmarshal(data, &msg); err != nil || !types.ValidatePlaybackControlMessage(msg) {
+			fmt.Println("[Player][readControls] Invalid control message")
+			continue
+		}
+
+		select {
+		case controls <- msg:
+		case <-stop:
+			return
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package websocket
 
 import (
+	"Shared/geometry"
 	"UpdatesService/kafkaUtils"
+	"UpdatesService/room"
 	"UpdatesService/types"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
@@ -14,11 +18,17 @@ type Pool struct {
 	Unregister    chan *Client
 	RoomBroadcast chan types.Message
 	PushToKafka   chan types.KafkaInterMessage
+	Publish       chan types.KafkaInterMessage // broadcast once applied to the document, then push to kafka
 	Rooms         map[string]map[*Client]bool
 	KafkaProducer *kafka.Producer
+	Documents     *room.Manager            // in-memory state of the open documents
+	Results       chan types.ResultMessage // what the consumer did with the ops
+	Resets        chan ResetRequest        // published unless the room moved on, see reset
+	Flushes       chan FlushRequest        // answered once the ops of the document are persisted
+	saves         *saveTracker             // save status of the sessions
 }
 
-func NewPool(p *kafka.Producer) *Pool {
+func NewPool(p *kafka.Producer, documents *room.Manager) *Pool {
 	return &Pool{
 		Register:      make(chan *Client),
 		Unregister:    make(chan *Client),
@@ -26,6 +36,12 @@ func NewPool(p *kafka.Producer) *Pool {
 		Rooms:         make(map[string]map[*Client]bool),
 		KafkaProducer: p,
 		PushToKafka:   make(chan types.KafkaInterMessage),
+		Publish:       make(chan types.KafkaInterMessage),
+		Documents:     documents,
+		Results:       make(chan types.ResultMessage),
+		Resets:        make(chan ResetRequest),
+		Flushes:       make(chan FlushRequest),
+		saves:         newSaveTracker(),
 	}
 }
 
@@ -38,6 +54,9 @@ func SerializeMessage(message types.Message) ([]byte, error) {
 }
 
 func (pool *Pool) Start() types.Message {
+	saveCheck := time.NewTicker(SaveCheckInterval)
+	defer saveCheck.Stop()
+
 	for {
 		select {
 		case client := <-pool.Register:
@@ -66,10 +85,18 @@ func (pool *Pool) Start() types.Message {
 				fmt.Println("[Pool][Register] Sending new user joined message")
 				client.Send <- message
 			}
+
+			// nothing pending yet, tells the seq the document is saved up to
+			pool.sendSaveStatus(client.SessionID, pool.saves.session(client.DocumentID, client.SessionID))
 			fmt.Println("Client registered")
 
 		case client := <-pool.Unregister:
 			delete(pool.Rooms[client.DocumentID], client)
+			pool.forgetSaves(client)
+			if state, ok := pool.Documents.Get(client.DocumentID); ok {
+				state.ForgetSession(client.SessionID)
+			}
+			pool.Documents.Release(client.DocumentID)
 			for c := range pool.Rooms[client.DocumentID] {
 				message, err := json.Marshal(types.Message{
 					DocumentID: c.DocumentID,
@@ -88,36 +115,196 @@ func (pool *Pool) Start() types.Message {
 			}
 
 		case message := <-pool.RoomBroadcast:
-			fmt.Printf("Broadcasting to room -> ")
-			for client := range pool.Rooms[message.DocumentID] {
-				if client.UserID == message.UserID {
-					continue
-				}
+			pool.broadcast(message, pool.regionOf(message))
 
-				// Convert message (struct) to []byte
-				jsonData, err := json.Marshal(message)
-				if err != nil {
-					fmt.Println("[Pool][RoomBroadcast] json Marshalling error")
-					break
-				}
+		case message := <-pool.PushToKafka:
+			_, inverse, followUps := pool.applyToDocument(message)
+			markApplied(message)
+			pool.hintSlideChanged(message.Message)
+			message.Message.Inverse = inverse
+			pool.produce(message)
+			pool.produceFollowUps(followUps)
 
-				client.Send <- jsonData
-			}
+		case message := <-pool.Publish:
+			pool.publish(message)
 
-			fmt.Println("Broadcasted!")
+		case request := <-pool.Resets:
+			request.Done <- pool.reset(request)
 
-		case message := <-pool.PushToKafka:
-			fmt.Println("[Pool][PushToKafka] Pushing message to kafka!")
-			serialized, err := SerializeMessage(message.Message)
-			if err != nil {
-				fmt.Println("[Pool][PushToKafka]", err)
-				break
-			}
-			err = kafkaUtils.ProduceMessage(pool.KafkaProducer, message.Topic, serialized)
-			if err != nil {
-				fmt.Println("[Pool][PushToKafka] Error pushing message to kafka: ", err)
-			}
+		case request := <-pool.Flushes:
+			pool.flush(request)
+
+		case result := <-pool.Results:
+			pool.relayResult(result)
+			pool.trackResult(result)
+
+		case now := <-saveCheck.C:
+			pool.checkSaves(now)
+		}
+
+	}
+}
+
+// publish applies the message to the document, broadcasts it and pushes it to kafka.
+// On whiteboards the region is only known once the change is applied.
+func (pool *Pool) publish(message types.KafkaInterMessage) {
+	region, inverse, followUps := pool.applyToDocument(message)
+	markApplied(message)
+	pool.broadcast(message.Message, region)
+	pool.hintSlideChanged(message.Message)
+	message.Message.Inverse = inverse
+	pool.produce(message)
+	pool.produceFollowUps(followUps)
+}
+
+// ErrDocumentChanged refuses a reset computed from a version of the room which is gone
+var ErrDocumentChanged = errors.New("the document changed since its version was read")
+
+// ResetRequest replaces the slides of a document, see reset
+type ResetRequest struct {
+	Message     types.KafkaInterMessage
+	BaseVersion uint64     // version of the room the slides were computed from
+	Done        chan error // buffered, receives the outcome
+}
+
+// reset publishes the reset unless the room applied actions since BaseVersion, they
+// would be overwritten. Actions are only applied by the pool goroutine, the room
+// can't move on between the check and the reset.
+func (pool *Pool) reset(request ResetRequest) error {
+	var version uint64
+	if state, ok := pool.Documents.Get(request.Message.Message.DocumentID); ok {
+		version = state.Version()
+	}
+	if version != request.BaseVersion {
+		return ErrDocumentChanged
+	}
+
+	pool.publish(request.Message)
+	return nil
+}
+
+// followUp is an op derived by the server, with the whiteboard region it affects
+type followUp struct {
+	types.KafkaInterMessage
+	Inverse map[string]interface{}
+	Region  *geometry.Rect
+}
+
+// broadcast sends the message to the clients of the room viewing its slides. When
+// region is set (whiteboards), clients whose viewport doesn't intersect it are skipped.
+func (pool *Pool) broadcast(message types.Message, region *geometry.Rect) {
+	fmt.Printf("Broadcasting to room -> ")
+	slideIds := messageSlides(message.Body)
+	for client := range pool.Rooms[message.DocumentID] {
+		if client.UserID == message.UserID {
+			continue
+		}
+
+		if slideIds != nil && !client.ViewsSlides(slideIds) {
+			continue
+		}
+
+		if region != nil && !client.InViewport(*region) {
+			continue
+		}
+
+		// Convert message (struct) to []byte
+		jsonData, err := json.Marshal(message)
+		if err != nil {
+			fmt.Println("[Pool][RoomBroadcast] json Marshalling error")
+			break
 		}
 
+		client.Send <- jsonData
+	}
+
+	fmt.Println("Broadcasted!")
+}
+
+func (pool *Pool) produce(message types.KafkaInterMessage) {
+	fmt.Println("[Pool][PushToKafka] Pushing message to kafka!")
+	serialized, err := SerializeMessage(message.Message)
+	if err != nil {
+		fmt.Println("[Pool][PushToKafka]", err)
+		return
+	}
+	err = kafkaUtils.ProduceMessage(pool.KafkaProducer, message.Topic, []byte(message.Message.DocumentID), serialized)
+	if err != nil {
+		fmt.Println("[Pool][PushToKafka] Error pushing message to kafka: ", err)
+		pool.trackSaveError(message, err)
+		return
+	}
+	pool.trackSaving(message)
+}
+
+// produceFollowUps sends the derived ops to every client including the sender, they come from the server
+func (pool *Pool) produceFollowUps(followUps []followUp) {
+	for _, f := range followUps {
+		pool.broadcast(f.Message, f.Region)
+		pool.hintSlideChanged(f.Message)
+		f.Message.Inverse = f.Inverse
+		pool.produce(f.KafkaInterMessage)
+	}
+}
+
+// regionOf returns the whiteboard region a broadcast-only message concerns, nil for everyone
+func (pool *Pool) regionOf(message types.Message) *geometry.Rect {
+	state, ok := pool.Documents.Get(message.DocumentID)
+	if !ok {
+		return nil
+	}
+
+	var action map[string]interface{}
+	if err := json.Unmarshal([]byte(message.Body), &action); err != nil {
+		return nil
+	}
+	return state.Region(action)
+}
+
+// applyToDocument keeps the in-memory document in sync with what is sent to the
+// consumer. It returns the whiteboard region the message affected, the op reverting
+// it and wraps the follow-up actions into messages.
+func (pool *Pool) applyToDocument(message types.KafkaInterMessage) (*geometry.Rect, map[string]interface{}, []followUp) {
+	state, ok := pool.Documents.Get(message.Message.DocumentID)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	var action map[string]interface{}
+	if err := json.Unmarshal([]byte(message.Message.Body), &action); err != nil {
+		fmt.Println("[Pool][applyToDocument]", err)
+		return nil, nil, nil
+	}
+
+	result := state.Apply(action, message.SessionID, message.Step)
+
+	var followUps []followUp
+	for _, change := range result.FollowUps {
+		body, err := json.Marshal(change.Action)
+		if err != nil {
+			fmt.Println("[Pool][applyToDocument]", err)
+			continue
+		}
+		followUps = append(followUps, followUp{
+			KafkaInterMessage: types.KafkaInterMessage{
+				Topic: message.Topic,
+				Message: types.Message{
+					DocumentID: message.Message.DocumentID,
+					Type:       1,
+					Body:       string(body),
+					OpID:       NewOpID(),
+				},
+			},
+			Inverse: change.Inverse,
+			Region:  change.Region,
+		})
+	}
+	return result.Region, result.Inverse, followUps
+}
+
+// markApplied wakes up the client waiting for the message to be applied, see Client.publish
+func markApplied(message types.KafkaInterMessage) {
+	if message.Applied != nil {
+		close(message.Applied)
 	}
 }
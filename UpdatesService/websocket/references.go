@@ -0,0 +1,17 @@
+package websocket
+
+import "fmt"
+
+// CheckReferences refuses an op on a slide, object or layer missing from the in-memory
+// document of the room, see room.State.Check
+func (c *Client) CheckReferences(msg map[string]interface{}) error {
+	state, ok := c.Pool.Documents.Get(c.DocumentID)
+	if !ok {
+		return nil
+	}
+
+	if err := state.Check(msg); err != nil {
+		return fmt.Errorf("[Client][CheckReferences][Error] %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package websocket
+
+import "UpdatesService/types"
+
+// MaxOpIDLength bounds the op ids chosen by clients
+const MaxOpIDLength = 64
+
+// NewOpID returns a random id for an op the client didn't name
+func NewOpID() string {
+	return NewSessionID()
+}
+
+// opIDOf returns the opId sent with the message, or a new one when it is missing or invalid
+func opIDOf(msg map[string]interface{}) string {
+	if opId, ok := msg["opId"].(string); ok && opId != "" && len(opId) <= MaxOpIDLength {
+		return opId
+	}
+	return NewOpID()
+}
+
+// relayResult handles a result published by the consumer. The seq of an applied op
+// is recorded on the open document. An op which couldn't be persisted has already
+// been broadcast: it is reverted in the open document unless a later op changed its
+// objects, the other clients receive the inverse and the client which sent the op is
+// told it failed. It reads the pending op, so it runs before trackResult.
+func (pool *Pool) relayResult(result types.ResultMessage) {
+	if result.Status == types.ResultApplied {
+		if state, ok := pool.Documents.Get(result.DocumentID); ok {
+			state.Acknowledge(result.Seq)
+		}
+		return
+	}
+	if result.Status != types.ResultFailed {
+		return
+	}
+
+	op, ok := pool.saves.pending[result.OpID]
+	if !ok {
+		return
+	}
+
+	reverted := false
+	if state, ok := pool.Documents.Get(result.DocumentID); ok && result.Inverse != nil {
+		reverted = state.Revert(result.Inverse, op.Version)
+	}
+
+	failed := types.OpFailedMessage{
+		Action:   "op_failed",
+		OpID:     result.OpID,
+		UserID:   result.UserID,
+		OpAction: result.Action,
+		Error:    result.Error,
+	}
+	if reverted {
+		failed.Inverse = result.Inverse
+	}
+
+	for client := range pool.Rooms[result.DocumentID] {
+		if client.SessionID == op.SessionID {
+			// its ack said the op was accepted
+			pool.sendTo(client, failed)
+		} else if reverted {
+			pool.sendTo(client, result.Inverse)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package websocket
+
+import (
+	"UpdatesService/types"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// SaveTimeout is how long an op may wait for its result before the session is told saving failed
+	SaveTimeout = 30 * time.Second
+	// SaveCheckInterval is how often pending ops are checked against SaveTimeout
+	SaveCheckInterval = 5 * time.Second
+)
+
+// pendingOp is an op pushed to kafka whose result hasn't come back yet
+type pendingOp struct {
+	DocumentID string
+	SessionID  string // empty for ops derived by the server
+	Sent       time.Time
+	Version    uint64 // version of the room once the op was applied, see room.State.Revert
+}
+
+// sessionSave is the save status of a session
+type sessionSave struct {
+	DocumentID string
+	Status     string
+	Pending    int
+	Seq        int64 // highest seq persisted for the session
+	Error      string
+}
+
+// saveTracker follows the ops from kafka to the consumer's results. It is only used
+// from the pool goroutine.
+type saveTracker struct {
+	pending   map[string]*pendingOp     // by opId
+	sessions  map[string]*sessionSave   // by sessionId
+	documents map[string]int64          // highest seq persisted per document
+	flushes   map[string][]FlushRequest // by documentId, waiting for its pending ops
+}
+
+// FlushRequest waits until the ops pushed for a document are persisted, see flush
+type FlushRequest struct {
+	DocumentID string
+	Done       chan uint64 // buffered, receives the version of the room then
+}
+
+func newSaveTracker() *saveTracker {
+	return &saveTracker{
+		pending:   make(map[string]*pendingOp),
+		sessions:  make(map[string]*sessionSave),
+		documents: make(map[string]int64),
+		flushes:   make(map[string][]FlushRequest),
+	}
+}
+
+func (t *saveTracker) session(documentId string, sessionId string) *sessionSave {
+	s, ok := t.sessions[sessionId]
+	if !ok {
+		s = &sessionSave{DocumentID: documentId, Status: types.SaveSaved}
+		t.sessions[sessionId] = s
+	}
+	return s
+}
+
+// trackSaving records an op which has been pushed to kafka, the session switches to
+// saving with its first pending op
+func (pool *Pool) trackSaving(message types.KafkaInterMessage) {
+	t := pool.saves
+	if message.Message.OpID == "" {
+		return
+	}
+	op := &pendingOp{
+		DocumentID: message.Message.DocumentID,
+		SessionID:  message.SessionID,
+		Sent:       time.Now(),
+	}
+	if state, ok := pool.Documents.Get(message.Message.DocumentID); ok {
+		op.Version = state.Version()
+	}
+	t.pending[message.Message.OpID] = op
+	if message.SessionID == "" {
+		return
+	}
+
+	s := t.session(message.Message.DocumentID, message.SessionID)
+	s.Pending++
+	if s.Status != types.SaveSaving {
+		s.Status = types.SaveSaving
+		s.Error = ""
+		pool.sendSaveStatus(message.SessionID, s)
+	}
+}
+
+// trackSaveError puts the session in error when its op couldn't be pushed to kafka
+func (pool *Pool) trackSaveError(message types.KafkaInterMessage, err error) {
+	if message.SessionID == "" {
+		return
+	}
+	s := pool.saves.session(message.Message.DocumentID, message.SessionID)
+	s.Status = types.SaveError
+	s.Error = err.Error()
+	pool.sendSaveStatus(message.SessionID, s)
+}
+
+// trackResult updates the save status with a result of the consumer. The session is
+// saved once all its pending ops are persisted, a failed op puts it in error.
+func (pool *Pool) trackResult(result types.ResultMessage) {
+	t := pool.saves
+	if result.Status == types.ResultApplied && result.Seq > t.documents[result.DocumentID] {
+		t.documents[result.DocumentID] = result.Seq
+	}
+
+	op, ok := t.pending[result.OpID]
+	if !ok {
+		return
+	}
+	delete(t.pending, result.OpID)
+	pool.answerFlushes(op.DocumentID)
+
+	s, ok := t.sessions[op.SessionID]
+	if !ok {
+		return
+	}
+	s.Pending--
+
+	if result.Status == types.ResultFailed {
+		s.Status = types.SaveError
+		s.Error = result.Error
+		pool.sendSaveStatus(op.SessionID, s)
+		return
+	}
+
+	if result.Seq > s.Seq {
+		s.Seq = result.Seq
+	}
+	if s.Pending == 0 {
+		s.Status = types.SaveSaved
+		s.Error = ""
+		pool.sendSaveStatus(op.SessionID, s)
+	}
+}
+
+// checkSaves puts the sessions whose ops have been waiting longer than SaveTimeout in
+// error. Ops of sessions which are gone are dropped.
+func (pool *Pool) checkSaves(now time.Time) {
+	t := pool.saves
+	for opId, op := range t.pending {
+		if now.Sub(op.Sent) < SaveTimeout {
+			continue
+		}
+
+		s, ok := t.sessions[op.SessionID]
+		if !ok {
+			delete(t.pending, opId)
+			pool.answerFlushes(op.DocumentID)
+			continue
+		}
+		if s.Status == types.SaveSaving {
+			s.Status = types.SaveError
+			s.Error = "changes haven't been saved yet"
+			pool.sendSaveStatus(op.SessionID, s)
+		}
+	}
+}
+
+// flush answers the request once no op of the document is pending. The database then
+// has the document as it is in the room, at the version the request receives.
+func (pool *Pool) flush(request FlushRequest) {
+	t := pool.saves
+	t.flushes[request.DocumentID] = append(t.flushes[request.DocumentID], request)
+	pool.answerFlushes(request.DocumentID)
+}
+
+// answerFlushes answers the flush requests of the document when none of its ops is
+// pending. A document which isn't open is at version 0.
+func (pool *Pool) answerFlushes(documentId string) {
+	t := pool.saves
+	requests, ok := t.flushes[documentId]
+	if !ok {
+		return
+	}
+	for _, op := range t.pending {
+		if op.DocumentID == documentId {
+			return
+		}
+	}
+
+	var version uint64
+	if state, ok := pool.Documents.Get(documentId); ok {
+		version = state.Version()
+	}
+	for _, request := range requests {
+		request.Done <- version
+	}
+	delete(t.flushes, documentId)
+}
+
+// forgetSaves drops the save status of a session which disconnected, and the seq of
+// the document when the room is empty
+func (pool *Pool) forgetSaves(client *Client) {
+	delete(pool.saves.sessions, client.SessionID)
+	if len(pool.Rooms[client.DocumentID]) == 0 {
+		delete(pool.saves.documents, client.DocumentID)
+	}
+}
+
+// sendSaveStatus sends the save status to the client of the session
+func (pool *Pool) sendSaveStatus(sessionId string, s *sessionSave) {
+	for client := range pool.Rooms[s.DocumentID] {
+		if client.SessionID != sessionId {
+			continue
+		}
+
+		pool.sendTo(client, types.SaveStatusMessage{
+			Action:      "save_status",
+			Status:      s.Status,
+			Seq:         s.Seq,
+			DocumentSeq: pool.saves.documents[s.DocumentID],
+			Pending:     s.Pending,
+			Error:       s.Error,
+		})
+		return
+	}
+}
+
+// sendTo wraps body into a message from the server and sends it to the client
+func (pool *Pool) sendTo(client *Client, body interface{}) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		fmt.Println("[Pool][sendTo] json Marshalling error")
+		return
+	}
+
+	message, err := json.Marshal(types.Message{
+		DocumentID: client.DocumentID,
+		Type:       1,
+		Body:       string(b),
+	})
+	if err != nil {
+		fmt.Println("[Pool][sendTo] json Marshalling error")
+		return
+	}
+	client.Send <- message
+}
@@ -0,0 +1,206 @@
+package websocket
+
+import (
+	"UpdatesService/room"
+	"UpdatesService/types"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// saveEvent is an op pushed to kafka, a result of the consumer or a timeout check
+type saveEvent func(pool *Pool)
+
+func pushed(opId string, sessionId string) saveEvent {
+	return func(pool *Pool) {
+		pool.trackSaving(types.KafkaInterMessage{
+			Message:   types.Message{DocumentID: "doc", OpID: opId},
+			SessionID: sessionId,
+		})
+	}
+}
+
+func applied(opId string, seq int64) saveEvent {
+	return func(pool *Pool) {
+		pool.trackResult(types.ResultMessage{OpID: opId, DocumentID: "doc", Status: types.ResultApplied, Seq: seq})
+	}
+}
+
+func failed(opId string, err string) saveEvent {
+	return func(pool *Pool) {
+		pool.trackResult(types.ResultMessage{OpID: opId, DocumentID: "doc", Status: types.ResultFailed, Error: err})
+	}
+}
+
+func timedOut(pool *Pool) {
+	pool.checkSaves(time.Now().Add(SaveTimeout + time.Second))
+}
+
+// saveStatuses reads the save statuses sent to the client
+func saveStatuses(t *testing.T, client *Client) []string {
+	out := []string{}
+	for {
+		select {
+		case raw := <-client.Send:
+			var message types.Message
+			var status types.SaveStatusMessage
+			if err := json.Unmarshal(raw, &message); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(message.Body), &status); err != nil {
+				t.Fatal(err)
+			}
+			line := fmt.Sprintf("%s seq=%d documentSeq=%d pending=%d", status.Status, status.Seq, status.DocumentSeq, status.Pending)
+			if status.Error != "" {
+				line += " error=" + status.Error
+			}
+			out = append(out, line)
+		default:
+			return out
+		}
+	}
+}
+
+func TestSaveStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []saveEvent
+		want   []string
+	}{
+		{
+			name:   "saving until the op is applied",
+			events: []saveEvent{pushed("a", "s1"), applied("a", 3)},
+			want:   []string{"saving seq=0 documentSeq=0 pending=1", "saved seq=3 documentSeq=3 pending=0"},
+		},
+		{
+			name:   "saved once every op is applied",
+			events: []saveEvent{pushed("a", "s1"), pushed("b", "s1"), applied("a", 1), applied("b", 2)},
+			want:   []string{"saving seq=0 documentSeq=0 pending=1", "saved seq=2 documentSeq=2 pending=0"},
+		},
+		{
+			name:   "a failed op puts the session in error",
+			events: []saveEvent{pushed("a", "s1"), failed("a", "write failed")},
+			want:   []string{"saving seq=0 documentSeq=0 pending=1", "error seq=0 documentSeq=0 pending=0 error=write failed"},
+		},
+		{
+			name:   "ops of other sessions only move the document seq",
+			events: []saveEvent{pushed("a", "s2"), applied("a", 5), pushed("b", "s1"), applied("b", 6)},
+			want:   []string{"saving seq=0 documentSeq=5 pending=1", "saved seq=6 documentSeq=6 pending=0"},
+		},
+		{
+			name:   "ops derived by the server are not tracked per session",
+			events: []saveEvent{pushed("a", ""), applied("a", 1)},
+			want:   []string{},
+		},
+		{
+			name:   "an op waiting too long puts the session in error",
+			events: []saveEvent{pushed("a", "s1"), timedOut},
+			want:   []string{"saving seq=0 documentSeq=0 pending=1", "error seq=0 documentSeq=0 pending=1 error=changes haven't been saved yet"},
+		},
+		{
+			name:   "a late result saves the session again",
+			events: []saveEvent{pushed("a", "s1"), timedOut, applied("a", 2)},
+			want: []string{
+				"saving seq=0 documentSeq=0 pending=1",
+				"error seq=0 documentSeq=0 pending=1 error=changes haven't been saved yet",
+				"saved seq=2 documentSeq=2 pending=0",
+			},
+		},
+		{
+			name:   "results of unknown ops are ignored",
+			events: []saveEvent{applied("unknown", 4), pushed("a", "s1")},
+			want:   []string{"saving seq=0 documentSeq=4 pending=1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewPool(nil, room.NewManager(nil, nil))
+			client := &Client{SessionID: "s1", DocumentID: "doc", Send: make(chan []byte, 16)}
+			other := &Client{SessionID: "s2", DocumentID: "doc", Send: make(chan []byte, 16)}
+			pool.Rooms["doc"] = map[*Client]bool{client: true, other: true}
+
+			for _, event := range tt.events {
+				event(pool)
+			}
+
+			if got := saveStatuses(t, client); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlush(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []saveEvent // events before the flush request
+		after  []saveEvent
+		want   bool // the flush is answered
+	}{
+		{
+			name: "answered at once when nothing is pending",
+			want: true,
+		},
+		{
+			name:   "waits for the pending ops",
+			before: []saveEvent{pushed("a", "s1"), pushed("b", "s2"), applied("a", 1)},
+			want:   false,
+		},
+		{
+			name:   "answered once the pending ops are applied",
+			before: []saveEvent{pushed("a", "s1"), pushed("b", "s2")},
+			after:  []saveEvent{applied("a", 1), applied("b", 2)},
+			want:   true,
+		},
+		{
+			name:   "a failed op isn't pending anymore",
+			before: []saveEvent{pushed("a", "s1")},
+			after:  []saveEvent{failed("a", "write failed")},
+			want:   true,
+		},
+		{
+			name:   "an op of a session which is gone is dropped once timed out",
+			before: []saveEvent{pushed("a", "")},
+			after:  []saveEvent{timedOut},
+			want:   true,
+		},
+		{
+			name:   "an op of a connected session is still pending once timed out",
+			before: []saveEvent{pushed("a", "s1")},
+			after:  []saveEvent{timedOut},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewPool(nil, room.NewManager(nil, nil))
+			client := &Client{SessionID: "s1", DocumentID: "doc", Send: make(chan []byte, 16)}
+			other := &Client{SessionID: "s2", DocumentID: "doc", Send: make(chan []byte, 16)}
+			pool.Rooms["doc"] = map[*Client]bool{client: true, other: true}
+
+			for _, event := range tt.before {
+				event(pool)
+			}
+			request := FlushRequest{DocumentID: "doc", Done: make(chan uint64, 1)}
+			pool.flush(request)
+			for _, event := range tt.after {
+				event(pool)
+			}
+
+			select {
+			case version := <-request.Done:
+				if !tt.want {
+					t.Errorf("answered with version %d, want pending", version)
+				}
+			default:
+				if tt.want {
+					t.Error("not answered")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package websocket
+
+import (
+	"UpdatesService/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Stroke is a pen object that is still being drawn by the client
+type Stroke struct {
+	SlideID    string
+	LayerID    string
+	Attributes map[string]interface{}
+	Points     []interface{}
+}
+
+func (c *Client) StrokeBegin(msg map[string]interface{}, outMsg types.Message) error {
+	objectId := msg["objectId"].(string)
+	slideId := msg["slideId"].(string)
+	attr := msg["attributes"].(map[string]interface{})
+
+	if _, ok := c.Strokes[objectId]; ok {
+		return fmt.Errorf("[Client][StrokeBegin][Error] stroke %s already started", objectId)
+	}
+
+	points, _ := attr["points"].([]interface{})
+	if points == nil {
+		points = []interface{}{}
+	}
+	if len(points) > types.MaxStrokePoints {
+		return fmt.Errorf("[Client][StrokeBegin][Error] stroke %s is too long", objectId)
+	}
+
+	// peers start rendering the stroke right away
+	if err := c.HoldLockAndBroadcast(outMsg, objectId); err != nil {
+		return err
+	}
+
+	layerId, _ := msg["layerId"].(string)
+	c.Strokes[objectId] = &Stroke{
+		SlideID:    slideId,
+		LayerID:    layerId,
+		Attributes: attr,
+		Points:     points,
+	}
+
+	return nil
+}
+
+func (c *Client) StrokeAppend(msg map[string]interface{}, outMsg types.Message) error {
+	objectId := msg["objectId"].(string)
+
+	stroke, ok := c.Strokes[objectId]
+	if !ok {
+		return fmt.Errorf("[Client][StrokeAppend][Error] stroke %s was not started", objectId)
+	}
+
+	points := msg["points"].([]interface{})
+	if len(stroke.Points)+len(points) > types.MaxStrokePoints {
+		return fmt.Errorf("[Client][StrokeAppend][Error] stroke %s is too long", objectId)
+	}
+
+	stroke.Points = append(stroke.Points, points...)
+
+	// only the new batch is relayed
+	c.Broadcast(outMsg)
+	return nil
+}
+
+func (c *Client) StrokeEnd(msg map[string]interface{}, outMsg types.Message) error {
+	objectId := msg["objectId"].(string)
+
+	stroke, ok := c.Strokes[objectId]
+	if !ok {
+		return fmt.Errorf("[Client][StrokeEnd][Error] stroke %s was not started", objectId)
+	}
+
+	// the stroke is kept until it is complete, a client whose last batch is refused
+	// can still end it (the lock stays held by the stroke meanwhile)
+	points := stroke.Points
+	if last, ok := msg["points"].([]interface{}); ok {
+		if len(points)+len(last) > types.MaxStrokePoints {
+			return fmt.Errorf("[Client][StrokeEnd][Error] stroke %s is too long", objectId)
+		}
+		points = append(points[:len(points):len(points)], last...)
+	}
+
+	// assemble the complete pen object
+	attr := make(map[string]interface{}, len(stroke.Attributes)+1)
+	for k, v := range stroke.Attributes {
+		attr[k] = v
+	}
+	attr["points"] = points
+
+	if !types.ValidatePenAttributes(attr) {
+		return fmt.Errorf("[Client][StrokeEnd][Error] invalid pen attributes")
+	}
+	delete(c.Strokes, objectId)
+
+	strokeEnd := map[string]interface{}{
+		"action":     "stroke_end",
+		"slideId":    stroke.SlideID,
+		"objectId":   objectId,
+		"objectType": "pen",
+		"attributes": attr,
+	}
+	if stroke.LayerID != "" {
+		strokeEnd["layerId"] = stroke.LayerID
+	}
+
+	body, err := json.Marshal(strokeEnd)
+	if err != nil {
+		return fmt.Errorf("[Client][StrokeEnd][Error] failure to marshal stroke: %w", err)
+	}
+
+	// peers already hold every point, they only need to know the stroke is closed
+	c.Broadcast(outMsg)
+
+	// the consumer persists the completed stroke once
+	kafkaMsg := outMsg
+	kafkaMsg.Body = string(body)
+	c.publish(c.Pool.PushToKafka, types.KafkaInterMessage{Topic: "document-updates", Message: kafkaMsg, SessionID: c.SessionID})
+
+	return nil
+}
+
+// CancelStrokes drops the strokes the client didn't end, it is called when the client
+// disconnects. Their locks are released and peers are told to drop what they rendered.
+func (c *Client) CancelStrokes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	for objectId, stroke := range c.Strokes {
+		if _, err := c.RedisClient.ReleaseLock(ctx, c.lockTarget(objectId)); err != nil {
+			fmt.Printf("[Client][CancelStrokes] %v\n", err)
+		}
+
+		body, err := json.Marshal(types.StrokeCancelMessage{Action: "stroke_cancel", SlideID: stroke.SlideID, ObjectID: objectId})
+		if err != nil {
+			fmt.Printf("[Client][CancelStrokes] %v\n", err)
+			continue
+		}
+		c.Broadcast(types.Message{
+			DocumentID: c.DocumentID,
+			Username:   c.Username,
+			UserID:     c.UserID,
+			Type:       1,
+			Body:       string(body),
+		})
+	}
+	c.Strokes = map[string]*Stroke{}
+}
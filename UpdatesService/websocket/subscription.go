@@ -0,0 +1,140 @@
+package websocket
+
+import (
+	"UpdatesService/types"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// slideListActions change the slide list and are sent to every client whatever it views
+var slideListActions = map[string]bool{
+	"add_slide":       true,
+	"remove_slide":    true,
+	"update_slide":    true,
+	"move_slide":      true,
+	"duplicate_slide": true,
+}
+
+// ViewsSlides reports whether the client views one of the slides. Clients which
+// haven't sent view_slides view every slide.
+func (c *Client) ViewsSlides(slideIds map[string]bool) bool {
+	c.viewMu.Lock()
+	defer c.viewMu.Unlock()
+
+	if c.slides == nil {
+		return true
+	}
+	for slideId := range slideIds {
+		if c.slides[slideId] {
+			return true
+		}
+	}
+	return false
+}
+
+// SetViewedSlides handles view_slides, it answers with the slides the client starts viewing
+func (c *Client) SetViewedSlides(p []byte) error {
+	var msg types.ViewSlidesMessage
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return fmt.Errorf("[Client][SetViewedSlides] %w", err)
+	}
+
+	var slides map[string]bool
+	if msg.SlideIDs != nil {
+		slides = make(map[string]bool, len(msg.SlideIDs))
+		for _, slideId := range msg.SlideIDs {
+			slides[slideId] = true
+		}
+	}
+
+	// the slides are set before they are read, changes made in between are not missed
+	c.viewMu.Lock()
+	before := c.slides
+	c.slides = slides
+	c.viewMu.Unlock()
+
+	state, ok := c.Pool.Documents.Get(c.DocumentID)
+	if !ok {
+		return fmt.Errorf("[Client][SetViewedSlides] document %s is not open", c.DocumentID)
+	}
+	encoded, err := state.EncodeSlides(func(slideId string) bool {
+		// a client without view_slides viewed every slide
+		return before != nil && !before[slideId] && (slides == nil || slides[slideId])
+	})
+	if err != nil {
+		return fmt.Errorf("[Client][SetViewedSlides] %w", err)
+	}
+
+	c.Pool.sendTo(c, types.SlidesStateMessage{Action: "slides_state", Slides: json.RawMessage(encoded)})
+	return nil
+}
+
+// messageSlides returns the slides the ops of a message belong to, nil when the
+// message concerns the whole document
+func messageSlides(body string) map[string]bool {
+	var action map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &action); err != nil {
+		return nil
+	}
+
+	if actionStr, _ := action["action"].(string); slideListActions[actionStr] {
+		return nil
+	}
+
+	slideIds := map[string]bool{}
+	if slideId, ok := action["slideId"].(string); ok {
+		slideIds[slideId] = true
+	}
+	if ops, ok := action["ops"].([]interface{}); ok {
+		for _, o := range ops {
+			if op, ok := o.(map[string]interface{}); ok {
+				if slideId, ok := op["slideId"].(string); ok {
+					slideIds[slideId] = true
+				}
+			}
+		}
+	}
+
+	if len(slideIds) == 0 {
+		return nil
+	}
+	return slideIds
+}
+
+// hintSlideChanged sends a slide_changed hint for a persisted message to the clients
+// which didn't receive it because they don't view its slides
+func (pool *Pool) hintSlideChanged(message types.Message) {
+	slideIds := messageSlides(message.Body)
+	if slideIds == nil {
+		return
+	}
+
+	hint := types.SlideChangedMessage{Action: "slide_changed"}
+	for slideId := range slideIds {
+		hint.SlideIDs = append(hint.SlideIDs, slideId)
+	}
+	sort.Strings(hint.SlideIDs)
+
+	body, err := json.Marshal(hint)
+	if err != nil {
+		fmt.Println("[Pool][hintSlideChanged] json marshalling error")
+		return
+	}
+	jsonData, err := json.Marshal(types.Message{
+		DocumentID: message.DocumentID,
+		Type:       1,
+		Body:       string(body),
+	})
+	if err != nil {
+		fmt.Println("[Pool][hintSlideChanged] json marshalling error")
+		return
+	}
+
+	for client := range pool.Rooms[message.DocumentID] {
+		if client.UserID == message.UserID || client.ViewsSlides(slideIds) {
+			continue
+		}
+		client.Send <- jsonData
+	}
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"Shared/geometry"
+	"UpdatesService/room"
+	"UpdatesService/types"
+	"encoding/json"
+	"fmt"
+)
+
+// whiteboardRejectedActions change the slide list, a whiteboard always has exactly one slide
+var whiteboardRejectedActions = map[string]bool{
+	"add_slide":       true,
+	"remove_slide":    true,
+	"move_slide":      true,
+	"duplicate_slide": true,
+}
+
+// InViewport reports whether r intersects the viewport of the client. Clients which
+// haven't set a viewport receive every change.
+func (c *Client) InViewport(r geometry.Rect) bool {
+	c.viewMu.Lock()
+	defer c.viewMu.Unlock()
+
+	if c.viewport == nil {
+		return true
+	}
+	return c.viewport.Intersects(r)
+}
+
+// whiteboard returns the state of the document of the client when it is a whiteboard
+func (c *Client) whiteboard() (*room.State, bool) {
+	state, ok := c.Pool.Documents.Get(c.DocumentID)
+	if !ok || !state.IsWhiteboard() {
+		return nil, false
+	}
+	return state, true
+}
+
+// CheckWhiteboardAction rejects the slide list operations on whiteboards
+func (c *Client) CheckWhiteboardAction(actionStr string) error {
+	if !whiteboardRejectedActions[actionStr] {
+		return nil
+	}
+	if _, ok := c.whiteboard(); ok {
+		return fmt.Errorf("[Client][CheckWhiteboardAction] %s is not allowed on whiteboards", actionStr)
+	}
+	return nil
+}
+
+// HandleViewport answers set_viewport and viewport_query with the objects of the
+// requested region. set_viewport also limits the changes the client receives to it.
+func (c *Client) HandleViewport(actionStr string, p []byte) error {
+	state, ok := c.whiteboard()
+	if !ok {
+		return fmt.Errorf("[Client][HandleViewport] document is not a whiteboard")
+	}
+
+	var msg types.ViewportMessage
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return fmt.Errorf("[Client][HandleViewport] %w", err)
+	}
+	rect := geometry.Rect{X: msg.Rect.X, Y: msg.Rect.Y, Width: msg.Rect.Width, Height: msg.Rect.Height}
+
+	// the viewport is set before the objects are read, changes made in between are not missed
+	if actionStr == "set_viewport" {
+		c.viewMu.Lock()
+		c.viewport = &rect
+		c.viewMu.Unlock()
+	}
+
+	slideId, objects, err := state.ViewportObjects(rect)
+	if err != nil {
+		return fmt.Errorf("[Client][HandleViewport] %w", err)
+	}
+
+	c.Pool.sendTo(c, types.ViewportObjectsMessage{
+		Action:  "viewport_objects",
+		SlideID: slideId,
+		Rect:    msg.Rect,
+		Objects: json.RawMessage(objects),
+	})
+	return nil
+}